@@ -8,7 +8,6 @@ import (
 	"context"
 	"fmt"
 	"go/ast"
-	"go/token"
 	"go/types"
 	"strings"
 
@@ -78,94 +77,97 @@ func (lc *AddLogCall) Do(ctx context.Context, root string, pkgs []string) error
 	if callgen == nil {
 		return fmt.Errorf("failed to find function call generator for %v", lc.CallGenerator.Type)
 	}
+	excludeOpt, err := excludeFilesOpt(lc.Exclusions)
+	if err != nil {
+		return err
+	}
 	locator := locate.New(
 		concurrencyOpt(lc.Concurrency),
 		locate.Trace(Verbosef),
+		overlayOpt(),
+		dirOpt(),
+		modulesOpt(),
 		locate.IgnoreMissingFuctionsEtc(),
 		locate.IncludeMethods(lc.IncludeMethods),
+		excludeOpt,
+		locate.ExcludeGenerated(lc.ExcludeGenerated),
 	)
 	locator.AddInterfaces(lc.Interfaces...)
-	locator.AddFunctions(lc.Functions...)
+	locator.AddFunctions(lc.functionSpecs()...)
 	if len(pkgs) == 0 {
 		pkgs = lc.Packages
 	}
 	locator.AddPackages(pkgs...)
 	Verbosef("locating functions to be annotated with a logcall...")
-	if err := locator.Do(ctx); err != nil {
-		return fmt.Errorf("failed to locate functions and/or interface implementations: %v", err)
-	}
-
-	commentMaps := locator.MakeCommentMaps()
-	comment := fmt.Sprintf("DO NOT EDIT, AUTO GENERATED BY %s#%s", lc.Type, lc.Name)
-
-	dirty := map[string]bool{}
-	edits := map[string][]edit.Delta{}
-	errs := &errors.M{}
-	locator.WalkFunctions(func(fullname string,
-		pkg *packages.Package,
-		file *ast.File,
-		fn *types.Func,
-		decl *ast.FuncDecl,
-		_ []string) {
-		if locateutil.FunctionStatements(decl) < lc.AtLeastStatements {
-			return
-		}
-		if len(lc.NoAnnotationComment) > 0 {
-			cmap := commentMaps[file]
-			if locateutil.FunctionHasComment(decl, cmap, lc.NoAnnotationComment) {
+	return locateAndEdit(ctx, root, locator, "functions to be annotated with a logcall", func() (map[string][]edit.Delta, error) {
+		commentMaps := locator.MakeCommentMaps()
+		comment := fmt.Sprintf("DO NOT EDIT, AUTO GENERATED BY %s#%s", lc.Type, lc.Name)
+
+		dirty := map[string]bool{}
+		edits := map[string][]edit.Delta{}
+		errs := &errors.M{}
+		locator.WalkFunctions(func(fullname string,
+			pkg *packages.Package,
+			file *ast.File,
+			fn *types.Func,
+			decl *ast.FuncDecl,
+			_ []string) {
+			if locateutil.FunctionStatements(decl) < lc.AtLeastStatements {
 				return
 			}
-		}
-
-		invovation, err := callgen.Generate(pkg.Fset, fn, decl)
-		if err != nil {
-			errs.Append(err)
-			return
-		}
+			if len(lc.NoAnnotationComment) > 0 {
+				cmap := commentMaps[file]
+				if locateutil.FunctionHasComment(decl, cmap, lc.NoAnnotationComment) {
+					return
+				}
+			}
 
-		if lc.alreadyAnnotated(fn, decl, commentMaps[file], comment) {
-			Verbosef("%v: already annotated\n", fullname)
-			return
-		}
-		lbrace := pkg.Fset.PositionFor(decl.Body.Lbrace, false)
-		delta := edit.InsertString(lbrace.Offset+1, invovation+" // "+comment)
-		edits[lbrace.Filename] = append(edits[lbrace.Filename], delta)
-		dirty[lbrace.Filename] = true
-		Verbosef("function: %v @ %v\n", fullname, lbrace)
-	})
+			invovation, err := callgen.Generate(pkg.Fset, fn, decl)
+			if err != nil {
+				errs.Append(err)
+				return
+			}
 
-	importPath := callgen.Import()
-	importStatement := "\n" + `import "` + importPath + `"` + "\n"
+			if firstStatementAnnotated(decl, commentMaps[file], comment) {
+				Verbosef("%v: already annotated\n", fullname)
+				return
+			}
+			lbrace := pkg.Fset.PositionFor(decl.Body.Lbrace, false)
+			delta := edit.InsertString(lbrace.Offset+1, invovation+" // "+comment)
+			edits[lbrace.Filename] = append(edits[lbrace.Filename], delta)
+			dirty[lbrace.Filename] = true
+			Verbosef("function: %v @ %v\n", fullname, lbrace)
+		})
+
+		importPath := callgen.Import()
+
+		locator.WalkFiles(func(filename string,
+			pkg *packages.Package,
+			_ ast.CommentMap,
+			file *ast.File,
+			mask locate.HitMask) {
+			if !dirty[filename] || ((mask | locate.HasFunction) == 0) {
+				return
+			}
+			if locateutil.IsImportedByFile(file, importPath) {
+				Verbosef("%v: %v: already imported\n", filename, importPath)
+				return
+			}
+			filename, delta := importDelta(pkg, file, importPath)
+			edits[filename] = append(edits[filename], delta)
+			Verbosef("import: %v @ %v\n", importPath, filename)
+		})
 
-	locator.WalkFiles(func(filename string,
-		pkg *packages.Package,
-		_ ast.CommentMap,
-		file *ast.File,
-		mask locate.HitMask) {
-		if !dirty[filename] || ((mask | locate.HasFunction) == 0) {
-			return
-		}
-		if locateutil.IsImportedByFile(file, importPath) {
-			Verbosef("%v: %v: already imported\n", filename, importPath)
-			return
-		}
-		_, end := locateutil.ImportBlock(file)
-		if end == token.NoPos {
-			end = file.Name.End()
+		if err := errs.Err(); err != nil {
+			return nil, err
 		}
-		pos := pkg.Fset.PositionFor(end, false)
-		delta := edit.InsertString(pos.Offset, importStatement)
-		edits[pos.Filename] = append(edits[pos.Filename], delta)
-		Verbosef("import: %v @ %v\n", importPath, pos)
+		return edits, nil
 	})
-
-	if err := errs.Err(); err != nil {
-		return err
-	}
-	return applyEdits(ctx, computeOutputs(root, edits), edits)
 }
 
-func (lc *AddLogCall) alreadyAnnotated(_ *types.Func, decl *ast.FuncDecl, cmap ast.CommentMap, comment string) bool {
+// firstStatementAnnotated returns true if the first statement in decl is
+// a defer statement with a comment that starts with comment.
+func firstStatementAnnotated(decl *ast.FuncDecl, cmap ast.CommentMap, comment string) bool {
 	if locateutil.FunctionStatements(decl) == 0 {
 		return false
 	}
@@ -43,6 +43,24 @@ var expectedAddcall = []testutil.DiffReport{
 `},
 }
 
+var expectedAddcallSignature = []testutil.DiffReport{
+	{Name: "empty.go", Diff: `2a3,4
+> import "cloudeng.io/go/cmd/goannotate/annotators/testdata/apilog"
+> 
+5a8
+> 	defer apilog.LogCallf(nil, "cloudeng.io/go/cmd/goannotate/annotators/testdata/impl.Write", "buf[:%d]=...", len(buf))(nil, "_=?") // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddLogCall#add-signature
+`},
+	{Name: "existing.go", Diff: `7a8
+> 	defer apilog.LogCallf(nil, "cloudeng.io/go/cmd/goannotate/annotators/testdata/impl.Write", "buf[:%d]=...", len(buf))(nil, "_=?")                       // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddLogCall#add-signature
+`},
+	{Name: "legacy.go", Diff: `8c8,9
+< 	defer apilog.LogCallfLegacy(nil, "buf=%v...", buf)(nil, "") // gologcop: DO NOT EDIT, MUST BE FIRST STATEMENT
+---
+> 	defer apilog.LogCallf(nil, "cloudeng.io/go/cmd/goannotate/annotators/testdata/impl.Write", "buf[:%d]=...", len(buf))(nil, "_=?") // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddLogCall#add-signature
+> 	defer apilog.LogCallfLegacy(nil, "buf=%v...", buf)(nil, "")                                                                      // gologcop: DO NOT EDIT, MUST BE FIRST STATEMENT
+`},
+}
+
 func TestAddLogCall(t *testing.T) {
 	ctx := context.Background()
 	tmpdir, cleanup := testutil.SetupAnnotators(t)
@@ -55,3 +73,50 @@ func TestAddLogCall(t *testing.T) {
 	diffs := testutil.DiffMultipleFiles(t, original, copies)
 	testutil.CompareDiffReports(t, diffs, expectedAddcall)
 }
+
+var expectedAddcallExclusions = []testutil.DiffReport{
+	{Name: "empty.go", Diff: `2a3,4
+> import "cloudeng.io/go/cmd/goannotate/annotators/testdata/apilog"
+> 
+5a8
+> 	defer apilog.LogCallf(nil, "cloudeng.io/go/cmd/goannotate/annotators/testdata/impl.Write", "buf[:%d]=...", len(buf))(nil, "_=?") // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddLogCall#add-exclusions
+9a13
+> 	defer apilog.LogCallf(nil, "cloudeng.io/go/cmd/goannotate/annotators/testdata/impl.APIEmpty", "n=%d", n)(nil, "_=?") // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddLogCall#add-exclusions
+`},
+	{Name: "existing.go", Diff: `7a8
+> 	defer apilog.LogCallf(nil, "cloudeng.io/go/cmd/goannotate/annotators/testdata/impl.Write", "buf[:%d]=...", len(buf))(nil, "_=?")                       // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddLogCall#add-exclusions
+12a14
+> 	defer apilog.LogCallf(nil, "cloudeng.io/go/cmd/goannotate/annotators/testdata/impl.APIExisting", "n=%d", n)(nil, "_=?")                       // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddLogCall#add-exclusions
+`},
+}
+
+func TestAddLogCallExclusions(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, cleanup := testutil.SetupAnnotators(t)
+	defer cleanup()
+	err := annotators.Lookup("add-exclusions").Do(ctx, tmpdir, []string{here + "impl"})
+	if err != nil {
+		t.Errorf("Do: %v", err)
+	}
+	// legacy.go is excluded and hence not written.
+	original := []string{
+		filepath.Join("testdata", "impl", "empty.go"),
+		filepath.Join("testdata", "impl", "existing.go"),
+	}
+	copies := list(t, tmpdir)
+	diffs := testutil.DiffMultipleFiles(t, original, copies)
+	testutil.CompareDiffReports(t, diffs, expectedAddcallExclusions)
+}
+
+func TestAddLogCallSignature(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, cleanup := testutil.SetupAnnotators(t)
+	defer cleanup()
+	err := annotators.Lookup("add-signature").Do(ctx, tmpdir, []string{here + "impl"})
+	if err != nil {
+		t.Errorf("Do: %v", err)
+	}
+	original, copies := list(t, filepath.Join("testdata", "impl")), list(t, tmpdir)
+	diffs := testutil.DiffMultipleFiles(t, original, copies)
+	testutil.CompareDiffReports(t, diffs, expectedAddcallSignature)
+}
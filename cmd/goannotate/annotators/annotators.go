@@ -13,12 +13,29 @@ import (
 
 	"cloudeng.io/cmdutil/flags"
 	"cloudeng.io/go/cmd/goannotate/annotators/internal"
+	"cloudeng.io/go/locate"
 	"gopkg.in/yaml.v2"
 )
 
 var (
 	// Verbose controls verbose logging.
-	Verbose        = false
+	Verbose = false
+	// Overlay, if set, provides the contents of files with unsaved
+	// changes, keyed by absolute filename. It is used in place of the
+	// on-disk contents both when locating code and when applying edits.
+	Overlay map[string][]byte
+	// Dir, if set, is the directory in which packages are located.
+	Dir string
+	// AllModules, if set, allows for packages from all of the modules
+	// under Dir to be annotated in a single run.
+	AllModules bool
+	// BatchSize, if set, causes packages to be located and annotated in
+	// batches of at most this many packages, in dependency order, so that
+	// the memory required is bounded by the size of a batch rather than
+	// by the number of packages. Annotators, such as MarkDeprecatedUses,
+	// that require all of the packages to be loaded at once return an
+	// error if it is set.
+	BatchSize      int
 	annotators     = map[string]Annotator{}
 	configurations = map[string]Annotation{}
 )
@@ -33,11 +50,32 @@ type EssentialOptions struct {
 }
 
 // LocateOptions represents the configuration options used to locate specific
-// interfaces and/or functions.
+// interfaces and/or functions. Interfaces, functions and packages may
+// include negative specs, eg. -./internal/... or !acme.com/a/b.Legacy.*,
+// to exclude the packages or names that they match.
 type LocateOptions struct {
-	Interfaces     []string `yaml:"interfaces" annotator:"list of interfaces whose implementations are to be annoated."`
-	Functions      []string `yaml:"functions" annotator:"list of functions that are to be annotated."`
-	IncludeMethods bool     `yaml:"includeMethods" annotator:"if set, methods as well as functions that match the function spec are annotated"`
+	Interfaces       []string `yaml:"interfaces" annotator:"list of interfaces whose implementations are to be annoated."`
+	Functions        []string `yaml:"functions" annotator:"list of functions that are to be annotated."`
+	IncludeMethods   bool     `yaml:"includeMethods" annotator:"if set, methods as well as functions that match the function spec are annotated"`
+	Signature        string   `yaml:"signature" annotator:"if set, only functions whose signatures match these ; separated predicates, eg. 'first=context.Context;results=error', are annotated."`
+	Exclusions       []string `yaml:"exclusions" annotator:"regular expressions for files to be excluded."`
+	ExcludeGenerated bool     `yaml:"excludeGenerated" annotator:"if set, generated files are excluded."`
+}
+
+// functionSpecs returns the function specs with the signature predicates,
+// if any, appended to each positive spec.
+func (lo LocateOptions) functionSpecs() []string {
+	if len(lo.Signature) == 0 {
+		return lo.Functions
+	}
+	specs := make([]string, len(lo.Functions))
+	for i, fn := range lo.Functions {
+		specs[i] = fn
+		if !locate.IsExclusion(fn) {
+			specs[i] += ";" + lo.Signature
+		}
+	}
+	return specs
 }
 
 // Verbosef is like fmt.Printf but will produce output if the Verbose
@@ -15,6 +15,7 @@ import (
 	"strings"
 
 	"cloudeng.io/errors"
+	"cloudeng.io/go/locate"
 	"cloudeng.io/path/cloudpath"
 	"cloudeng.io/text/edit"
 )
@@ -27,6 +28,16 @@ func computeOutputs(writeDir string, edits map[string][]edit.Delta) map[string]s
 		}
 		return outputs
 	}
+	if modules := modulesForFiles(edits); len(modules) > 1 {
+		// Mirror each module under its module path so that files from
+		// different modules with the same relative names do not collide.
+		for k := range edits {
+			m := modules[filepath.Dir(k)]
+			rel, _ := filepath.Rel(m.Dir, k)
+			outputs[k] = filepath.Join(writeDir, filepath.FromSlash(m.Path), rel)
+		}
+		return outputs
+	}
 	var prefix string
 	if len(edits) > 1 {
 		filepaths := make([]cloudpath.T, 0, len(edits))
@@ -48,6 +59,38 @@ func computeOutputs(writeDir string, edits map[string][]edit.Delta) map[string]s
 	return outputs
 }
 
+// modulesForFiles returns the module for the directory of each of the
+// edited files, indexed by directory, if they all belong to a module.
+func modulesForFiles(edits map[string][]edit.Delta) map[string]locate.Module {
+	modules := map[string]locate.Module{}
+	distinct := map[string]bool{}
+	for k := range edits {
+		dir := filepath.Dir(k)
+		if _, ok := modules[dir]; ok {
+			continue
+		}
+		m, err := locate.FindEnclosingModule(dir)
+		if err != nil {
+			return nil
+		}
+		modules[dir] = m
+		distinct[m.Dir] = true
+	}
+	if len(distinct) < 2 {
+		return nil
+	}
+	return modules
+}
+
+// deleteWithLeadingSpace returns an edit that deletes the text between
+// from and to in buf along with any whitespace that immediately precedes it.
+func deleteWithLeadingSpace(buf []byte, from, to int) edit.Delta {
+	for from > 0 && strings.ContainsRune(" \t\n", rune(buf[from-1])) {
+		from--
+	}
+	return edit.Delete(from, to-from)
+}
+
 func applyEdits(ctx context.Context, outputs map[string]string, edits map[string][]edit.Delta) error {
 	errs := &errors.M{}
 	for file, edits := range edits {
@@ -71,12 +114,75 @@ func applyEdits(ctx context.Context, outputs map[string]string, edits map[string
 	return errs.Err()
 }
 
+// locateAndEdit uses locator to locate the code to be annotated and then
+// applies the edits returned by annotate. If BatchSize is set the packages
+// are located, and annotate is called, in batches of at most that many
+// packages so that the memory required is bounded by the size of a batch
+// rather than by the number of packages. The edits for each batch are
+// applied before the next batch is loaded unless root is set, in which
+// case they are applied once all of the batches have been processed since
+// the output filenames depend on all of the files that are edited.
+func locateAndEdit(ctx context.Context, root string, locator *locate.T, what string, annotate func() (map[string][]edit.Delta, error)) error {
+	if BatchSize <= 0 {
+		if err := locator.Do(ctx); err != nil {
+			return fmt.Errorf("failed to locate %v: %v", what, err)
+		}
+		edits, err := annotate()
+		if err != nil {
+			return err
+		}
+		return applyEdits(ctx, computeOutputs(root, edits), edits)
+	}
+	all := map[string][]edit.Delta{}
+	var editErr error
+	err := locator.DoBatches(ctx, BatchSize, func(ctx context.Context, pkgs []string) error {
+		Verbosef("batch: %v\n", strings.Join(pkgs, ", "))
+		edits, err := annotate()
+		if err == nil && len(root) == 0 {
+			err = applyEdits(ctx, computeOutputs(root, edits), edits)
+		}
+		if err != nil {
+			editErr = err
+			return err
+		}
+		if len(root) > 0 {
+			for filename, deltas := range edits {
+				all[filename] = append(all[filename], deltas...)
+			}
+		}
+		return nil
+	})
+	if editErr != nil {
+		return editErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to locate %v: %v", what, err)
+	}
+	if len(root) == 0 {
+		return nil
+	}
+	return applyEdits(ctx, computeOutputs(root, all), all)
+}
+
+// readFile returns the contents of filename from the Overlay, if present,
+// or from the filesystem otherwise.
+func readFile(filename string) ([]byte, error) {
+	if buf, ok := Overlay[filename]; ok {
+		return buf, nil
+	}
+	return os.ReadFile(filename)
+}
+
 func editFile(ctx context.Context, src, dst string, deltas []edit.Delta) error {
+	perm := os.FileMode(0644)
 	info, err := os.Stat(src)
-	if err != nil {
+	if err == nil {
+		perm = info.Mode().Perm()
+	} else if _, ok := Overlay[src]; !ok {
+		// Files that exist only in the overlay are created.
 		return err
 	}
-	buf, err := os.ReadFile(src)
+	buf, err := readFile(src)
 	if err != nil {
 		return err
 	}
@@ -102,5 +208,5 @@ func editFile(ctx context.Context, src, dst string, deltas []edit.Delta) error {
 		}
 		return fmt.Errorf("%v: %v", strings.Join(cmd.Args, " "), err)
 	}
-	return os.WriteFile(dst, out, info.Mode().Perm())
+	return os.WriteFile(dst, out, perm)
 }
@@ -0,0 +1,334 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package annotators
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"cloudeng.io/go/cmd/goannotate/annotators/internal"
+	"cloudeng.io/go/locate"
+	"cloudeng.io/text/edit"
+	"golang.org/x/tools/go/packages"
+	"gopkg.in/yaml.v2"
+)
+
+// AddInterfaceAssertions represents an annotator that inserts compile-time
+// assertions that a type implements an interface, or removes them.
+type AddInterfaceAssertions struct {
+	EssentialOptions `yaml:",inline"`
+
+	Interfaces []string `yaml:"interfaces" annotator:"list of interfaces whose implementations are to be asserted."`
+	Remove     bool     `yaml:"remove" annotator:"if set, existing assertions for the interfaces are removed rather than added."`
+}
+
+// AddInterfaceAssertionsDescription documents AddInterfaceAssertions.
+const AddInterfaceAssertionsDescription = `
+AddInterfaceAssertions is an annotator that adds a compile-time assertion,
+of the form var _ pkg.Interface = (*Impl)(nil), immediately after the
+declaration of every type that implements one of the specified interfaces,
+including types that do so only via methods promoted from embedded fields.
+The value form, eg. var _ pkg.Interface = Impl{}, is used when the type
+implements the interface using value receivers. The interface's package is
+imported if required and types that already have an assertion are skipped.
+If remove is set, the existing assertions for the specified interfaces are
+removed instead.
+`
+
+func init() {
+	Register(&AddInterfaceAssertions{})
+}
+
+// New implements annotators.Annotator.
+func (ia *AddInterfaceAssertions) New(name string) Annotation {
+	n := &AddInterfaceAssertions{}
+	n.Name = name
+	return n
+}
+
+// UnmarshalYAML implements annotators.Annotation.
+func (ia *AddInterfaceAssertions) UnmarshalYAML(buf []byte) error {
+	return yaml.Unmarshal(buf, ia)
+}
+
+// Describe implements annotators.Annotation.
+func (ia *AddInterfaceAssertions) Describe() string {
+	return internal.MustDescribe(ia, AddInterfaceAssertionsDescription)
+}
+
+type assertedInterface struct {
+	path string
+	pkg  string
+	name string
+	ifc  *types.Interface
+}
+
+// assertion represents an existing var _ Interface = <value> declaration.
+type assertion struct {
+	ifc  string
+	impl *types.TypeName
+	fset *token.FileSet
+	decl *ast.GenDecl
+	spec *ast.ValueSpec
+}
+
+// Do implements annotators.Annotation.
+func (ia *AddInterfaceAssertions) Do(ctx context.Context, root string, pkgs []string) error {
+	locator := locate.New(
+		concurrencyOpt(ia.Concurrency),
+		locate.Trace(Verbosef),
+		overlayOpt(),
+		dirOpt(),
+		modulesOpt(),
+		locate.IgnoreMissingFuctionsEtc(),
+		locate.RequireTypes(),
+	)
+	locator.AddInterfaces(ia.Interfaces...)
+	if len(pkgs) == 0 {
+		pkgs = ia.Packages
+	}
+	locator.AddPackages(pkgs...)
+	Verbosef("locating interface implementations...")
+	return locateAndEdit(ctx, root, locator, "interface implementations", func() (map[string][]edit.Delta, error) {
+		interfaces := map[string]assertedInterface{}
+		locator.WalkInterfaces(func(fullname string, pkg *packages.Package, _ *ast.File, decl *ast.TypeSpec, ifc *types.Interface) {
+			interfaces[fullname] = assertedInterface{
+				path: pkg.PkgPath,
+				pkg:  pkg.Name,
+				name: decl.Name.Name,
+				ifc:  ifc,
+			}
+		})
+
+		targets := map[string]bool{}
+		for _, pkg := range locator.Packages() {
+			targets[pkg] = true
+		}
+
+		// Determine the types, in the target packages, that implement each
+		// interface, including those that do so only via methods promoted
+		// from embedded fields.
+		implementers := map[*types.TypeName]map[string]bool{}
+		locator.WalkImplementations(func(impl locate.Implementation) {
+			if !targets[impl.Package.PkgPath] || impl.Decl == nil || impl.Decl.TypeParams != nil {
+				return
+			}
+			// Every type implements an interface with no methods.
+			if ifc, ok := interfaces[impl.Interface]; !ok || ifc.ifc.NumMethods() == 0 {
+				return
+			}
+			obj, ok := impl.Package.TypesInfo.Defs[impl.Decl.Name].(*types.TypeName)
+			if !ok {
+				return
+			}
+			if implementers[obj] == nil {
+				implementers[obj] = map[string]bool{}
+			}
+			implementers[obj][impl.Interface] = true
+		})
+
+		var existing []assertion
+		asserted := map[*types.TypeName]map[string]bool{}
+		locator.WalkFiles(func(_ string, pkg *packages.Package, _ ast.CommentMap, file *ast.File, _ locate.HitMask) {
+			if !targets[pkg.PkgPath] {
+				return
+			}
+			for _, a := range findAssertions(pkg, file) {
+				if _, ok := interfaces[a.ifc]; !ok {
+					continue
+				}
+				existing = append(existing, a)
+				if asserted[a.impl] == nil {
+					asserted[a.impl] = map[string]bool{}
+				}
+				asserted[a.impl][a.ifc] = true
+			}
+		})
+
+		edits := map[string][]edit.Delta{}
+		if ia.Remove {
+			if err := removeAssertions(existing, edits); err != nil {
+				return nil, err
+			}
+			return edits, nil
+		}
+
+		locator.WalkFiles(func(_ string, pkg *packages.Package, _ ast.CommentMap, file *ast.File, _ locate.HitMask) {
+			if !targets[pkg.PkgPath] {
+				return
+			}
+			imports := map[string]bool{}
+			for _, decl := range file.Decls {
+				d, ok := decl.(*ast.GenDecl)
+				if !ok || d.Tok != token.TYPE {
+					continue
+				}
+				var lines []string
+				for _, spec := range d.Specs {
+					obj, ok := pkg.TypesInfo.Defs[spec.(*ast.TypeSpec).Name].(*types.TypeName)
+					if !ok {
+						continue
+					}
+					for _, ifcName := range sortedKeys(implementers[obj]) {
+						if asserted[obj][ifcName] {
+							Verbosef("%v: %v: already asserted\n", obj.Name(), ifcName)
+							continue
+						}
+						ifc := interfaces[ifcName]
+						ifcExpr := ifc.name
+						if ifc.path != pkg.PkgPath {
+							name, imported := importedAs(file, ifc.path, ifc.pkg)
+							if len(name) > 0 {
+								ifcExpr = name + "." + ifc.name
+							}
+							if !imported {
+								imports[ifc.path] = true
+							}
+						}
+						lines = append(lines, fmt.Sprintf("var _ %s = %s", ifcExpr, assertionValue(obj, ifc.ifc)))
+					}
+				}
+				if len(lines) == 0 {
+					continue
+				}
+				pos := pkg.Fset.PositionFor(d.End(), false)
+				delta := edit.InsertString(pos.Offset, "\n\n"+strings.Join(lines, "\n"))
+				edits[pos.Filename] = append(edits[pos.Filename], delta)
+				Verbosef("assertions: %v @ %v\n", lines, pos)
+			}
+			for _, importPath := range sortedKeys(imports) {
+				filename, delta := importDelta(pkg, file, importPath)
+				edits[filename] = append(edits[filename], delta)
+				Verbosef("import: %v @ %v\n", importPath, filename)
+			}
+		})
+		return edits, nil
+	})
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// assertionValue returns the expression to be used on the right hand side
+// of an assertion for obj. The value form is used when obj implements the
+// interface using value receivers and the pointer form otherwise.
+func assertionValue(obj *types.TypeName, ifc *types.Interface) string {
+	name := obj.Name()
+	ptr := "(*" + name + ")(nil)"
+	if !types.Implements(obj.Type(), ifc) {
+		return ptr
+	}
+	switch u := obj.Type().Underlying().(type) {
+	case *types.Struct, *types.Array:
+		return name + "{}"
+	case *types.Pointer, *types.Slice, *types.Map, *types.Chan, *types.Signature, *types.Interface:
+		return name + "(nil)"
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return name + "(false)"
+		case u.Info()&types.IsString != 0:
+			return name + `("")`
+		case u.Info()&types.IsNumeric != 0:
+			return name + "(0)"
+		}
+	}
+	return ptr
+}
+
+// findAssertions returns all var _ Interface = <value> declarations in
+// file where <value> is of a named type, or a pointer to one.
+func findAssertions(pkg *packages.Package, file *ast.File) []assertion {
+	var found []assertion
+	for _, decl := range file.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range d.Specs {
+			s := spec.(*ast.ValueSpec)
+			if len(s.Names) != 1 || s.Names[0].Name != "_" || s.Type == nil || len(s.Values) != 1 {
+				continue
+			}
+			ifc, ok := pkg.TypesInfo.TypeOf(s.Type).(*types.Named)
+			if !ok || !types.IsInterface(ifc) || ifc.Obj().Pkg() == nil {
+				continue
+			}
+			val := pkg.TypesInfo.TypeOf(s.Values[0])
+			if ptr, ok := val.(*types.Pointer); ok {
+				val = ptr.Elem()
+			}
+			impl, ok := val.(*types.Named)
+			if !ok {
+				continue
+			}
+			found = append(found, assertion{
+				ifc:  ifc.Obj().Pkg().Path() + "." + ifc.Obj().Name(),
+				impl: impl.Obj(),
+				fset: pkg.Fset,
+				decl: d,
+				spec: s,
+			})
+		}
+	}
+	return found
+}
+
+// removeAssertions computes the edits required to remove the supplied
+// assertions. A declaration is removed in its entirety if all of its
+// specs are being removed. Any whitespace preceding the removed text is
+// also removed so as to undo the edits made when adding assertions.
+func removeAssertions(existing []assertion, edits map[string][]edit.Delta) error {
+	removed := map[*ast.GenDecl]int{}
+	for _, a := range existing {
+		removed[a.decl]++
+	}
+	contents := map[string][]byte{}
+	done := map[*ast.GenDecl]bool{}
+	for _, a := range existing {
+		if done[a.decl] {
+			continue
+		}
+		var from, to ast.Node = a.spec, a.spec
+		if a.spec.Doc != nil {
+			from = a.spec.Doc
+		}
+		if a.spec.Comment != nil {
+			to = a.spec.Comment
+		}
+		if removed[a.decl] == len(a.decl.Specs) {
+			from, to = a.decl, a.decl
+			if a.decl.Doc != nil {
+				from = a.decl.Doc
+			}
+			done[a.decl] = true
+		}
+		start := a.fset.PositionFor(from.Pos(), false)
+		end := a.fset.PositionFor(to.End(), false)
+		buf, ok := contents[start.Filename]
+		if !ok {
+			var err error
+			if buf, err = readFile(start.Filename); err != nil {
+				return err
+			}
+			contents[start.Filename] = buf
+		}
+		delta := deleteWithLeadingSpace(buf, start.Offset, end.Offset)
+		edits[start.Filename] = append(edits[start.Filename], delta)
+		Verbosef("remove assertion: %v: %v @ %v\n", a.impl.Name(), a.ifc, start)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package annotators_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"cloudeng.io/go/cmd/goannotate/annotators"
+	"cloudeng.io/go/cmd/goannotate/annotators/internal/testutil"
+)
+
+var expectedAssertions = []testutil.DiffReport{
+	{Name: "assert.go", Diff: `13a14,15
+> var _ io.Writer = Value{}
+> 
+22a25,26
+> var _ io.Writer = (*Pointer)(nil)
+> 
+36a41,43
+> 
+> var _ io.Writer = Counter(0)
+> var _ Local = Names(nil)
+`},
+	{Name: "embedded.go", Diff: `2a3,4
+> import "io"
+> 
+8a11,12
+> var _ io.Writer = Wrapped{}
+> 
+13a18,19
+> 
+> var _ io.Reader = Nested{}
+`},
+	{Name: "reader.go", Diff: `2a3,4
+> import "io"
+> 
+4a7,8
+> 
+> var _ io.Reader = (*Reader)(nil)
+`},
+}
+
+var expectedRmAssertions = []testutil.DiffReport{
+	{Name: "assert.go", Diff: `3,6d2
+< import (
+< 	"io"
+< )
+< 
+51,52d46
+< var _ io.Writer = (*Asserted)(nil)
+< 
+54,55c48
+< 	_ Local = (*Pointer)(nil)
+< 	x       = 1
+---
+> 	x = 1
+`},
+}
+
+func TestInterfaceAssertions(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		annotation string
+		files      []string
+		expected   []testutil.DiffReport
+	}{
+		{"assertions", []string{"assert.go", "embedded.go", "reader.go"}, expectedAssertions},
+		{"rm-assertions", []string{"assert.go"}, expectedRmAssertions},
+	} {
+		tmpdir, cleanup := testutil.SetupAnnotators(t)
+		defer cleanup()
+		err := annotators.Lookup(tc.annotation).Do(ctx, tmpdir, []string{here + "assert"})
+		if err != nil {
+			t.Errorf("%v: Do: %v", tc.annotation, err)
+		}
+		original := []string{}
+		for _, file := range tc.files {
+			original = append(original, filepath.Join("testdata", "assert", file))
+		}
+		copies := list(t, tmpdir)
+		diffs := testutil.DiffMultipleFiles(t, original, copies)
+		testutil.CompareDiffReports(t, diffs, tc.expected)
+	}
+}
@@ -81,28 +81,38 @@ func (ec *EnsureCopyrightAndLicense) Do(ctx context.Context, root string, pkgs [
 	locator := locate.New(
 		concurrencyOpt(ec.Concurrency),
 		locate.Trace(Verbosef),
+		overlayOpt(),
+		dirOpt(),
+		modulesOpt(),
 		locate.IgnoreMissingFuctionsEtc(),
 		locate.IncludeTests(),
+		locate.TolerateErrors(),
 	)
 	if len(pkgs) == 0 {
 		pkgs = ec.Packages
 	}
 	locator.AddPackages(pkgs...)
 	Verbosef("locating functions to have a copyright/license annotation...")
-	if err := locator.Do(ctx); err != nil {
-		return fmt.Errorf("failed to locate functions and/or interface implementations: %v", err)
-	}
-
-	state := walkerState{
-		EnsureCopyrightAndLicense: ec,
-		dirty:                     map[string]bool{},
-		edits:                     map[string][]edit.Delta{},
-		exclusionREs:              exclusionREs,
-		newCopyright:              strings.TrimSuffix(ec.Copyright, "\n") + "\n",
-		newLicense:                strings.TrimSuffix(ec.License, "\n") + "\n\n",
-	}
-	locator.WalkFiles(state.determineEdits)
-	return applyEdits(ctx, computeOutputs(root, state.edits), state.edits)
+	return locateAndEdit(ctx, root, locator, "files to be annotated with a copyright and license", func() (map[string][]edit.Delta, error) {
+		// Copyright and license annotations only require the syntax of
+		// each file and hence packages that fail to type check are tolerated.
+		locator.WalkDiagnostics(func(pkgPath string, errs []packages.Error) {
+			for _, err := range errs {
+				Verbosef("%v: ignoring: %v\n", pkgPath, err)
+			}
+		})
+
+		state := walkerState{
+			EnsureCopyrightAndLicense: ec,
+			dirty:                     map[string]bool{},
+			edits:                     map[string][]edit.Delta{},
+			exclusionREs:              exclusionREs,
+			newCopyright:              strings.TrimSuffix(ec.Copyright, "\n") + "\n",
+			newLicense:                strings.TrimSuffix(ec.License, "\n") + "\n\n",
+		}
+		locator.WalkFiles(state.determineEdits)
+		return state.edits, nil
+	})
 }
 
 type walkerState struct {
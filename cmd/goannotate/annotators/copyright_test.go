@@ -6,7 +6,9 @@ package annotators_test
 
 import (
 	"context"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"cloudeng.io/go/cmd/goannotate/annotators"
@@ -29,6 +31,12 @@ var expectedPersonalApache = []testutil.DiffReport{
 > 
 `},
 	{Name: "personal.go", Diff: ""},
+	{Name: "typeerror.go", Diff: `0a1,4
+> // Copyright 2020 Cosmos Nicolaou. All rights reserved.
+> // Use of this source code is governed by the Apache-2.0
+> // license that can be found in the LICENSE file.
+> 
+`},
 }
 
 var expectedPersonalApacheUpdate = []testutil.DiffReport{
@@ -51,6 +59,12 @@ var expectedPersonalApacheUpdate = []testutil.DiffReport{
 > 
 `},
 	{Name: "personal.go", Diff: ""},
+	{Name: "typeerror.go", Diff: `0a1,4
+> // Copyright 2020 Cosmos Nicolaou. All rights reserved.
+> // Use of this source code is governed by the Apache-2.0
+> // license that can be found in the LICENSE file.
+> 
+`},
 }
 
 func TestCopyright(t *testing.T) {
@@ -73,3 +87,64 @@ func TestCopyright(t *testing.T) {
 	diffs = testutil.DiffMultipleFiles(t, original, copies)
 	testutil.CompareDiffReports(t, diffs, expectedPersonalApacheUpdate)
 }
+
+func TestCopyrightOverlay(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, cleanup := testutil.SetupAnnotators(t)
+	defer cleanup()
+	empty, err := filepath.Abs(filepath.Join("testdata", "copyright", "empty.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	annotators.Overlay = map[string][]byte{
+		empty: []byte("package copyright\n\nfunc Unsaved() {}\n"),
+	}
+	defer func() { annotators.Overlay = nil }()
+	err = annotators.Lookup("personal-apache").Do(ctx, tmpdir, []string{here + "copyright"})
+	if err != nil {
+		t.Errorf("Do: %v", err)
+	}
+	buf, err := os.ReadFile(filepath.Join(tmpdir, "empty.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(buf), `// Copyright 2020 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package copyright
+
+func Unsaved() {}
+`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCopyrightAllModules(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, cleanup := testutil.SetupAnnotators(t)
+	defer cleanup()
+	annotators.Dir = filepath.Join("testdata", "modules")
+	annotators.AllModules = true
+	defer func() {
+		annotators.Dir = ""
+		annotators.AllModules = false
+	}()
+	err := annotators.Lookup("personal-apache").Do(ctx, tmpdir, []string{"./..."})
+	if err != nil {
+		t.Errorf("Do: %v", err)
+	}
+	for _, file := range []string{
+		filepath.Join("example.com", "a", "x", "x.go"),
+		filepath.Join("example.com", "b", "y", "x.go"),
+	} {
+		buf, err := os.ReadFile(filepath.Join(tmpdir, file))
+		if err != nil {
+			t.Errorf("%v: %v", file, err)
+			continue
+		}
+		if !strings.HasPrefix(string(buf), "// Copyright 2020 Cosmos Nicolaou.") {
+			t.Errorf("%v: missing copyright: %s", file, buf)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package annotators
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"cloudeng.io/go/cmd/goannotate/annotators/internal"
+	"cloudeng.io/go/locate"
+	"cloudeng.io/text/edit"
+	"gopkg.in/yaml.v2"
+)
+
+// MarkDeprecatedUses represents an annotator that inserts a TODO comment
+// before every use of a deprecated function, method, type, field or
+// constant.
+type MarkDeprecatedUses struct {
+	EssentialOptions `yaml:",inline"`
+
+	Template   string   `yaml:"template" annotator:"template for the comment, the default is 'TODO: migrate off {{.Name}}'."`
+	Exclusions []string `yaml:"exclusions" annotator:"regular expressions for files to be excluded."`
+}
+
+// MarkDeprecatedUsesDescription documents MarkDeprecatedUses.
+const MarkDeprecatedUsesDescription = `
+MarkDeprecatedUses is an annotator that inserts a comment, '// TODO: migrate off
+<name>' by default, on the line preceding every use of a deprecated function,
+method, type, struct field or constant, ie. one whose doc comment contains a
+paragraph that starts with 'Deprecated: ', declared in the annotated packages or
+any of their dependencies. Uses within the package that declares the deprecated
+object are ignored. The comment is generated from a text/template which is
+supplied with the Name of the deprecated object and the Message of its
+deprecation notice. A comment is inserted once per line for each deprecated
+object used on that line and lines that are already preceded by the same comment
+are skipped. Packages cannot be processed in batches, via --batch-size, since
+all of the packages and their dependencies must be loaded at once.
+`
+
+const defaultDeprecatedTemplate = "TODO: migrate off {{.Name}}"
+
+func init() {
+	Register(&MarkDeprecatedUses{})
+}
+
+// New implements annotators.Annotator.
+func (md *MarkDeprecatedUses) New(name string) Annotation {
+	n := &MarkDeprecatedUses{}
+	n.Name = name
+	return n
+}
+
+// UnmarshalYAML implements annotators.Annotation.
+func (md *MarkDeprecatedUses) UnmarshalYAML(buf []byte) error {
+	return yaml.Unmarshal(buf, md)
+}
+
+// Describe implements annotators.Annotation.
+func (md *MarkDeprecatedUses) Describe() string {
+	return internal.MustDescribe(md, MarkDeprecatedUsesDescription)
+}
+
+// Do implements annotators.Annotation.
+func (md *MarkDeprecatedUses) Do(ctx context.Context, root string, pkgs []string) error {
+	if BatchSize > 0 {
+		return fmt.Errorf("%v: uses of deprecated objects cannot be located in batches since all of the packages and their dependencies must be loaded at once", md.Name)
+	}
+	tpl, err := parseTemplate("template", md.Template, defaultDeprecatedTemplate)
+	if err != nil {
+		return err
+	}
+	excludeOpt, err := excludeFilesOpt(md.Exclusions)
+	if err != nil {
+		return err
+	}
+	locator := locate.New(
+		concurrencyOpt(md.Concurrency),
+		locate.Trace(Verbosef),
+		overlayOpt(),
+		dirOpt(),
+		modulesOpt(),
+		excludeOpt,
+	)
+	if len(pkgs) == 0 {
+		pkgs = md.Packages
+	}
+	locator.AddDeprecatedUses(pkgs...)
+	Verbosef("locating uses of deprecated objects...")
+	if err := locator.Do(ctx); err != nil {
+		return fmt.Errorf("failed to locate uses of deprecated objects: %v", err)
+	}
+
+	edits := map[string][]edit.Delta{}
+	contents := map[string][]byte{}
+	// Indexed by filename and then line, the comments already inserted.
+	inserted := map[string]map[int]map[string]bool{}
+	var walkErr error
+	locator.WalkDeprecatedUses(func(use locate.DeprecatedUse) {
+		if walkErr != nil {
+			return
+		}
+		filename := use.Position.Filename
+		buf, ok := contents[filename]
+		if !ok {
+			if buf, walkErr = readFile(filename); walkErr != nil {
+				return
+			}
+			contents[filename] = buf
+			inserted[filename] = map[int]map[string]bool{}
+		}
+		var comment string
+		if comment, walkErr = expandDeprecated(tpl, use); walkErr != nil {
+			return
+		}
+		line := use.Position.Line
+		if inserted[filename][line][comment] {
+			return
+		}
+		start := use.Position.Offset - (use.Position.Column - 1)
+		if precededBy(buf, start, comment) {
+			return
+		}
+		if inserted[filename][line] == nil {
+			inserted[filename][line] = map[string]bool{}
+		}
+		inserted[filename][line][comment] = true
+		indent := leadingWhitespace(buf[start:])
+		delta := edit.InsertString(start, indent+comment+"\n")
+		edits[filename] = append(edits[filename], delta)
+		Verbosef("%v: %v\n", use.Position, use.Name)
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+	return applyEdits(ctx, computeOutputs(root, edits), edits)
+}
+
+func expandDeprecated(tpl *template.Template, use locate.DeprecatedUse) (string, error) {
+	out := &strings.Builder{}
+	err := tpl.Execute(out, struct {
+		Name    string
+		Message string
+	}{
+		Name:    use.Name,
+		Message: use.Message,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%v: failed to expand template for %v: %v", use.Position, use.Name, err)
+	}
+	return "// " + strings.Join(strings.Fields(out.String()), " "), nil
+}
+
+// precededBy returns true if the line starting at offset start in buf is
+// immediately preceded by a block of comment lines that includes comment.
+func precededBy(buf []byte, start int, comment string) bool {
+	lines := bytes.Split(buf[:start], []byte("\n"))
+	// The final element is empty since start is at the beginning of a line.
+	for i := len(lines) - 2; i >= 0; i-- {
+		line := bytes.TrimSpace(lines[i])
+		if !bytes.HasPrefix(line, []byte("//")) {
+			return false
+		}
+		if string(line) == comment {
+			return true
+		}
+	}
+	return false
+}
+
+func leadingWhitespace(buf []byte) string {
+	n := 0
+	for n < len(buf) && (buf[n] == ' ' || buf[n] == '\t') {
+		n++
+	}
+	return string(buf[:n])
+}
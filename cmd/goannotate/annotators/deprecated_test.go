@@ -0,0 +1,56 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package annotators_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cloudeng.io/go/cmd/goannotate/annotators"
+	"cloudeng.io/go/cmd/goannotate/annotators/internal/testutil"
+)
+
+var expectedDeprecated = []testutil.DiffReport{
+	{Name: "deprecated.go", Diff: `11a12,14
+> 	// TODO: migrate off cloudeng.io/go/cmd/goannotate/annotators/testdata/deprecated/old.Type.Field
+> 	// TODO: migrate off cloudeng.io/go/cmd/goannotate/annotators/testdata/deprecated/old.Func
+> 	// TODO: migrate off cloudeng.io/go/cmd/goannotate/annotators/testdata/deprecated/old.C
+13a17
+> 		// TODO: migrate off strings.Title
+20a25
+> // TODO: migrate off cloudeng.io/go/cmd/goannotate/annotators/testdata/deprecated/old.C
+`},
+}
+
+func TestMarkDeprecatedUses(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, cleanup := testutil.SetupAnnotators(t)
+	defer cleanup()
+	err := annotators.Lookup("deprecated").Do(ctx, tmpdir, []string{here + "deprecated"})
+	if err != nil {
+		t.Errorf("Do: %v", err)
+	}
+	// Only the file that uses the deprecated objects is annotated.
+	original := []string{filepath.Join("testdata", "deprecated", "deprecated.go")}
+	copies := list(t, tmpdir)
+	diffs := testutil.DiffMultipleFiles(t, original, copies)
+	testutil.CompareDiffReports(t, diffs, expectedDeprecated)
+}
+
+func TestMarkDeprecatedUsesBatches(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, cleanup := testutil.SetupAnnotators(t)
+	defer cleanup()
+	annotators.BatchSize = 1
+	defer func() {
+		annotators.BatchSize = 0
+	}()
+	err := annotators.Lookup("deprecated").Do(ctx, tmpdir, []string{here + "deprecated"})
+	if err == nil || !strings.Contains(err.Error(), "cannot be located in batches") {
+		t.Errorf("missing or wrong error: %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package annotators
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"cloudeng.io/go/cmd/goannotate/annotators/internal"
+	"cloudeng.io/go/locate"
+	"cloudeng.io/text/edit"
+	"golang.org/x/tools/go/packages"
+	"gopkg.in/yaml.v2"
+)
+
+// EnsureDocComments represents an annotator that inserts stub doc comments
+// for exported functions, methods, types, constants and variables that
+// do not have one.
+type EnsureDocComments struct {
+	EssentialOptions `yaml:",inline"`
+
+	Interfaces         []string `yaml:"interfaces" annotator:"list of interfaces whose implementations are documented as implementing them."`
+	Template           string   `yaml:"template" annotator:"template for the stub comment, the default is '{{.Name}} ...'."`
+	ImplementsTemplate string   `yaml:"implementsTemplate" annotator:"template for the stub comment for methods that implement one of the interfaces, the default is '{{.Name}} implements {{.Interfaces}}.'."`
+	ReportOnly         bool     `yaml:"reportOnly" annotator:"if set, undocumented exported identifiers are listed per package rather than being annotated."`
+}
+
+// EnsureDocCommentsDescription documents EnsureDocComments.
+const EnsureDocCommentsDescription = `
+EnsureDocComments is an annotator that inserts a stub doc comment for every
+exported function, method, type, constant and variable that does not have one.
+The stub is generated from a text/template which is supplied with the Name,
+Kind (one of function, method, type, const or var) and Interfaces fields.
+Interfaces is only set for methods that implement one of the configured
+interfaces and lists them, using their package names, separated by commas. Each
+line of the expanded template is prefixed with '// '.
+`
+
+const (
+	defaultDocTemplate        = "{{.Name}} ..."
+	defaultImplementsTemplate = "{{.Name}} implements {{.Interfaces}}."
+)
+
+func init() {
+	Register(&EnsureDocComments{})
+}
+
+// New implements annotators.Annotator.
+func (ed *EnsureDocComments) New(name string) Annotation {
+	n := &EnsureDocComments{}
+	n.Name = name
+	return n
+}
+
+// UnmarshalYAML implements annotators.Annotation.
+func (ed *EnsureDocComments) UnmarshalYAML(buf []byte) error {
+	return yaml.Unmarshal(buf, ed)
+}
+
+// Describe implements annotators.Annotation.
+func (ed *EnsureDocComments) Describe() string {
+	return internal.MustDescribe(ed, EnsureDocCommentsDescription)
+}
+
+type undocumented struct {
+	pkgPath    string
+	kind       string
+	name       string
+	position   token.Position
+	indent     string
+	implements []string
+}
+
+func parseTemplate(name, text, def string) (*template.Template, error) {
+	if len(text) == 0 {
+		text = def
+	}
+	tpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %v: %q: %v", name, text, err)
+	}
+	return tpl, nil
+}
+
+// Do implements annotators.Annotation.
+func (ed *EnsureDocComments) Do(ctx context.Context, root string, pkgs []string) error {
+	docTpl, err := parseTemplate("template", ed.Template, defaultDocTemplate)
+	if err != nil {
+		return err
+	}
+	implTpl, err := parseTemplate("implementsTemplate", ed.ImplementsTemplate, defaultImplementsTemplate)
+	if err != nil {
+		return err
+	}
+	locator := locate.New(
+		concurrencyOpt(ed.Concurrency),
+		locate.Trace(Verbosef),
+		overlayOpt(),
+		dirOpt(),
+		modulesOpt(),
+		locate.IgnoreMissingFuctionsEtc(),
+	)
+	locator.AddInterfaces(ed.Interfaces...)
+	if len(pkgs) == 0 {
+		pkgs = ed.Packages
+	}
+	locator.AddPackages(pkgs...)
+	Verbosef("locating exported identifiers without doc comments...")
+	return locateAndEdit(ctx, root, locator, "exported identifiers without doc comments", func() (map[string][]edit.Delta, error) {
+		// Use the package name rather than the full package path for the
+		// interfaces, eg. io.Writer.
+		names := map[string]string{}
+		interfaces := map[string]*types.Interface{}
+		locator.WalkInterfaces(func(fullname string, pkg *packages.Package, _ *ast.File, decl *ast.TypeSpec, ifc *types.Interface) {
+			names[fullname] = pkg.Name + "." + decl.Name.Name
+			interfaces[fullname] = ifc
+		})
+		// Only methods that are part of an interface are documented as
+		// implementing it rather than all of the methods of the implementing
+		// type.
+		implements := map[*ast.FuncDecl][]string{}
+		locator.WalkFunctions(func(_ string, _ *packages.Package, _ *ast.File, fn *types.Func, decl *ast.FuncDecl, impls []string) {
+			for _, impl := range impls {
+				if obj, _, _ := types.LookupFieldOrMethod(interfaces[impl], false, fn.Pkg(), fn.Name()); obj != nil {
+					implements[decl] = append(implements[decl], names[impl])
+				}
+			}
+		})
+
+		targets := map[string]bool{}
+		for _, pkg := range locator.Packages() {
+			targets[pkg] = true
+		}
+		var missing []undocumented
+		locator.WalkFiles(func(_ string, pkg *packages.Package, _ ast.CommentMap, file *ast.File, _ locate.HitMask) {
+			if !targets[pkg.PkgPath] {
+				return
+			}
+			missing = append(missing, findUndocumented(pkg, file, implements)...)
+		})
+
+		if ed.ReportOnly {
+			reportUndocumented(os.Stdout, missing)
+			return nil, nil
+		}
+
+		edits := map[string][]edit.Delta{}
+		for _, ud := range missing {
+			tpl := docTpl
+			if len(ud.implements) > 0 {
+				tpl = implTpl
+			}
+			stub, err := ud.stub(tpl)
+			if err != nil {
+				return nil, err
+			}
+			delta := edit.InsertString(ud.position.Offset, stub+ud.indent)
+			edits[ud.position.Filename] = append(edits[ud.position.Filename], delta)
+			Verbosef("%v: %v %v\n", ud.position, ud.kind, ud.name)
+		}
+		return edits, nil
+	})
+}
+
+func (ud undocumented) stub(tpl *template.Template) (string, error) {
+	out := &strings.Builder{}
+	err := tpl.Execute(out, struct {
+		Name       string
+		Kind       string
+		Interfaces string
+	}{
+		Name:       ud.name,
+		Kind:       ud.kind,
+		Interfaces: strings.Join(ud.implements, ", "),
+	})
+	if err != nil {
+		return "", fmt.Errorf("%v: failed to expand template for %v: %v", ud.position, ud.name, err)
+	}
+	comment := &strings.Builder{}
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		comment.WriteString("// ")
+		comment.WriteString(line)
+		comment.WriteString("\n")
+	}
+	return comment.String(), nil
+}
+
+func reportUndocumented(out io.Writer, missing []undocumented) {
+	byPackage := map[string][]undocumented{}
+	for _, ud := range missing {
+		byPackage[ud.pkgPath] = append(byPackage[ud.pkgPath], ud)
+	}
+	pkgs := make([]string, 0, len(byPackage))
+	for pkg := range byPackage {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		fmt.Fprintf(out, "%s:\n", pkg)
+		for _, ud := range byPackage[pkg] {
+			fmt.Fprintf(out, "\t%s: %s %s\n", ud.position, ud.kind, ud.name)
+		}
+	}
+}
+
+// receiverTypeName returns the name of the base type of a method's receiver.
+func receiverTypeName(expr ast.Expr) string {
+	switch rt := expr.(type) {
+	case *ast.Ident:
+		return rt.Name
+	case *ast.StarExpr:
+		return receiverTypeName(rt.X)
+	case *ast.ParenExpr:
+		return receiverTypeName(rt.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(rt.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(rt.X)
+	}
+	return ""
+}
+
+func firstExported(names []*ast.Ident) string {
+	for _, n := range names {
+		if n.IsExported() {
+			return n.Name
+		}
+	}
+	return ""
+}
+
+// findUndocumented returns the exported identifiers in file that do not
+// have a doc comment. Methods on unexported types are ignored as are
+// grouped constants and variables when the group itself is documented.
+func findUndocumented(pkg *packages.Package, file *ast.File, implements map[*ast.FuncDecl][]string) []undocumented {
+	var missing []undocumented
+	add := func(pos token.Pos, kind, name, indent string, impls []string) {
+		missing = append(missing, undocumented{
+			pkgPath:    pkg.PkgPath,
+			kind:       kind,
+			name:       name,
+			position:   pkg.Fset.PositionFor(pos, false),
+			indent:     indent,
+			implements: impls,
+		})
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil || !d.Name.IsExported() {
+				continue
+			}
+			kind := "function"
+			if d.Recv != nil {
+				if len(d.Recv.List) == 0 || !ast.IsExported(receiverTypeName(d.Recv.List[0].Type)) {
+					continue
+				}
+				kind = "method"
+			}
+			add(d.Pos(), kind, d.Name.Name, "", implements[d])
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT || d.Doc != nil {
+				continue
+			}
+			// Stubs for grouped declarations are inserted before the
+			// spec rather than the declaration.
+			specPos := func(spec ast.Spec) (token.Pos, string) {
+				if d.Lparen.IsValid() {
+					return spec.Pos(), "\t"
+				}
+				return d.Pos(), ""
+			}
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Doc != nil || !s.Name.IsExported() {
+						continue
+					}
+					at, indent := specPos(s)
+					add(at, "type", s.Name.Name, indent, nil)
+				case *ast.ValueSpec:
+					name := firstExported(s.Names)
+					if s.Doc != nil || s.Comment != nil || len(name) == 0 {
+						continue
+					}
+					at, indent := specPos(s)
+					add(at, d.Tok.String(), name, indent, nil)
+				}
+			}
+		}
+	}
+	return missing
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package annotators_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cloudeng.io/go/cmd/goannotate/annotators"
+	"cloudeng.io/go/cmd/goannotate/annotators/internal/testutil"
+)
+
+var expectedDocComments = []testutil.DiffReport{
+	{Name: "docs.go", Diff: `4a5
+> // Undocumented ...
+11a13
+> // Write implements io.Writer.
+15a18
+> // Method ...
+19a23
+> // Function ...
+27a32
+> 	// B ...
+31a37
+> // C ...
+33a40
+> // W ...
+37a45
+> 	// Y ...
+47a56
+> 	// T1 ...
+`},
+}
+
+func TestDocComments(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, cleanup := testutil.SetupAnnotators(t)
+	defer cleanup()
+	err := annotators.Lookup("docs").Do(ctx, tmpdir, []string{here + "docs"})
+	if err != nil {
+		t.Errorf("Do: %v", err)
+	}
+	original, copies := list(t, filepath.Join("testdata", "docs")), list(t, tmpdir)
+	diffs := testutil.DiffMultipleFiles(t, original, copies)
+	testutil.CompareDiffReports(t, diffs, expectedDocComments)
+}
+
+func TestDocCommentsBatches(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, cleanup := testutil.SetupAnnotators(t)
+	defer cleanup()
+	annotators.BatchSize = 1
+	defer func() {
+		annotators.BatchSize = 0
+	}()
+	err := annotators.Lookup("docs").Do(ctx, tmpdir, []string{here + "docs"})
+	if err != nil {
+		t.Errorf("Do: %v", err)
+	}
+	original, copies := list(t, filepath.Join("testdata", "docs")), list(t, tmpdir)
+	diffs := testutil.DiffMultipleFiles(t, original, copies)
+	testutil.CompareDiffReports(t, diffs, expectedDocComments)
+}
+
+func captureStdout(t *testing.T, fn func()) string {
+	rd, wr, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = wr
+	defer func() {
+		os.Stdout = stdout
+	}()
+	ch := make(chan string)
+	go func() {
+		buf, _ := io.ReadAll(rd)
+		ch <- string(buf)
+	}()
+	fn()
+	wr.Close()
+	return <-ch
+}
+
+func TestDocCommentsReport(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, cleanup := testutil.SetupAnnotators(t)
+	defer cleanup()
+	out := captureStdout(t, func() {
+		err := annotators.Lookup("docs-report").Do(ctx, tmpdir, []string{here + "docs"})
+		if err != nil {
+			t.Errorf("Do: %v", err)
+		}
+	})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{
+		here + "docs:",
+		"docs.go:5:1: type Undocumented",
+		"docs.go:12:1: method Write",
+		"docs.go:16:1: method Method",
+		"docs.go:20:1: function Function",
+		"docs.go:28:2: const B",
+		"docs.go:32:1: const C",
+		"docs.go:34:1: var W",
+		"docs.go:38:2: var Y",
+		"docs.go:48:2: type T1",
+	}
+	if got, want := len(lines), len(expected); got != want {
+		t.Fatalf("got %v, want %v: %v", got, want, out)
+	}
+	for i, line := range lines {
+		if got, want := line, expected[i]; !strings.HasSuffix(got, want) {
+			t.Errorf("%v: got %v does not end with %v", i, got, want)
+		}
+	}
+}
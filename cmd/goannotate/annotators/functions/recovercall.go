@@ -0,0 +1,86 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package functions
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+	"text/template"
+
+	"cloudeng.io/go/cmd/goannotate/annotators/internal"
+	"cloudeng.io/go/derive"
+	"gopkg.in/yaml.v2"
+)
+
+// RecoverCall represents a function call generator for a deferred call
+// to a function that recovers from, and reports, panics. The function
+// must have the following signature:
+//
+//	func (ctx <contextType>, functionName string)
+//
+// See RecoverCallDescription for a complete description.
+type RecoverCall struct {
+	EssentialOptions `yaml:",inline"`
+	ContextType      string `yaml:"contextType" annotator:"type for the context parameter."`
+}
+
+// RecoverCallDescription documents RecoverCall.
+const RecoverCallDescription = `
+RecoverCall provides a function call generator for generating deferred calls
+to functions that recover from and report panics. The function must have
+the following signature:
+  func (ctx <contextType>, functionName string)
+These are invoked via defer as shown below:
+  defer <call>(ctx, "<function-name>")
+The actual type of the context is determined by the ContextType configuration
+field, nil is passed for functions that do not have a context parameter.
+Note that the function must call recover itself, since recover only stops a
+panic when it is called directly by a deferred function.
+`
+
+func init() {
+	RegisterCallGenerator(&RecoverCall{})
+}
+
+// UnmarshalYAML implements functions.CallGenerator.
+func (rc *RecoverCall) UnmarshalYAML(buf []byte) error {
+	return yaml.Unmarshal(buf, rc)
+}
+
+// Describe implements functions.CallGenerator.
+func (rc *RecoverCall) Describe() string {
+	return internal.MustDescribe(rc, RecoverCallDescription)
+}
+
+// Import implements functions.CallGenerator.
+func (rc *RecoverCall) Import() string {
+	return rc.ImportPath
+}
+
+var recoverCallTemplate = template.Must(template.New("call").Parse(`defer {{.FunctionName}}({{.ContextParam}}, "{{.RecoveredFunction}}")`))
+
+func (rc *RecoverCall) Generate(_ *token.FileSet, fn *types.Func, _ *ast.FuncDecl) (string, error) {
+	sig := fn.Type().(*types.Signature)
+	ctxParam, hasContext := derive.HasCustomContext(sig, rc.ContextType)
+	if !hasContext || len(ctxParam) == 0 || ctxParam == "_" {
+		ctxParam = "nil"
+	}
+	call := &strings.Builder{}
+	data := struct {
+		*RecoverCall
+		RecoveredFunction string
+		ContextParam      string
+	}{
+		RecoverCall:       rc,
+		RecoveredFunction: fn.Pkg().Path() + "." + fn.Name(),
+		ContextParam:      ctxParam,
+	}
+	if err := recoverCallTemplate.Execute(call, data); err != nil {
+		return "", err
+	}
+	return call.String(), nil
+}
@@ -0,0 +1,24 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package functions_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRecoverCall(t *testing.T) {
+	importPath, calls := execute(t, ".RecoverCall")
+	if got, want := importPath, "cloudeng.io/go/cmd/goannotate/annotators/testdata/apilog"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	expectedCalls := []string{
+		`defer apilog.RecoverAndReport(ctx, "cloudeng.io/go/cmd/goannotate/annotators/functions/testdata/sample.ExampleCtx")`,
+		`defer apilog.RecoverAndReport(nil, "cloudeng.io/go/cmd/goannotate/annotators/functions/testdata/sample.Example")`,
+	}
+	if got, want := calls, expectedCalls; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
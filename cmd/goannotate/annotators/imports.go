@@ -0,0 +1,105 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package annotators
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"cloudeng.io/go/locate/locateutil"
+	"cloudeng.io/text/edit"
+	"golang.org/x/tools/go/packages"
+)
+
+// importDelta returns the filename and edit required to add an import
+// statement for importPath to file. The import is added after any existing
+// import block, or the package clause if there is none; goimports will
+// subsequently merge it into the existing block.
+func importDelta(pkg *packages.Package, file *ast.File, importPath string) (string, edit.Delta) {
+	return namedImportDelta(pkg, file, "", importPath)
+}
+
+// namedImportDelta is like importDelta but imports importPath using name,
+// unless name is empty.
+func namedImportDelta(pkg *packages.Package, file *ast.File, name, importPath string) (string, edit.Delta) {
+	_, end := locateutil.ImportBlock(file)
+	if end == token.NoPos {
+		end = file.Name.End()
+	}
+	pos := pkg.Fset.PositionFor(end, false)
+	spec := `"` + importPath + `"`
+	if len(name) > 0 {
+		spec = name + " " + spec
+	}
+	return pos.Filename, edit.InsertString(pos.Offset, "\nimport "+spec+"\n")
+}
+
+// qualifierAt returns the qualifier, eg. "fmt.", to use to refer to the
+// package importPath, whose package name is pkgName, at pos within file.
+// The package may already be imported under a different name, or its name
+// may be shadowed at pos by another import or declaration, in which case
+// it must be imported using a name that is not in use at pos. That name,
+// if any, is returned along with whether an import is required.
+func qualifierAt(pkg *packages.Package, file *ast.File, pos token.Pos, importPath, pkgName string) (qualifier, name string, required bool) {
+	scope := pkg.Types.Scope().Innermost(pos)
+	if scope == nil {
+		scope = pkg.TypesInfo.Scopes[file]
+	}
+	refersTo := func(name string) bool {
+		_, obj := scope.LookupParent(name, pos)
+		pn, ok := obj.(*types.PkgName)
+		return ok && pn.Imported().Path() == importPath
+	}
+	for _, imp := range file.Imports {
+		if imp.Path.Value != `"`+importPath+`"` {
+			continue
+		}
+		switch {
+		case imp.Name == nil:
+			if refersTo(pkgName) {
+				return pkgName + ".", "", false
+			}
+		case imp.Name.Name == ".":
+			return "", "", false
+		case imp.Name.Name != "_" && refersTo(imp.Name.Name):
+			return imp.Name.Name + ".", "", false
+		}
+	}
+	inUse := func(name string) bool {
+		_, obj := scope.LookupParent(name, pos)
+		return obj != nil
+	}
+	if !inUse(pkgName) {
+		return pkgName + ".", "", true
+	}
+	name = "std" + pkgName
+	for i := 2; inUse(name); i++ {
+		name = fmt.Sprintf("std%s%d", pkgName, i)
+	}
+	return name + ".", name, true
+}
+
+// importedAs returns the name by which importPath is referred to in file
+// and whether it is imported at all. The name is empty for dot imports.
+func importedAs(file *ast.File, importPath, pkgName string) (string, bool) {
+	for _, imp := range file.Imports {
+		if imp.Path.Value != `"`+importPath+`"` {
+			continue
+		}
+		if imp.Name == nil {
+			return pkgName, true
+		}
+		switch imp.Name.Name {
+		case "_":
+			continue
+		case ".":
+			return "", true
+		}
+		return imp.Name.Name, true
+	}
+	return pkgName, false
+}
@@ -16,3 +16,23 @@ func concurrencyOpt(val int) locate.Option {
 	}
 	return locate.Concurrency(val)
 }
+
+func overlayOpt() locate.Option {
+	return locate.Overlay(Overlay)
+}
+
+func dirOpt() locate.Option {
+	return locate.Dir(Dir)
+}
+
+func modulesOpt() locate.Option {
+	return locate.AllModules(AllModules)
+}
+
+func excludeFilesOpt(exclusions []string) (locate.Option, error) {
+	exclusionREs, err := compileREs(exclusions)
+	if err != nil {
+		return nil, err
+	}
+	return locate.ExcludeFiles(exclusionREs...), nil
+}
@@ -0,0 +1,205 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package annotators
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"cloudeng.io/errors"
+	"cloudeng.io/go/cmd/goannotate/annotators/functions"
+	"cloudeng.io/go/cmd/goannotate/annotators/internal"
+	"cloudeng.io/go/derive"
+	"cloudeng.io/go/locate"
+	"cloudeng.io/go/locate/locateutil"
+	"cloudeng.io/text/edit"
+	"golang.org/x/tools/go/packages"
+	"gopkg.in/yaml.v2"
+)
+
+// AddRecovery represents an annotator for adding panic recovery to every
+// function and method that is matched by the locator.
+type AddRecovery struct {
+	EssentialOptions `yaml:",inline"`
+	LocateOptions    `yaml:",inline"`
+
+	Inline              bool           `yaml:"inline" annotator:"if set, an inline recover block that sets the function's named error result, or logs the panic if there is none, is added rather than a call to the call generator."`
+	NoAnnotationComment string         `yaml:"noAnnotationComment" annotator:"do not annotate functions that contain this comment"`
+	CallGenerator       functions.Spec `yaml:"callGenerator" annotator:"the spec for the function call to be generated, it is also used for inline annotations of functions without a named error result."`
+}
+
+func init() {
+	Register(&AddRecovery{})
+}
+
+// New implements annotators.Annotator.
+func (ar *AddRecovery) New(name string) Annotation {
+	n := &AddRecovery{}
+	n.Name = name
+	return n
+}
+
+// UnmarshalYAML implements annotators.Annotation.
+func (ar *AddRecovery) UnmarshalYAML(buf []byte) error {
+	return yaml.Unmarshal(buf, ar)
+}
+
+// AddRecoveryDescription documents AddRecovery.
+const AddRecoveryDescription = `
+AddRecovery is an annotator to add panic recovery to functions. By default
+a deferred call, typically to a function that recovers from and reports the
+panic, is added as the first statement in the specified function. If inline
+is set, a deferred recover block that converts a panic into an error assigned
+to the function's named error result is added instead. Functions without a
+named error result are annotated with the deferred call, if a call generator
+is configured, or otherwise with a deferred recover block that logs the
+panic using log.Printf.
+`
+
+// Describe implements annotators.Annotation.
+func (ar *AddRecovery) Describe() string {
+	return internal.MustDescribe(ar, AddRecoveryDescription)
+}
+
+const inlineRecoverTemplate = `
+defer func() {
+	if r := recover(); r != nil {
+		%s = %sErrorf("%s: panic: %%v", r)
+	}
+}() // %s`
+
+const inlineLogRecoverTemplate = `
+defer func() {
+	if r := recover(); r != nil {
+		%sPrintf("%s: panic: %%v", r)
+	}
+}() // %s`
+
+// Do implements annotators.Annotation.
+func (ar *AddRecovery) Do(ctx context.Context, root string, pkgs []string) error {
+	var callgen functions.CallGenerator
+	if len(ar.CallGenerator.Type) > 0 {
+		callgen = functions.Lookup(ar.CallGenerator.Type)
+		if callgen == nil {
+			return fmt.Errorf("failed to find function call generator for %v", ar.CallGenerator.Type)
+		}
+	}
+	if callgen == nil && !ar.Inline {
+		return fmt.Errorf("a function call generator must be specified unless inline is set")
+	}
+	excludeOpt, err := excludeFilesOpt(ar.Exclusions)
+	if err != nil {
+		return err
+	}
+	locator := locate.New(
+		concurrencyOpt(ar.Concurrency),
+		locate.Trace(Verbosef),
+		overlayOpt(),
+		dirOpt(),
+		modulesOpt(),
+		locate.IgnoreMissingFuctionsEtc(),
+		locate.IncludeMethods(ar.IncludeMethods),
+		excludeOpt,
+		locate.ExcludeGenerated(ar.ExcludeGenerated),
+	)
+	locator.AddInterfaces(ar.Interfaces...)
+	locator.AddFunctions(ar.functionSpecs()...)
+	if len(pkgs) == 0 {
+		pkgs = ar.Packages
+	}
+	locator.AddPackages(pkgs...)
+	Verbosef("locating functions to be annotated with panic recovery...")
+	return locateAndEdit(ctx, root, locator, "functions to be annotated with panic recovery", func() (map[string][]edit.Delta, error) {
+		commentMaps := locator.MakeCommentMaps()
+		comment := fmt.Sprintf("DO NOT EDIT, AUTO GENERATED BY %s#%s", ar.Type, ar.Name)
+
+		imports := map[string]map[string]bool{}
+		edits := map[string][]edit.Delta{}
+		errs := &errors.M{}
+		locator.WalkFunctions(func(fullname string,
+			pkg *packages.Package,
+			file *ast.File,
+			fn *types.Func,
+			decl *ast.FuncDecl,
+			_ []string) {
+			if decl.Body == nil {
+				return
+			}
+			if len(ar.NoAnnotationComment) > 0 {
+				cmap := commentMaps[file]
+				if locateutil.FunctionHasComment(decl, cmap, ar.NoAnnotationComment) {
+					return
+				}
+			}
+			if firstStatementAnnotated(decl, commentMaps[file], comment) {
+				Verbosef("%v: already annotated\n", fullname)
+				return
+			}
+			var annotation, importName, importPath string
+			required := false
+			// The inline annotations refer to fmt or log as they are named
+			// at the start of the function body, importing them under an
+			// unused name if need be.
+			bodyStart := decl.Body.Lbrace + 1
+			errResult, hasErrResult := derive.NamedErrorResult(fn.Type().(*types.Signature))
+			switch {
+			case ar.Inline && hasErrResult:
+				var qualifier string
+				importPath = "fmt"
+				qualifier, importName, required = qualifierAt(pkg, file, bodyStart, importPath, "fmt")
+				annotation = fmt.Sprintf(inlineRecoverTemplate, errResult, qualifier, fn.FullName(), comment)
+			case callgen != nil:
+				invocation, err := callgen.Generate(pkg.Fset, fn, decl)
+				if err != nil {
+					errs.Append(err)
+					return
+				}
+				annotation = invocation + " // " + comment
+				importPath = callgen.Import()
+				required = len(importPath) > 0 && !locateutil.IsImportedByFile(file, importPath)
+			default:
+				var qualifier string
+				importPath = "log"
+				qualifier, importName, required = qualifierAt(pkg, file, bodyStart, importPath, "log")
+				annotation = fmt.Sprintf(inlineLogRecoverTemplate, qualifier, fn.FullName(), comment)
+			}
+			lbrace := pkg.Fset.PositionFor(decl.Body.Lbrace, false)
+			delta := edit.InsertString(lbrace.Offset+1, annotation)
+			edits[lbrace.Filename] = append(edits[lbrace.Filename], delta)
+			if required {
+				if imports[lbrace.Filename] == nil {
+					imports[lbrace.Filename] = map[string]bool{}
+				}
+				imports[lbrace.Filename][strings.TrimSpace(importName+" "+importPath)] = true
+			}
+			Verbosef("function: %v @ %v\n", fullname, lbrace)
+		})
+
+		locator.WalkFiles(func(filename string,
+			pkg *packages.Package,
+			_ ast.CommentMap,
+			file *ast.File,
+			_ locate.HitMask) {
+			// The imports are recorded as <path> or <name> <path>.
+			for _, spec := range sortedKeys(imports[filename]) {
+				name, importPath, named := strings.Cut(spec, " ")
+				if !named {
+					name, importPath = "", spec
+				}
+				filename, delta := namedImportDelta(pkg, file, name, importPath)
+				edits[filename] = append(edits[filename], delta)
+				Verbosef("import: %v @ %v\n", spec, filename)
+			}
+		})
+
+		if err := errs.Err(); err != nil {
+			return nil, err
+		}
+		return edits, nil
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package annotators_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"cloudeng.io/go/cmd/goannotate/annotators"
+	"cloudeng.io/go/cmd/goannotate/annotators/internal/testutil"
+)
+
+var expectedRecovery = []testutil.DiffReport{
+	{Name: "guard.go", Diff: `5a6,7
+> 
+> 	"cloudeng.io/go/cmd/goannotate/annotators/testdata/apilog"
+8a11
+> 	defer apilog.RecoverAndReport(ctx, "cloudeng.io/go/cmd/goannotate/annotators/testdata/guard.Serve") // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddRecovery#recover-call
+12a16
+> 	defer apilog.RecoverAndReport(nil, "cloudeng.io/go/cmd/goannotate/annotators/testdata/guard.Handle") // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddRecovery#recover-call
+16a21
+> 	defer apilog.RecoverAndReport(nil, "cloudeng.io/go/cmd/goannotate/annotators/testdata/guard.Close") // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddRecovery#recover-call
+19a25
+> 	defer apilog.RecoverAndReport(ctx, "cloudeng.io/go/cmd/goannotate/annotators/testdata/guard.Guarded") // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddRecovery#recover-call
+`},
+}
+
+var expectedInlineRecovery = []testutil.DiffReport{
+	{Name: "guard.go", Diff: `5a6,7
+> 
+> 	"cloudeng.io/go/cmd/goannotate/annotators/testdata/apilog"
+8a11,15
+> 	defer func() {
+> 		if r := recover(); r != nil {
+> 			err = fmt.Errorf("cloudeng.io/go/cmd/goannotate/annotators/testdata/guard.Serve: panic: %v", r)
+> 		}
+> 	}() // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddRecovery#recover-inline
+12a20
+> 	defer apilog.RecoverAndReport(nil, "cloudeng.io/go/cmd/goannotate/annotators/testdata/guard.Handle") // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddRecovery#recover-inline
+16a25
+> 	defer apilog.RecoverAndReport(nil, "cloudeng.io/go/cmd/goannotate/annotators/testdata/guard.Close") // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddRecovery#recover-inline
+`},
+}
+
+var expectedInlineLogRecovery = []testutil.DiffReport{
+	{Name: "conflicts.go", Diff: `6a7,10
+> 
+> 	stdfmt "fmt"
+> 
+> 	stdlog "log"
+12a17,21
+> 	defer func() {
+> 		if r := recover(); r != nil {
+> 			err = stdfmt.Errorf("(*cloudeng.io/go/cmd/goannotate/annotators/testdata/guard.Server).Close: panic: %v", r)
+> 		}
+> 	}() // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddRecovery#recover-inline-log
+18a28,32
+> 	defer func() {
+> 		if r := recover(); r != nil {
+> 			stdlog.Printf("(*cloudeng.io/go/cmd/goannotate/annotators/testdata/guard.Server).Stop: panic: %v", r)
+> 		}
+> 	}() // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddRecovery#recover-inline-log
+`},
+	{Name: "guard.go", Diff: `5a6
+> 	"log"
+8a10,14
+> 	defer func() {
+> 		if r := recover(); r != nil {
+> 			err = fmt.Errorf("cloudeng.io/go/cmd/goannotate/annotators/testdata/guard.Serve: panic: %v", r)
+> 		}
+> 	}() // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddRecovery#recover-inline-log
+12a19,23
+> 	defer func() {
+> 		if r := recover(); r != nil {
+> 			log.Printf("cloudeng.io/go/cmd/goannotate/annotators/testdata/guard.Handle: panic: %v", r)
+> 		}
+> 	}() // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddRecovery#recover-inline-log
+16a28,32
+> 	defer func() {
+> 		if r := recover(); r != nil {
+> 			log.Printf("cloudeng.io/go/cmd/goannotate/annotators/testdata/guard.Close: panic: %v", r)
+> 		}
+> 	}() // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddRecovery#recover-inline-log
+19a36,40
+> 	defer func() {
+> 		if r := recover(); r != nil {
+> 			err = fmt.Errorf("cloudeng.io/go/cmd/goannotate/annotators/testdata/guard.Guarded: panic: %v", r)
+> 		}
+> 	}() // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddRecovery#recover-inline-log
+`},
+}
+
+func TestRecovery(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		annotation string
+		files      []string
+		expected   []testutil.DiffReport
+	}{
+		{"recover-call", []string{"guard.go"}, expectedRecovery},
+		{"recover-inline", []string{"guard.go"}, expectedInlineRecovery},
+		{"recover-inline-log", []string{"conflicts.go", "guard.go"}, expectedInlineLogRecovery},
+	} {
+		tmpdir, cleanup := testutil.SetupAnnotators(t)
+		defer cleanup()
+		err := annotators.Lookup(tc.annotation).Do(ctx, tmpdir, []string{here + "guard"})
+		if err != nil {
+			t.Errorf("%v: Do: %v", tc.annotation, err)
+		}
+		original := []string{}
+		for _, file := range tc.files {
+			original = append(original, filepath.Join("testdata", "guard", file))
+		}
+		copies := list(t, tmpdir)
+		diffs := testutil.DiffMultipleFiles(t, original, copies)
+		testutil.CompareDiffReports(t, diffs, tc.expected)
+	}
+}
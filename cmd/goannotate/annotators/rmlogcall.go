@@ -6,11 +6,11 @@ package annotators
 
 import (
 	"context"
-	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
 	"regexp"
+	"sort"
 
 	"cloudeng.io/go/cmd/goannotate/annotators/internal"
 	"cloudeng.io/go/locate"
@@ -25,9 +25,11 @@ type RmLogCall struct {
 	EssentialOptions `yaml:",inline"`
 	LocateOptions    `yaml:",inline"`
 
-	FunctionNameRE string `yaml:"functionNameRE" annotator:"the function call (regexp) to be removed"`
-	Comment        string `yaml:"comment" annotator:"optional comment that must appear in the comments associated with the function call if it is to be removed."`
-	Deferred       bool   `yaml:"deferred" annotator:"if set requires that the function to be removed must be defered."`
+	FunctionNameRE string   `yaml:"functionNameRE" annotator:"the function call (regexp) to be removed"`
+	Comment        string   `yaml:"comment" annotator:"optional comment that must appear in the comments associated with the function call if it is to be removed."`
+	Deferred       bool     `yaml:"deferred" annotator:"if set requires that the function to be removed must be defered."`
+	Assignments    bool     `yaml:"assignments" annotator:"if set, assignments whose right hand side is a call to the function, eg. ctx, span := tracer.Start(ctx), are also removed."`
+	PairedCalls    []string `yaml:"pairedCalls" annotator:"methods (regexps) that, when called on a variable assigned by a removed assignment, are also removed, eg. End for defer span.End()."`
 }
 
 func init() {
@@ -48,70 +50,480 @@ func (rc *RmLogCall) UnmarshalYAML(buf []byte) error {
 
 // Describe implements annotators.Annotation.
 func (rc *RmLogCall) Describe() string {
-	return internal.MustDescribe(rc, "an annotator that removes instances of calls to functions.")
+	return internal.MustDescribe(rc, RmLogCallDescription)
 }
 
+// RmLogCallDescription documents RmLogCall.
+const RmLogCallDescription = `
+RmLogCall is an annotator that removes instances of calls to functions
+anywhere within the body of the functions matched by the locator. Assignments
+whose right hand side is such a call may also be removed, along with any
+paired method calls on the variables they assign, eg. defer span.End(). An
+assignment is not removed if any of the variables it assigns are used
+elsewhere, unless an existing variable is also passed to the removed call,
+eg. ctx, span = tracer.Start(ctx). Variables that are left unused by the removals are replaced
+by _, or have their declarations removed if their values can be computed
+without side effects, eg. n, err := w.Write(buf) becomes _, _ = w.Write(buf).
+Imports that are only referenced by the removed statements are deleted.
+`
+
 // Do implements annotators.Annotation.
 func (rc *RmLogCall) Do(ctx context.Context, root string, pkgs []string) error {
 	logcallRE, err := regexp.Compile(rc.FunctionNameRE)
 	if err != nil {
 		return err
 	}
+	paired := make([]*regexp.Regexp, len(rc.PairedCalls))
+	for i, expr := range rc.PairedCalls {
+		if paired[i], err = regexp.Compile(expr); err != nil {
+			return err
+		}
+	}
+	kinds := locateutil.CallStatement
+	if rc.Deferred {
+		kinds = locateutil.DeferStatement
+	}
+	if rc.Assignments {
+		kinds |= locateutil.AssignStatement
+	}
+	excludeOpt, err := excludeFilesOpt(rc.Exclusions)
+	if err != nil {
+		return err
+	}
 	locator := locate.New(
 		concurrencyOpt(rc.Concurrency),
 		locate.Trace(Verbosef),
+		overlayOpt(),
+		dirOpt(),
+		modulesOpt(),
 		locate.IgnoreMissingFuctionsEtc(),
 		locate.IncludeMethods(rc.IncludeMethods),
+		excludeOpt,
+		locate.ExcludeGenerated(rc.ExcludeGenerated),
 	)
 	locator.AddInterfaces(rc.Interfaces...)
-	locator.AddFunctions(rc.Functions...)
+	locator.AddFunctions(rc.functionSpecs()...)
 	if len(pkgs) == 0 {
 		pkgs = rc.Packages
 	}
 	locator.AddPackages(pkgs...)
 	Verbosef("locating functions to have a logcall annotation removal...")
-	if err := locator.Do(ctx); err != nil {
-		return fmt.Errorf("failed to locate functions and/or interface implementations: %v", err)
-	}
-
-	commentMaps := locator.MakeCommentMaps()
-
-	edits := map[string][]edit.Delta{}
-	locator.WalkFunctions(func(_ string,
-		pkg *packages.Package,
-		file *ast.File,
-		_ *types.Func,
-		decl *ast.FuncDecl,
-		_ []string) {
-		if locateutil.FunctionStatements(decl) == 0 {
-			return
-		}
-		nodes := locateutil.FunctionCalls(decl, logcallRE, rc.Deferred)
-		if len(nodes) == 0 {
-			return
-		}
-		var start, end token.Pos
-		cmap := commentMaps[file]
-		for _, node := range nodes {
-			start = node.Pos()
-			end = node.End()
-			cgs := cmap[node]
-			if !locateutil.CommentGroupsContain(cgs, rc.Comment) {
+	return locateAndEdit(ctx, root, locator, "functions containing log calls to be removed", func() (map[string][]edit.Delta, error) {
+		commentMaps := locator.MakeCommentMaps()
+
+		edits := map[string][]edit.Delta{}
+		removedInFile := map[*ast.File]map[ast.Stmt]bool{}
+		locator.WalkFunctions(func(fullname string,
+			pkg *packages.Package,
+			file *ast.File,
+			_ *types.Func,
+			decl *ast.FuncDecl,
+			_ []string) {
+			if locateutil.FunctionStatements(decl) == 0 {
+				return
+			}
+			cmap := commentMaps[file]
+			removed := map[ast.Stmt]bool{}
+			for _, stmt := range locateutil.CallStatements(decl, logcallRE, kinds) {
+				if len(rc.Comment) > 0 && !locateutil.CommentGroupsContain(cmap[stmt], rc.Comment) {
+					continue
+				}
+				stmts := []ast.Stmt{stmt}
+				if assign, ok := stmt.(*ast.AssignStmt); ok {
+					vars := locateutil.AssignedVars(pkg.TypesInfo, assign)
+					for _, re := range paired {
+						stmts = append(stmts, locateutil.MethodCallStatements(pkg.TypesInfo, decl, assign.End(), vars, re)...)
+					}
+					if v := stillUsed(pkg.TypesInfo, decl, vars, removed, stmts); v != nil {
+						Verbosef("%v: not removing assignment @ %v: %v is still used\n", fullname, pkg.Fset.PositionFor(stmt.Pos(), false), v.Name())
+						continue
+					}
+				}
+				for _, stmt := range stmts {
+					removed[stmt] = true
+				}
+			}
+			if len(removed) == 0 {
+				return
+			}
+			blanks, redefined := unusedVars(pkg.TypesInfo, decl, removed, logcallRE)
+			if removedInFile[file] == nil {
+				removedInFile[file] = map[ast.Stmt]bool{}
+			}
+			for stmt := range removed {
+				removedInFile[file][stmt] = true
+			}
+			for _, stmt := range outermost(removed) {
+				start, end := stmt.Pos(), stmt.End()
+				if cgs := cmap[stmt]; len(cgs) > 0 {
+					cFirst, cLast := locateutil.CommentGroupBounds(cgs)
+					if cFirst < start {
+						start = cFirst
+					}
+					if cLast > end {
+						end = cLast
+					}
+				}
+				from := pkg.Fset.PositionFor(start, false)
+				to := pkg.Fset.PositionFor(end, false)
+				delta := edit.Delete(from.Offset, to.Offset-from.Offset+1)
+				edits[from.Filename] = append(edits[from.Filename], delta)
+				Verbosef("delete: %v...%v\n", from, to)
+			}
+			for _, id := range blanks {
+				pos := pkg.Fset.PositionFor(id.Pos(), false)
+				delta := edit.ReplaceString(pos.Offset, len(id.Name), "_")
+				edits[pos.Filename] = append(edits[pos.Filename], delta)
+				Verbosef("unused: %v @ %v\n", id.Name, pos)
+			}
+			for _, assign := range redefined {
+				pos := pkg.Fset.PositionFor(assign.TokPos, false)
+				delta := edit.ReplaceString(pos.Offset, len(token.DEFINE.String()), token.ASSIGN.String())
+				edits[pos.Filename] = append(edits[pos.Filename], delta)
+			}
+		})
+
+		// Remove the imports that were only referenced by the removed
+		// statements, this is the inverse of AddLogCall adding an import
+		// for the function call it adds.
+		var err error
+		locator.WalkFiles(func(filename string,
+			pkg *packages.Package,
+			_ ast.CommentMap,
+			file *ast.File,
+			_ locate.HitMask) {
+			removed := removedInFile[file]
+			if len(removed) == 0 || err != nil {
+				return
+			}
+			var deltas []edit.Delta
+			deltas, err = removeOrphanedImports(pkg, file, removed)
+			edits[filename] = append(edits[filename], deltas...)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return edits, nil
+	})
+}
+
+// orphanedImports returns the imported packages that are referenced by the
+// removed statements and nowhere else in file.
+func orphanedImports(info *types.Info, file *ast.File, removed map[ast.Stmt]bool) map[*types.PkgName]bool {
+	orphaned := map[*types.PkgName]bool{}
+	for stmt := range removed {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			if id, ok := node.(*ast.Ident); ok {
+				if pkgName, ok := info.Uses[id].(*types.PkgName); ok {
+					orphaned[pkgName] = true
+				}
+			}
+			return true
+		})
+	}
+	if len(orphaned) == 0 {
+		return nil
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if stmt, ok := node.(ast.Stmt); ok && removed[stmt] {
+			return false
+		}
+		if id, ok := node.(*ast.Ident); ok {
+			if pkgName, ok := info.Uses[id].(*types.PkgName); ok {
+				delete(orphaned, pkgName)
+			}
+		}
+		return true
+	})
+	return orphaned
+}
+
+// removeOrphanedImports returns the edits required to remove the import
+// specs for the packages that are only referenced by the removed statements.
+// An import declaration is removed in its entirety if all of its specs
+// are orphaned.
+func removeOrphanedImports(pkg *packages.Package, file *ast.File, removed map[ast.Stmt]bool) ([]edit.Delta, error) {
+	orphaned := orphanedImports(pkg.TypesInfo, file, removed)
+	if len(orphaned) == 0 {
+		return nil, nil
+	}
+	decls, specs := locateutil.ImportSpecs(file)
+	isOrphaned := func(spec *ast.ImportSpec) bool {
+		obj := pkg.TypesInfo.Implicits[spec]
+		if spec.Name != nil {
+			obj = pkg.TypesInfo.Defs[spec.Name]
+		}
+		pkgName, ok := obj.(*types.PkgName)
+		return ok && orphaned[pkgName]
+	}
+	remaining := map[*ast.GenDecl]int{}
+	for i := range specs {
+		if !isOrphaned(specs[i]) {
+			remaining[decls[i]]++
+		}
+	}
+	filename := pkg.Fset.PositionFor(file.Pos(), false).Filename
+	buf, err := readFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var deltas []edit.Delta
+	done := map[*ast.GenDecl]bool{}
+	for i, spec := range specs {
+		decl := decls[i]
+		if done[decl] || !isOrphaned(spec) {
+			continue
+		}
+		var from, to ast.Node = spec, spec
+		if spec.Doc != nil {
+			from = spec.Doc
+		}
+		if spec.Comment != nil {
+			to = spec.Comment
+		}
+		if remaining[decl] == 0 {
+			from, to = decl, decl
+			if decl.Doc != nil {
+				from = decl.Doc
+			}
+			done[decl] = true
+		}
+		start := pkg.Fset.PositionFor(from.Pos(), false)
+		end := pkg.Fset.PositionFor(to.End(), false)
+		deltas = append(deltas, deleteWithLeadingSpace(buf, start.Offset, end.Offset))
+		Verbosef("import: remove %v @ %v\n", spec.Path.Value, start)
+	}
+	return deltas, nil
+}
+
+// uses returns the number of references to each object within decl
+// that are not within the removed statements.
+func uses(info *types.Info, decl *ast.FuncDecl, removed map[ast.Stmt]bool) map[types.Object]int {
+	counts := map[types.Object]int{}
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		if stmt, ok := node.(ast.Stmt); ok && removed[stmt] {
+			return false
+		}
+		if id, ok := node.(*ast.Ident); ok {
+			if obj := info.Uses[id]; obj != nil {
+				counts[obj]++
+			}
+		}
+		return true
+	})
+	return counts
+}
+
+// stillUsed returns the first of vars assigned by a statement that is to
+// be removed that is still referenced once all of the removals are made,
+// including variables declared before the statement.
+// Variables that shadow a variable of the same type are ignored since
+// references to them will refer to the shadowed variable once the
+// declaration is removed.
+func stillUsed(info *types.Info, decl *ast.FuncDecl, vars []types.Object, removed map[ast.Stmt]bool, stmts []ast.Stmt) types.Object {
+	all := map[ast.Stmt]bool{}
+	for stmt := range removed {
+		all[stmt] = true
+	}
+	for _, stmt := range stmts {
+		all[stmt] = true
+	}
+	counts := uses(info, decl, all)
+	for _, v := range vars {
+		if counts[v] == 0 {
+			continue
+		}
+		if v.Pos() < stmts[0].Pos() {
+			// An assignment to an existing variable that is still used
+			// cannot be removed since subsequent uses would see its
+			// previous value, unless that value is passed to the removed
+			// call, eg. ctx, span = tracer.Start(ctx), in which case the
+			// call is assumed to derive the new value from it.
+			if !passedTo(info, stmts[0], v) {
+				return v
+			}
+			continue
+		}
+		if scope := v.Parent(); scope != nil && scope.Parent() != nil {
+			if _, outer := scope.Parent().LookupParent(v.Name(), v.Pos()); outer != nil && types.Identical(outer.Type(), v.Type()) {
 				continue
 			}
-			cFirst, cLast := locateutil.CommentGroupBounds(cgs)
-			if cFirst < start {
-				start = cFirst
+		}
+		return v
+	}
+	return nil
+}
+
+// passedTo returns true if v is passed as an argument to the function
+// called on the right hand side of the assignment stmt.
+func passedTo(info *types.Info, stmt ast.Stmt, v types.Object) bool {
+	assign, ok := stmt.(*ast.AssignStmt)
+	if !ok || len(assign.Rhs) != 1 {
+		return false
+	}
+	call, ok := assign.Rhs[0].(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	for _, arg := range call.Args {
+		if id, ok := arg.(*ast.Ident); ok && info.Uses[id] == v {
+			return true
+		}
+	}
+	return false
+}
+
+// referencedBy returns the local variables referenced by the removed
+// statements.
+func referencedBy(info *types.Info, decl *ast.FuncDecl, removed map[ast.Stmt]bool) map[types.Object]bool {
+	refs := map[types.Object]bool{}
+	for stmt := range removed {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			id, ok := node.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			if v, ok := info.Uses[id].(*types.Var); ok && v.Pos() > decl.Body.Lbrace && v.Pos() < decl.Body.Rbrace {
+				refs[v] = true
+			}
+			return true
+		})
+	}
+	return refs
+}
+
+// unusedVars determines which local variables are no longer used once the
+// removed statements are deleted. Declarations whose variables are all
+// unused, and whose values can be evaluated without side effects, are
+// added to removed, this is repeated until no more declarations become
+// unused. Otherwise, the identifiers for the unused variables are
+// returned so that they may be replaced with _, along with any short
+// variable declarations that no longer declare a new variable and hence
+// must become assignments, eg. n, err := w.Write(buf) becomes
+// _, _ = w.Write(buf).
+func unusedVars(info *types.Info, decl *ast.FuncDecl, removed map[ast.Stmt]bool, logcallRE *regexp.Regexp) ([]*ast.Ident, []*ast.AssignStmt) {
+	for {
+		refs := referencedBy(info, decl, removed)
+		counts := uses(info, decl, removed)
+		unused := func(id *ast.Ident) bool {
+			obj := info.Defs[id]
+			return obj != nil && refs[obj] && counts[obj] == 0
+		}
+		var blanks []*ast.Ident
+		var redefined []*ast.AssignStmt
+		changed := false
+		ast.Inspect(decl.Body, func(node ast.Node) bool {
+			stmt, ok := node.(ast.Stmt)
+			if !ok {
+				return true
+			}
+			if removed[stmt] {
+				return false
+			}
+			var names []*ast.Ident
+			var values []ast.Expr
+			switch s := stmt.(type) {
+			case *ast.AssignStmt:
+				if s.Tok != token.DEFINE {
+					return true
+				}
+				for _, lhs := range s.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok {
+						names = append(names, id)
+					}
+				}
+				values = s.Rhs
+			case *ast.DeclStmt:
+				gd, ok := s.Decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.VAR || len(gd.Specs) != 1 {
+					return true
+				}
+				spec := gd.Specs[0].(*ast.ValueSpec)
+				names, values = spec.Names, spec.Values
+			default:
+				return true
+			}
+			var unusedNames []*ast.Ident
+			used, declared := 0, 0
+			for _, id := range names {
+				switch {
+				case unused(id):
+					unusedNames = append(unusedNames, id)
+				case id.Name == "_":
+				default:
+					used++
+					if info.Defs[id] != nil {
+						declared++
+					}
+				}
 			}
-			if cLast > end {
-				end = cLast
+			switch {
+			case len(unusedNames) == 0:
+			case used == 0 && sideEffectFree(info, logcallRE, values):
+				removed[stmt] = true
+				changed = true
+				return false
+			default:
+				blanks = append(blanks, unusedNames...)
+				if assign, ok := stmt.(*ast.AssignStmt); ok && declared == 0 {
+					redefined = append(redefined, assign)
+				}
 			}
-			from := pkg.Fset.PositionFor(start, false)
-			to := pkg.Fset.PositionFor(end, false)
-			delta := edit.Delete(from.Offset, to.Offset-from.Offset+1)
-			edits[from.Filename] = append(edits[from.Filename], delta)
-			Verbosef("delete: %v...%v\n", from, to)
+			return true
+		})
+		if !changed {
+			return blanks, redefined
 		}
+	}
+}
+
+// sideEffectFree returns true if the supplied expressions can be evaluated
+// without side effects, ie. they contain no function calls, other than
+// conversions or calls to the functions being removed, and no channel
+// receives.
+func sideEffectFree(info *types.Info, logcallRE *regexp.Regexp, exprs []ast.Expr) bool {
+	free := true
+	for _, expr := range exprs {
+		ast.Inspect(expr, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.FuncLit:
+				// The body of a function literal is not evaluated.
+				return false
+			case *ast.CallExpr:
+				if tv, ok := info.Types[n.Fun]; ok && tv.IsType() {
+					break
+				}
+				if !locateutil.CallMatches(n, logcallRE) {
+					free = false
+				}
+				return false
+			case *ast.UnaryExpr:
+				if n.Op == token.ARROW {
+					free = false
+				}
+			}
+			return free
+		})
+	}
+	return free
+}
+
+// outermost returns the removed statements, ordered by position, that are
+// not nested within another removed statement.
+func outermost(removed map[ast.Stmt]bool) []ast.Stmt {
+	stmts := make([]ast.Stmt, 0, len(removed))
+	for stmt := range removed {
+		stmts = append(stmts, stmt)
+	}
+	sort.Slice(stmts, func(i, j int) bool {
+		return stmts[i].Pos() < stmts[j].Pos()
 	})
-	return applyEdits(ctx, computeOutputs(root, edits), edits)
+	var outer []ast.Stmt
+	for _, stmt := range stmts {
+		if n := len(outer); n > 0 && stmt.End() <= outer[n-1].End() {
+			continue
+		}
+		outer = append(outer, stmt)
+	}
+	return outer
 }
@@ -50,3 +50,63 @@ func TestRmLogCall(t *testing.T) {
 	diffs = testutil.DiffMultipleFiles(t, original, copies)
 	testutil.CompareDiffReports(t, diffs, expectedRmNoDeferLegacycall)
 }
+
+var expectedRmTrace = []testutil.DiffReport{
+	{Name: "only.go", Diff: `5,6d4
+< 
+< 	"cloudeng.io/go/cmd/goannotate/annotators/testdata/apilog"
+10d7
+< 	apilog.Trace(ctx, "only")
+`},
+	{Name: "single.go", Diff: `3,4d2
+< import "cloudeng.io/go/cmd/goannotate/annotators/testdata/apilog"
+< 
+6d3
+< 	apilog.Trace(nil, "single")
+`},
+	{Name: "trace.go", Diff: `13d12
+< 		apilog.Trace(ctx, "n=%v", n)
+16,17c15
+< 		msg := fmt.Sprintf("i=%v", i)
+< 		apilog.Trace(ctx, msg)
+---
+> 		_ = fmt.Sprintf("i=%v", i)
+23,25c21
+< 	name := fmt.Sprintf("%v", "spans")
+< 	ctx, span := apilog.StartSpan(ctx, name)
+< 	defer span.End()
+---
+> 	_ = fmt.Sprintf("%v", "spans")
+38,39c34
+< 	a, b := 1, 2
+< 	apilog.Trace(ctx, "%v", b)
+---
+> 	a, _ := 1, 2
+45,46c40
+< 	n, m := n+1, n+2
+< 	apilog.Trace(ctx, "%v", m)
+---
+> 	n, _ = n+1, n+2
+51,52c45
+< 	n, err := w.Write(buf)
+< 	apilog.Trace(ctx, "%v %v", n, err)
+---
+> 	_, _ = w.Write(buf)
+56,57d48
+< 	m := int64(n) * 2
+< 	apilog.Trace(ctx, "%v", m)
+`},
+}
+
+func TestRmLogCallAssignments(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, cleanup := testutil.SetupAnnotators(t)
+	defer cleanup()
+	err := annotators.Lookup("rmtrace").Do(ctx, tmpdir, []string{here + "trace"})
+	if err != nil {
+		t.Errorf("Do: %v", err)
+	}
+	original, copies := list(t, filepath.Join("testdata", "trace")), list(t, tmpdir)
+	diffs := testutil.DiffMultipleFiles(t, original, copies)
+	testutil.CompareDiffReports(t, diffs, expectedRmTrace)
+}
@@ -9,3 +9,7 @@ func LogCallfLegacy(ctx *context.Context, format string, v ...interface{}) func(
 func LogCallf(ctx *context.Context, name, callerLocation, format string, v ...interface{}) func(*context.Context, string, ...interface{}) {
 	return nil
 }
+
+func RecoverAndReport(ctx *context.Context, name string) {
+	recover()
+}
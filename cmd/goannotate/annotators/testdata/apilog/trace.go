@@ -0,0 +1,15 @@
+package apilog
+
+import "context"
+
+type Span struct{}
+
+func (s *Span) End() {}
+
+func (s *Span) Annotate(msg string) {}
+
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	return ctx, &Span{}
+}
+
+func Trace(ctx context.Context, format string, args ...interface{}) {}
@@ -0,0 +1,56 @@
+package assert
+
+import (
+	"io"
+)
+
+type Local interface {
+	Local()
+}
+
+// Value implements io.Writer using a value receiver.
+type Value struct{}
+
+func (Value) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// Pointer implements io.Writer and Local using pointer receivers.
+type Pointer struct {
+	n int
+}
+
+func (p *Pointer) Write(buf []byte) (int, error) {
+	p.n += len(buf)
+	return len(buf), nil
+}
+
+func (p *Pointer) Local() {}
+
+type (
+	// Counter implements io.Writer using a non-struct type.
+	Counter int
+
+	// Names implements Local using a slice type.
+	Names []string
+)
+
+func (c Counter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (n Names) Local() {}
+
+// Asserted already has an assertion.
+type Asserted struct{}
+
+func (a *Asserted) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+var _ io.Writer = (*Asserted)(nil)
+
+var (
+	_ Local = (*Pointer)(nil)
+	x       = 1
+)
@@ -0,0 +1,13 @@
+package assert
+
+// Wrapped implements io.Writer only via the method promoted from its
+// embedded Value.
+type Wrapped struct {
+	Value
+}
+
+// Nested implements io.Reader only via the method promoted from its
+// embedded *Reader.
+type Nested struct {
+	*Reader
+}
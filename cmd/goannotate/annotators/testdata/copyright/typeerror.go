@@ -0,0 +1,4 @@
+package copyright
+
+// typeError ensures that this package fails to type check.
+var typeError int = "not an int"
@@ -0,0 +1,21 @@
+package deprecated
+
+import (
+	"strings"
+
+	"cloudeng.io/go/cmd/goannotate/annotators/testdata/deprecated/old"
+)
+
+func Uses() string {
+	old.NewFunc()
+	var t old.Type
+	t.Field = old.Func() + old.Func() + old.C
+	if t.Other > 0 {
+		return strings.Title("x")
+	}
+	// Already annotated.
+	// TODO: migrate off cloudeng.io/go/cmd/goannotate/annotators/testdata/deprecated/old.Func
+	return string(rune(old.Func()))
+}
+
+var v = old.C
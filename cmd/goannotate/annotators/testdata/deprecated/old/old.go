@@ -0,0 +1,23 @@
+package old
+
+// Func is an old function.
+//
+// Deprecated: use NewFunc instead.
+func Func() int { return 0 }
+
+// NewFunc replaces Func.
+func NewFunc() int { return 0 }
+
+// Type is an old type.
+type Type struct {
+	// Deprecated: use Other.
+	Field int
+	Other int
+}
+
+// Deprecated: use D.
+const C = 3
+
+func internal() int {
+	return Func()
+}
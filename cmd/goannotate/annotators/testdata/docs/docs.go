@@ -0,0 +1,52 @@
+package docs
+
+import "io"
+
+type Undocumented struct{}
+
+// Documented is documented.
+type Documented struct{}
+
+type unexported struct{}
+
+func (u *Undocumented) Write(buf []byte) (int, error) {
+	return len(buf), nil
+}
+
+func (u *Undocumented) Method() {}
+
+func (u *unexported) Method() {}
+
+func Function() {}
+
+// DocumentedFunction is documented.
+func DocumentedFunction() {}
+
+const (
+	// A is documented.
+	A = 1
+	B = 2
+	c = 3
+)
+
+const C = 3
+
+var v, W int
+
+var (
+	X = 1 // X has a trailing comment.
+	Y = 2
+)
+
+// Grouped is documented as a group.
+var (
+	G1 = 1
+	G2 = 2
+)
+
+type (
+	T1 int
+	t2 int
+)
+
+var _ io.Writer = (*Undocumented)(nil)
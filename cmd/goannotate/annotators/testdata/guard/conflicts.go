@@ -0,0 +1,20 @@
+package guard
+
+import (
+	"context"
+
+	log "cloudeng.io/go/cmd/goannotate/annotators/testdata/apilog"
+)
+
+type Server struct{}
+
+// Close's fmt parameter shadows the fmt package.
+func (s *Server) Close(ctx context.Context, fmt string) (err error) {
+	log.Trace(ctx, fmt)
+	return nil
+}
+
+// Stop is in a file that imports another package as log.
+func (s *Server) Stop(ctx context.Context) {
+	log.Trace(ctx, "stop")
+}
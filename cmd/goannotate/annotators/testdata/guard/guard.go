@@ -0,0 +1,26 @@
+package guard
+
+import (
+	"context"
+	"fmt"
+)
+
+func Serve(ctx context.Context, req string) (err error) {
+	return nil
+}
+
+func Handle(req string) error {
+	return nil
+}
+
+func Close() {
+}
+
+func Guarded(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cloudeng.io/go/cmd/goannotate/annotators/testdata/guard.Guarded: panic: %v", r)
+		}
+	}() // DO NOT EDIT, AUTO GENERATED BY cloudeng.io/go/cmd/goannotate/annotators.AddRecovery#recover-inline
+	return nil
+}
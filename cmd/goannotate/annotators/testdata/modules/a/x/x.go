@@ -0,0 +1,4 @@
+package x
+
+// X exists to be annotated.
+func X() {}
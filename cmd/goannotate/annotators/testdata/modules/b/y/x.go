@@ -0,0 +1,4 @@
+package y
+
+// Y exists to be annotated.
+func Y() {}
@@ -0,0 +1,11 @@
+package trace
+
+import (
+	"context"
+
+	"cloudeng.io/go/cmd/goannotate/annotators/testdata/apilog"
+)
+
+func Only(ctx context.Context) {
+	apilog.Trace(ctx, "only")
+}
@@ -0,0 +1,7 @@
+package trace
+
+import "cloudeng.io/go/cmd/goannotate/annotators/testdata/apilog"
+
+func Single() {
+	apilog.Trace(nil, "single")
+}
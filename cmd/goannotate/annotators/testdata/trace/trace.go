@@ -0,0 +1,66 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"cloudeng.io/go/cmd/goannotate/annotators/testdata/apilog"
+)
+
+func Nested(ctx context.Context, n int) error {
+	if n > 0 {
+		apilog.Trace(ctx, "n=%v", n)
+	}
+	for i := 0; i < n; i++ {
+		msg := fmt.Sprintf("i=%v", i)
+		apilog.Trace(ctx, msg)
+	}
+	return nil
+}
+
+func Spans(ctx context.Context) error {
+	name := fmt.Sprintf("%v", "spans")
+	ctx, span := apilog.StartSpan(ctx, name)
+	defer span.End()
+	fmt.Println(ctx)
+	return nil
+}
+
+func StillUsed(ctx context.Context) error {
+	_, span := apilog.StartSpan(ctx, "used")
+	defer span.End()
+	span.Annotate("used")
+	return nil
+}
+
+func Partial(ctx context.Context) error {
+	a, b := 1, 2
+	apilog.Trace(ctx, "%v", b)
+	fmt.Println(a)
+	return nil
+}
+
+func Redefine(ctx context.Context, n int) int {
+	n, m := n+1, n+2
+	apilog.Trace(ctx, "%v", m)
+	return n
+}
+
+func Write(ctx context.Context, w io.Writer, buf []byte) {
+	n, err := w.Write(buf)
+	apilog.Trace(ctx, "%v %v", n, err)
+}
+
+func Pure(ctx context.Context, n int) {
+	m := int64(n) * 2
+	apilog.Trace(ctx, "%v", m)
+}
+
+func Reassigned(ctx context.Context) context.Context {
+	sctx := ctx
+	var span *apilog.Span
+	sctx, span = apilog.StartSpan(ctx, "reassigned")
+	defer span.End()
+	return sctx
+}
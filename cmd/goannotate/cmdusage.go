@@ -5,14 +5,22 @@
 //
 // Command line flags:
 //
+//	-all-modules
+//	  	if set, packages from all of the modules under --dir, or those in its go.work workspace, may be annotated in a single run.
 //	-annotation string
 //	  	annotation to be applied
+//	-batch-size int
+//	  	if set, packages are loaded, annotated and released in dependency ordered batches of at most this many packages, with the edits for each batch being written before the next is loaded, so that the memory used is bounded by the size of a batch rather than the number of packages.
 //	-config string
 //	  	yaml configuration file (default "config.yaml")
+//	-dir string
+//	  	if set, the directory in which packages are located, the current directory is used by default.
 //	-list
 //	  	list available annotators
 //	-list-config
 //	  	list available annotations and their configurations
+//	-overlay string
+//	  	if set, a JSON file, in the format used by go build -overlay, that specifies replacements for files with unsaved changes.
 //	-verbose
 //	  	display verbose debug info
 //	-write-dir string
@@ -20,6 +28,25 @@
 //
 // Available annotators:
 //
+// cloudeng.io/go/cmd/goannotate/annotators.AddInterfaceAssertions:
+// AddInterfaceAssertions is an annotator that adds a compile-time assertion,
+// of the form var _ pkg.Interface = (*Impl)(nil), immediately after the
+// declaration of every type that implements one of the specified interfaces,
+// including types that do so only via methods promoted from embedded fields.
+// The value form, eg. var _ pkg.Interface = Impl{}, is used when the type
+// implements the interface using value receivers. The interface's package is
+// imported if required and types that already have an assertion are skipped.
+// If remove is set, the existing assertions for the specified interfaces are
+// removed instead.
+//
+//	type:        name of annotator type.
+//	name:        name of annotation.
+//	packages:    []packages to be annotated
+//	concurrency: the number of goroutines to use, zero for a sensible default.
+//	interfaces:  []list of interfaces whose implementations are to be asserted.
+//	remove:      if set, existing assertions for the interfaces are removed rather
+//	             than added.
+//
 // cloudeng.io/go/cmd/goannotate/annotators.AddLogCall:
 // AddLogCall is an annotator to add function calls that are intended to log entry and exit from functions. The calls will be added as the first statement in the specified function.
 //
@@ -33,6 +60,11 @@
 //	functions:           []list of functions that are to be annotated.
 //	includeMethods:      if set, methods as well as functions that match the function
 //	                     spec are annotated
+//	signature:           if set, only functions whose signatures match these ;
+//	                     separated predicates, eg. 'first=context.Context;results=error',
+//	                     are annotated.
+//	exclusions:          []regular expressions for files to be excluded.
+//	excludeGenerated:    if set, generated files are excluded.
 //	atLeastStatements:   the number of statements that must be present in a function
 //	                     in order for it to be annotated.
 //	noAnnotationComment: do not annotate functions that contain this comment
@@ -41,6 +73,7 @@
 //	  Available Call Generators:
 //
 //	  cloudeng.io/go/cmd/goannotate/annotators/functions.LogCallWithContext
+//	  cloudeng.io/go/cmd/goannotate/annotators/functions.RecoverCall
 //	  cloudeng.io/go/cmd/goannotate/annotators/functions.SimpleLogCall
 //
 //	  cloudeng.io/go/cmd/goannotate/annotators/functions.LogCallWithContext:
@@ -63,6 +96,22 @@
 //	    functionName: name of the function to be invoked.
 //	    contextType:  type for the context parameter and result.
 //
+//	  cloudeng.io/go/cmd/goannotate/annotators/functions.RecoverCall:
+//	  RecoverCall provides a function call generator for generating deferred calls
+//	  to functions that recover from and report panics. The function must have
+//	  the following signature:
+//	    func (ctx <contextType>, functionName string)
+//	  These are invoked via defer as shown below:
+//	    defer <call>(ctx, "<function-name>")
+//	  The actual type of the context is determined by the ContextType configuration
+//	  field, nil is passed for functions that do not have a context parameter.
+//	  Note that the function must call recover itself, since recover only stops a
+//	  panic when it is called directly by a deferred function.
+//	    type:         name of annotator type.
+//	    importPath:   import path for the logging function.
+//	    functionName: name of the function to be invoked.
+//	    contextType:  type for the context parameter.
+//
 //	  cloudeng.io/go/cmd/goannotate/annotators/functions.SimpleLogCall:
 //	  SimpleLogCall provides a functon call generator for generating calls to
 //	  functions with the same signature log.Callf and fmt.Printf.
@@ -71,6 +120,39 @@
 //	    functionName: name of the function to be invoked.
 //	    contextType:  type for the context parameter and result.
 //
+// cloudeng.io/go/cmd/goannotate/annotators.AddRecovery:
+// AddRecovery is an annotator to add panic recovery to functions. By default
+// a deferred call, typically to a function that recovers from and reports the
+// panic, is added as the first statement in the specified function. If inline
+// is set, a deferred recover block that converts a panic into an error assigned
+// to the function's named error result is added instead. Functions without a
+// named error result are annotated with the deferred call, if a call generator
+// is configured, or otherwise with a deferred recover block that logs the
+// panic using log.Printf.
+//
+//	type:                name of annotator type.
+//	name:                name of annotation.
+//	packages:            []packages to be annotated
+//	concurrency:         the number of goroutines to use, zero for a sensible
+//	                     default.
+//	interfaces:          []list of interfaces whose implementations are to be
+//	                     annoated.
+//	functions:           []list of functions that are to be annotated.
+//	includeMethods:      if set, methods as well as functions that match the function
+//	                     spec are annotated
+//	signature:           if set, only functions whose signatures match these ;
+//	                     separated predicates, eg. 'first=context.Context;results=error',
+//	                     are annotated.
+//	exclusions:          []regular expressions for files to be excluded.
+//	excludeGenerated:    if set, generated files are excluded.
+//	inline:              if set, an inline recover block that sets the function's
+//	                     named error result, or logs the panic if there is none,
+//	                     is added rather than a call to the call generator.
+//	noAnnotationComment: do not annotate functions that contain this comment
+//	callGenerator:       the spec for the function call to be generated, it is also
+//	                     used for inline annotations of functions without a named
+//	                     error result.
+//
 // cloudeng.io/go/cmd/goannotate/annotators.EnsureCopyrightAndLicense:
 // an annotator that ensures that a copyright and license notice is
 // present at the top of all files. It will not remove existing notices.
@@ -85,19 +167,83 @@
 //	updateCopyright: set to true to update existing copyright notice
 //	updateLicense:   set to true to update existing license notice
 //
+// cloudeng.io/go/cmd/goannotate/annotators.EnsureDocComments:
+// EnsureDocComments is an annotator that inserts a stub doc comment for every
+// exported function, method, type, constant and variable that does not have one.
+// The stub is generated from a text/template which is supplied with the Name,
+// Kind (one of function, method, type, const or var) and Interfaces fields.
+// Interfaces is only set for methods that implement one of the configured
+// interfaces and lists them, using their package names, separated by commas. Each
+// line of the expanded template is prefixed with '// '.
+//
+//	type:               name of annotator type.
+//	name:               name of annotation.
+//	packages:           []packages to be annotated
+//	concurrency:        the number of goroutines to use, zero for a sensible default.
+//	interfaces:         []list of interfaces whose implementations are documented
+//	                    as implementing them.
+//	template:           template for the stub comment, the default is '{{.Name}}
+//	                    ...'.
+//	implementsTemplate: template for the stub comment for methods that implement
+//	                    one of the interfaces, the default is '{{.Name}} implements
+//	                    {{.Interfaces}}.'.
+//	reportOnly:         if set, undocumented exported identifiers are listed per
+//	                    package rather than being annotated.
+//
+// cloudeng.io/go/cmd/goannotate/annotators.MarkDeprecatedUses:
+// MarkDeprecatedUses is an annotator that inserts a comment, '// TODO: migrate off
+// <name>' by default, on the line preceding every use of a deprecated function,
+// method, type, struct field or constant, ie. one whose doc comment contains a
+// paragraph that starts with 'Deprecated: ', declared in the annotated packages or
+// any of their dependencies. Uses within the package that declares the deprecated
+// object are ignored. The comment is generated from a text/template which is
+// supplied with the Name of the deprecated object and the Message of its
+// deprecation notice. A comment is inserted once per line for each deprecated
+// object used on that line and lines that are already preceded by the same comment
+// are skipped. Packages cannot be processed in batches, via --batch-size, since
+// all of the packages and their dependencies must be loaded at once.
+//
+//	type:        name of annotator type.
+//	name:        name of annotation.
+//	packages:    []packages to be annotated
+//	concurrency: the number of goroutines to use, zero for a sensible default.
+//	template:    template for the comment, the default is 'TODO: migrate off
+//	             {{.Name}}'.
+//	exclusions:  []regular expressions for files to be excluded.
+//
 // cloudeng.io/go/cmd/goannotate/annotators.RmLogCall:
-// an annotator that removes instances of calls to functions.
-//
-//	type:           name of annotator type.
-//	name:           name of annotation.
-//	packages:       []packages to be annotated
-//	concurrency:    the number of goroutines to use, zero for a sensible default.
-//	interfaces:     []list of interfaces whose implementations are to be annoated.
-//	functions:      []list of functions that are to be annotated.
-//	includeMethods: if set, methods as well as functions that match the function
-//	                spec are annotated
-//	functionNameRE: the function call (regexp) to be removed
-//	comment:        optional comment that must appear in the comments associated
-//	                with the function call if it is to be removed.
-//	deferred:       if set requires that the function to be removed must be defered.
+// RmLogCall is an annotator that removes instances of calls to functions
+// anywhere within the body of the functions matched by the locator. Assignments
+// whose right hand side is such a call may also be removed, along with any
+// paired method calls on the variables they assign, eg. defer span.End(). An
+// assignment is not removed if any of the variables it assigns are used
+// elsewhere, unless an existing variable is also passed to the removed call,
+// eg. ctx, span = tracer.Start(ctx). Variables that are left unused by the removals are replaced
+// by _, or have their declarations removed if their values can be computed
+// without side effects, eg. n, err := w.Write(buf) becomes _, _ = w.Write(buf).
+// Imports that are only referenced by the removed statements are deleted.
+//
+//	type:             name of annotator type.
+//	name:             name of annotation.
+//	packages:         []packages to be annotated
+//	concurrency:      the number of goroutines to use, zero for a sensible default.
+//	interfaces:       []list of interfaces whose implementations are to be annoated.
+//	functions:        []list of functions that are to be annotated.
+//	includeMethods:   if set, methods as well as functions that match the function
+//	                  spec are annotated
+//	signature:        if set, only functions whose signatures match these ; separated
+//	                  predicates, eg. 'first=context.Context;results=error', are
+//	                  annotated.
+//	exclusions:       []regular expressions for files to be excluded.
+//	excludeGenerated: if set, generated files are excluded.
+//	functionNameRE:   the function call (regexp) to be removed
+//	comment:          optional comment that must appear in the comments associated
+//	                  with the function call if it is to be removed.
+//	deferred:         if set requires that the function to be removed must be
+//	                  defered.
+//	assignments:      if set, assignments whose right hand side is a call to the
+//	                  function, eg. ctx, span := tracer.Start(ctx), are also removed.
+//	pairedCalls:      []methods (regexps) that, when called on a variable assigned
+//	                  by a removed assignment, are also removed, eg. End for defer
+//	                  span.End().
 package main
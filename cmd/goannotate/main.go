@@ -16,6 +16,7 @@ import (
 	"cloudeng.io/cmdutil"
 	"cloudeng.io/cmdutil/flags"
 	"cloudeng.io/go/cmd/goannotate/annotators"
+	"cloudeng.io/go/locate"
 )
 
 var (
@@ -25,6 +26,10 @@ var (
 	listFlag       bool
 	listConfigFlag bool
 	verboseFlag    bool
+	overlayFlag    string
+	dirFlag        string
+	allModulesFlag bool
+	batchSizeFlag  int
 )
 
 const defaultConfigFile = "config.yaml"
@@ -36,6 +41,10 @@ func init() {
 	flag.BoolVar(&listFlag, "list", false, "list available annotators")
 	flag.BoolVar(&listConfigFlag, "list-config", false, "list available annotations and their configurations")
 	flag.BoolVar(&verboseFlag, "verbose", false, "display verbose debug info")
+	flag.StringVar(&overlayFlag, "overlay", "", "if set, a JSON file, in the format used by go build -overlay, that specifies replacements for files with unsaved changes.")
+	flag.StringVar(&dirFlag, "dir", "", "if set, the directory in which packages are located, the current directory is used by default.")
+	flag.BoolVar(&allModulesFlag, "all-modules", false, "if set, packages from all of the modules under --dir, or those in its go.work workspace, may be annotated in a single run.")
+	flag.IntVar(&batchSizeFlag, "batch-size", 0, "if set, packages are loaded, annotated and released in dependency ordered batches of at most this many packages, with the edits for each batch being written before the next is loaded, so that the memory used is bounded by the size of a batch rather than the number of packages.")
 }
 
 func handleDebug(_ context.Context, cfg debug) (func(), error) {
@@ -64,6 +73,16 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	flag.Parse()
 	annotators.Verbose = verboseFlag
+	annotators.Dir = dirFlag
+	annotators.AllModules = allModulesFlag
+	annotators.BatchSize = batchSizeFlag
+	if len(overlayFlag) > 0 {
+		overlay, err := locate.ReadOverlayFile(overlayFlag)
+		if err != nil {
+			cmdutil.Exit("failed to read overlay: %v\n", err)
+		}
+		annotators.Overlay = overlay
+	}
 
 	if listFlag {
 		fmt.Println(describe(annotators.Registered()))
@@ -12,20 +12,158 @@
 //
 //	go run . --functions='.*' ./...
 //
+// Locate all functions and methods in ./... that accept a context.Context
+// as their first parameter and return only an error
+//
+//	go run . --functions='.*' --signature='first=context.Context;results=error' ./...
+//
+// Locate all implementations of io.Writer and their callers in ./...
+//
+//	go run . --interfaces io.Writer --references ./...
+//
 // Locate all comments in ./...
 //
 //	go run . --comments='.*' ./...
 //
+// Locate all //go:generate and //nolint directives in ./..., directives are
+// matched against the raw text of the comment since they are not included
+// in the text matched by --comments.
+//
+//	go run . --directives='^//(go:generate|nolint)' ./...
+//
+// Locate declarations using a query that combines predicates on their kind,
+// name, receiver, the interfaces they implement, signature, documentation,
+// file and comments, see cloudeng.io/go/locate/query for details.
+//
+//	go run . --query='kind:method receiver:*Server name:/^Handle/ doc:missing' ./...
+//
+// Print the functions, and calls, reachable from those that match a
+// <package>.<regex> specification using a static call graph built by one of
+// the static, cha or rta algorithms. --callgraph-direction=callers prints the
+// callers of the functions instead and --format=dot or --format=json may be
+// used to generate graphviz or JSON output.
+//
+//	go run . --callgraph=cloudeng.io/go/locate.Do$ --callgraph-algorithm=rta ./...
+//
+// Packages, and the packages or names matched by <package>.<regex> specs,
+// may be excluded using negative specs that start with - or !. Files may be
+// excluded using --exclude-files and generated files via --exclude-generated.
+//
+//	go run . --functions='.*' --exclude-generated ./... -./internal/...
+//
+// Packages that fail to load or type check cause golocate to fail unless
+// --tolerate-errors is specified, in which case the errors are reported and
+// the packages are excluded from locating interfaces and functions.
+//
+// Results may be cached across runs using --cache-dir, in which case only
+// packages whose files, or those of their dependencies, have changed are
+// reloaded. The cache can be listed via --cache-inspect and emptied via
+// --cache-clear.
+//
+//	go run . --cache-dir=$HOME/.cache/golocate --functions='.*' ./...
+//
+// Files with unsaved changes, eg. editor buffers, may be specified via
+// --overlay using the JSON format accepted by go build -overlay.
+//
+// All of the modules in a repository, or go.work workspace, can be searched
+// in a single run using --all-modules.
+//
+//	go run . --dir=$HOME/src/repo --all-modules --interfaces=io.Writer ./...
+//
+// Locate the exported functions, methods, types and constants in ./... that
+// are not referenced outside of their own package by any of the packages in
+// ./..., ignoring methods required to satisfy an interface. Declarations that
+// are documented with a //golocate:keep directive are not reported.
+//
+//	go run . --unused-exports ./...
+//
+// Locate all uses, in ./..., of the functions, methods, types, struct fields
+// and constants that are declared in ./... or any of its dependencies and
+// whose doc comments contain a paragraph that starts with 'Deprecated: ',
+// printing the deprecation notice for each. Uses within the package that
+// declares the deprecated object are not reported.
+//
+//	go run . --deprecated ./...
+//
+// Print the import graph of the packages in ./..., as text, DOT or JSON, and
+// check that it contains no import cycles and satisfies the layering rules
+// in rules.yaml, exiting with an error if it does not. See
+// cloudeng.io/go/locate/importgraph for the format of the rules.
+//
+//	go run . --imports --import-rules=rules.yaml --format=dot ./...
+//
+// Print the enclosing declaration, the identifier and its types.Object, and
+// the interfaces and functions related to it, at a <file>:<line>:<column>
+// position, eg. for use by editor commands.
+//
+//	go run . --at=locate/locate.go:120:6
+//
+// Packages may be loaded once and then queried repeatedly, eg. by an editor
+// integration, using --serve. Requests are JSON-RPC 2.0 calls, one per line
+// over stdin/stdout for --serve=stdio or HTTP POST requests for a local
+// address. The methods are interfaces, functions, comments, directives,
+// query, reload and packages, see cloudeng.io/go/locate/server for details.
+//
+//	go run . --serve=stdio ./...
+//	{"jsonrpc":"2.0","id":1,"method":"functions","params":{"Spec":"^Handle"}}
+//	{"jsonrpc":"2.0","id":2,"method":"reload"}
+//
 // The output of golocate is limited right now but is easily extended as
 // uses cases arise. Currently locating interface implementations is the
 // most useful.
 //
 // Command line flags:
 //
+//	-all-modules
+//	  	if set, packages from all of the modules under --dir, or those in its go.work workspace, are located in a single run.
+//	-at string
+//	  	if set, print the enclosing declaration, identifier, object and the related interfaces and functions at this <file>:<line>:<column> position, the package containing the file is used if no packages are specified.
+//	-cache-clear
+//	  	if set, remove all of the entries in the cache specified by --cache-dir.
+//	-cache-dir string
+//	  	if set, the results for each package are cached in this directory and reused for packages that have not changed.
+//	-cache-inspect
+//	  	if set, list the entries in the cache specified by --cache-dir.
+//	-callgraph string
+//	  	if set, print the call graph reachable from the functions that match this <package>.<regex> specification.
+//	-callgraph-algorithm string
+//	  	the algorithm used to build the call graph, one of static, cha or rta. (default "cha")
+//	-callgraph-direction string
+//	  	the direction in which the call graph is traversed from its roots, one of callees or callers. (default "callees")
 //	-comments string
 //	  	if set, find all comments that match this regular expression in the specified packages.
+//	-deprecated
+//	  	if set, find all uses, in the specified packages, of the deprecated functions, methods, types, fields and constants declared in those packages or their dependencies, ie. those whose doc comments contain a paragraph that starts with 'Deprecated: '.
+//	-dir string
+//	  	if set, the directory in which packages are located, the current directory is used by default.
+//	-directives string
+//	  	if set, find all directives, such as //go:generate or //nolint:errcheck, whose raw text matches this regular expression in the specified packages.
+//	-exclude-files string
+//	  	if set, files whose absolute filename matches this regular expression are excluded, eg. '/(testdata|vendor)/'.
+//	-exclude-generated
+//	  	if set, generated files are excluded.
+//	-format string
+//	  	the output format for --callgraph, --imports, --query, --unused-exports or --deprecated, one of text, dot or json, dot is only supported by --callgraph and --imports. (default "text")
 //	-functions string
 //	  	if set, find all functions whose name matches this regular expression.
+//	-import-rules string
+//	  	if set, with --imports, a YAML file of layering rules, such as 'api/... must not import storage/...', that the imports must satisfy, golocate exits with an error if any are violated. See cloudeng.io/go/locate/importgraph for the format.
+//	-imports
+//	  	if set, print the import graph of the specified packages and report any import cycles between them, golocate exits with an error if there are any cycles. Packages with errors are always tolerated, as per --tolerate-errors, since go list fails for packages with import cycles.
 //	-interfaces string
 //	  	if set, find all implementations of these interfaces in the speficied packages. The package local component of the interface name is treated as a regular expression
+//	-overlay string
+//	  	if set, a JSON file, in the format used by go build -overlay, that specifies replacements for files with unsaved changes.
+//	-query string
+//	  	if set, find all declarations that satisfy this query, eg. 'kind:method receiver:*Server name:/^Handle/ doc:missing'. See cloudeng.io/go/locate/query for the supported predicates.
+//	-references
+//	  	if set, with --functions or --interfaces, also find all references to the located functions, interface methods and implementations in the specified packages.
+//	-serve string
+//	  	if set, load the specified packages once and answer JSON-RPC 2.0 requests, for interfaces, functions, comments, directives, queries and to reload changed packages, either over stdin/stdout if set to 'stdio' or via HTTP POST requests on this address otherwise, eg. 'localhost:8080' or 'unix:/tmp/golocate.sock'.
+//	-signature string
+//	  	if set, with --functions, only functions whose signatures match these ; separated predicates are located, eg. 'first=context.Context;results=error'. The predicates are param=<type>, result=<type>, params=<type>,..., results=<type>,..., first=<type>, recv=<type> and variadic.
+//	-tolerate-errors
+//	  	if set, packages that fail to load or type check are reported and otherwise ignored rather than causing golocate to fail.
+//	-unused-exports
+//	  	if set, find the exported functions, methods, types and constants that are not referenced outside of their own package by any of the specified packages. Methods required to satisfy an interface and declarations documented with a //golocate:keep directive are ignored.
 package main
@@ -22,9 +22,88 @@ Locate all instances of io.Writer ./...
 Locate all exported functions in ./...
   go run . --functions='.*' ./...
 
+Locate all functions and methods in ./... that accept a context.Context
+as their first parameter and return only an error
+  go run . --functions='.*' --signature='first=context.Context;results=error' ./...
+
+Locate all implementations of io.Writer and their callers in ./...
+  go run . --interfaces io.Writer --references ./...
+
 Locate all comments in ./...
   go run . --comments='.*' ./...
 
+Locate all //go:generate and //nolint directives in ./..., directives are
+matched against the raw text of the comment since they are not included
+in the text matched by --comments.
+  go run . --directives='^//(go:generate|nolint)' ./...
+
+Locate declarations using a query that combines predicates on their kind,
+name, receiver, the interfaces they implement, signature, documentation,
+file and comments, see cloudeng.io/go/locate/query for details.
+  go run . --query='kind:method receiver:*Server name:/^Handle/ doc:missing' ./...
+
+Print the functions, and calls, reachable from those that match a
+<package>.<regex> specification using a static call graph built by one of
+the static, cha or rta algorithms. --callgraph-direction=callers prints the
+callers of the functions instead and --format=dot or --format=json may be
+used to generate graphviz or JSON output.
+  go run . --callgraph=cloudeng.io/go/locate.Do$ --callgraph-algorithm=rta ./...
+
+Packages, and the packages or names matched by <package>.<regex> specs,
+may be excluded using negative specs that start with - or !. Files may be
+excluded using --exclude-files and generated files via --exclude-generated.
+  go run . --functions='.*' --exclude-generated ./... -./internal/...
+
+Packages that fail to load or type check cause golocate to fail unless
+--tolerate-errors is specified, in which case the errors are reported and
+the packages are excluded from locating interfaces and functions.
+
+Results may be cached across runs using --cache-dir, in which case only
+packages whose files, or those of their dependencies, have changed are
+reloaded. The cache can be listed via --cache-inspect and emptied via
+--cache-clear.
+  go run . --cache-dir=$HOME/.cache/golocate --functions='.*' ./...
+
+Files with unsaved changes, eg. editor buffers, may be specified via
+--overlay using the JSON format accepted by go build -overlay.
+
+All of the modules in a repository, or go.work workspace, can be searched
+in a single run using --all-modules.
+  go run . --dir=$HOME/src/repo --all-modules --interfaces=io.Writer ./...
+
+Locate the exported functions, methods, types and constants in ./... that
+are not referenced outside of their own package by any of the packages in
+./..., ignoring methods required to satisfy an interface. Declarations that
+are documented with a //golocate:keep directive are not reported.
+  go run . --unused-exports ./...
+
+Locate all uses, in ./..., of the functions, methods, types, struct fields
+and constants that are declared in ./... or any of its dependencies and
+whose doc comments contain a paragraph that starts with 'Deprecated: ',
+printing the deprecation notice for each. Uses within the package that
+declares the deprecated object are not reported.
+  go run . --deprecated ./...
+
+Print the import graph of the packages in ./..., as text, DOT or JSON, and
+check that it contains no import cycles and satisfies the layering rules
+in rules.yaml, exiting with an error if it does not. See
+cloudeng.io/go/locate/importgraph for the format of the rules.
+  go run . --imports --import-rules=rules.yaml --format=dot ./...
+
+Print the enclosing declaration, the identifier and its types.Object, and
+the interfaces and functions related to it, at a <file>:<line>:<column>
+position, eg. for use by editor commands.
+  go run . --at=locate/locate.go:120:6
+
+Packages may be loaded once and then queried repeatedly, eg. by an editor
+integration, using --serve. Requests are JSON-RPC 2.0 calls, one per line
+over stdin/stdout for --serve=stdio or HTTP POST requests for a local
+address. The methods are interfaces, functions, comments, directives,
+query, reload and packages, see cloudeng.io/go/locate/server for details.
+  go run . --serve=stdio ./...
+  {"jsonrpc":"2.0","id":1,"method":"functions","params":{"Spec":"^Handle"}}
+  {"jsonrpc":"2.0","id":2,"method":"reload"}
+
 The output of golocate is limited right now but is easily extended as
 uses cases arise. Currently locating interface implementations is the
 most useful.
@@ -6,37 +6,147 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"go/ast"
-	"go/types"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"cloudeng.io/cmdutil"
 	"cloudeng.io/cmdutil/flags"
 	"cloudeng.io/go/locate"
-	"cloudeng.io/go/locate/locateutil"
+	"cloudeng.io/go/locate/callgraph"
+	"cloudeng.io/go/locate/importgraph"
+	"cloudeng.io/go/locate/query"
+	"cloudeng.io/go/locate/server"
+	"cloudeng.io/go/locate/unused"
 	"golang.org/x/tools/go/packages"
 )
 
 var (
-	interfaceFlag string
-	commentFlag   string
-	functionFlag  string
+	interfaceFlag      string
+	commentFlag        string
+	directivesFlag     string
+	queryFlag          string
+	functionFlag       string
+	tolerateErrorsFlag bool
+	cacheDirFlag       string
+	cacheInspectFlag   bool
+	cacheClearFlag     bool
+	overlayFlag        string
+	dirFlag            string
+	allModulesFlag     bool
+	referencesFlag     bool
+	callgraphFlag      string
+	algorithmFlag      string
+	directionFlag      string
+	formatFlag         string
+	signatureFlag      string
+	excludeFilesFlag   string
+	excludeGenFlag     bool
+	serveFlag          string
+	atFlag             string
+	unusedExportsFlag  bool
+	deprecatedFlag     bool
+	importsFlag        bool
+	importRulesFlag    string
 )
 
 func init() {
 	flag.StringVar(&interfaceFlag, "interfaces", "", "if set, find all implementations of these interfaces in the speficied packages. The package local component of the interface name is treated as a regular expression")
 	flag.StringVar(&commentFlag, "comments", "", "if set, find all comments that match this regular expression in the specified packages.")
+	flag.StringVar(&directivesFlag, "directives", "", "if set, find all directives, such as //go:generate or //nolint:errcheck, whose raw text matches this regular expression in the specified packages.")
+	flag.StringVar(&queryFlag, "query", "", "if set, find all declarations that satisfy this query, eg. 'kind:method receiver:*Server name:/^Handle/ doc:missing'. See cloudeng.io/go/locate/query for the supported predicates.")
 	flag.StringVar(&functionFlag, "functions", "", "if set, find all functions whose name matches this regular expression.")
+	flag.BoolVar(&tolerateErrorsFlag, "tolerate-errors", false, "if set, packages that fail to load or type check are reported and otherwise ignored rather than causing golocate to fail.")
+	flag.StringVar(&cacheDirFlag, "cache-dir", "", "if set, the results for each package are cached in this directory and reused for packages that have not changed.")
+	flag.BoolVar(&cacheInspectFlag, "cache-inspect", false, "if set, list the entries in the cache specified by --cache-dir.")
+	flag.BoolVar(&cacheClearFlag, "cache-clear", false, "if set, remove all of the entries in the cache specified by --cache-dir.")
+	flag.StringVar(&overlayFlag, "overlay", "", "if set, a JSON file, in the format used by go build -overlay, that specifies replacements for files with unsaved changes.")
+	flag.StringVar(&dirFlag, "dir", "", "if set, the directory in which packages are located, the current directory is used by default.")
+	flag.BoolVar(&allModulesFlag, "all-modules", false, "if set, packages from all of the modules under --dir, or those in its go.work workspace, are located in a single run.")
+	flag.BoolVar(&referencesFlag, "references", false, "if set, with --functions or --interfaces, also find all references to the located functions, interface methods and implementations in the specified packages.")
+	flag.StringVar(&signatureFlag, "signature", "", "if set, with --functions, only functions whose signatures match these ; separated predicates are located, eg. 'first=context.Context;results=error'. The predicates are param=<type>, result=<type>, params=<type>,..., results=<type>,..., first=<type>, recv=<type> and variadic.")
+	flag.StringVar(&excludeFilesFlag, "exclude-files", "", "if set, files whose absolute filename matches this regular expression are excluded, eg. '/(testdata|vendor)/'.")
+	flag.BoolVar(&excludeGenFlag, "exclude-generated", false, "if set, generated files are excluded.")
+	flag.StringVar(&callgraphFlag, "callgraph", "", "if set, print the call graph reachable from the functions that match this <package>.<regex> specification.")
+	flag.StringVar(&algorithmFlag, "callgraph-algorithm", "cha", "the algorithm used to build the call graph, one of static, cha or rta.")
+	flag.StringVar(&directionFlag, "callgraph-direction", "callees", "the direction in which the call graph is traversed from its roots, one of callees or callers.")
+	flag.StringVar(&serveFlag, "serve", "", "if set, load the specified packages once and answer JSON-RPC 2.0 requests, for interfaces, functions, comments, directives, queries and to reload changed packages, either over stdin/stdout if set to 'stdio' or via HTTP POST requests on this address otherwise, eg. 'localhost:8080' or 'unix:/tmp/golocate.sock'.")
+	flag.StringVar(&atFlag, "at", "", "if set, print the enclosing declaration, identifier, object and the related interfaces and functions at this <file>:<line>:<column> position, the package containing the file is used if no packages are specified.")
+	flag.BoolVar(&unusedExportsFlag, "unused-exports", false, "if set, find the exported functions, methods, types and constants that are not referenced outside of their own package by any of the specified packages. Methods required to satisfy an interface and declarations documented with a //golocate:keep directive are ignored.")
+	flag.BoolVar(&deprecatedFlag, "deprecated", false, "if set, find all uses, in the specified packages, of the deprecated functions, methods, types, fields and constants declared in those packages or their dependencies, ie. those whose doc comments contain a paragraph that starts with 'Deprecated: '.")
+	flag.BoolVar(&importsFlag, "imports", false, "if set, print the import graph of the specified packages and report any import cycles between them, golocate exits with an error if there are any cycles. Packages with errors are always tolerated, as per --tolerate-errors, since go list fails for packages with import cycles.")
+	flag.StringVar(&importRulesFlag, "import-rules", "", "if set, with --imports, a YAML file of layering rules, such as 'api/... must not import storage/...', that the imports must satisfy, golocate exits with an error if any are violated. See cloudeng.io/go/locate/importgraph for the format.")
+	flag.StringVar(&formatFlag, "format", "text", "the output format for --callgraph, --imports, --query, --unused-exports or --deprecated, one of text, dot or json, dot is only supported by --callgraph and --imports.")
+}
+
+func newLocator(opts ...locate.Option) *locate.T {
+	return locate.New(append(opts, locatorOptions(true)...)...)
+}
+
+// locatorOptions returns the options common to all of golocate's uses
+// of locate.T, including --cache-dir if cache is set.
+func locatorOptions(cache bool) []locate.Option {
+	opts := []locate.Option{locate.Dir(dirFlag), locate.AllModules(allModulesFlag), locate.ExcludeGenerated(excludeGenFlag)}
+	if len(excludeFilesFlag) > 0 {
+		re, err := regexp.Compile(excludeFilesFlag)
+		if err != nil {
+			cmdutil.Exit("failed to compile --exclude-files: %v", err)
+		}
+		opts = append(opts, locate.ExcludeFiles(re))
+	}
+	if tolerateErrorsFlag {
+		opts = append(opts, locate.TolerateErrors())
+	}
+	if cache && len(cacheDirFlag) > 0 {
+		opts = append(opts, locate.CacheDir(cacheDirFlag))
+	}
+	if len(overlayFlag) > 0 {
+		overlay, err := locate.ReadOverlayFile(overlayFlag)
+		if err != nil {
+			cmdutil.Exit("failed to read overlay: %v", err)
+		}
+		opts = append(opts, locate.Overlay(overlay))
+	}
+	return opts
+}
+
+func reportDiagnostics(locator *locate.T) {
+	locator.WalkDiagnostics(func(pkgPath string, errs []packages.Error) {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", pkgPath, err)
+		}
+	})
+}
+
+// isSet returns a non-empty string if val is true since
+// flags.ExactlyOneSet does not accept bools.
+func isSet(val bool) string {
+	if val {
+		return "set"
+	}
+	return ""
 }
 
 func main() {
 	ctx := context.Background()
 	flag.Parse()
 
-	if !flags.ExactlyOneSet(commentFlag, functionFlag, interfaceFlag) {
-		cmdutil.Exit("only one of --comments, --functions or --interfaces can be set")
+	if cacheInspectFlag || cacheClearFlag {
+		if err := handleCache(); err != nil {
+			cmdutil.Exit("error: %v", err)
+		}
+		return
+	}
+	if !flags.ExactlyOneSet(commentFlag, directivesFlag, functionFlag, interfaceFlag, callgraphFlag, queryFlag, serveFlag, atFlag, isSet(unusedExportsFlag), isSet(deprecatedFlag), isSet(importsFlag)) {
+		cmdutil.Exit("only one of --comments, --directives, --functions, --interfaces, --callgraph, --query, --serve, --at, --unused-exports, --deprecated or --imports can be set")
 	}
 	var err error
 	if len(interfaceFlag) > 0 {
@@ -45,60 +155,465 @@ func main() {
 	if len(commentFlag) > 0 {
 		err = handleComments(ctx, commentFlag, flag.Args())
 	}
+	if len(directivesFlag) > 0 {
+		err = handleDirectives(ctx, directivesFlag, flag.Args())
+	}
 	if len(functionFlag) > 0 {
 		err = handleFunctions(ctx, functionFlag, flag.Args())
 	}
+	if len(queryFlag) > 0 {
+		err = handleQuery(ctx, queryFlag, flag.Args())
+	}
+	if len(callgraphFlag) > 0 {
+		err = handleCallgraph(ctx, callgraphFlag, flag.Args())
+	}
+	if len(serveFlag) > 0 {
+		err = handleServe(ctx, serveFlag, flag.Args())
+	}
+	if len(atFlag) > 0 {
+		err = handleAt(ctx, atFlag, flag.Args())
+	}
+	if unusedExportsFlag {
+		err = handleUnusedExports(ctx, flag.Args())
+	}
+	if deprecatedFlag {
+		err = handleDeprecated(ctx, flag.Args())
+	}
+	if importsFlag {
+		err = handleImports(ctx, flag.Args())
+	}
 	if err != nil {
 		cmdutil.Exit("error: %v", err)
 	}
 }
 
+func handleCache() error {
+	if len(cacheDirFlag) == 0 {
+		return fmt.Errorf("--cache-dir must be specified")
+	}
+	if cacheInspectFlag {
+		err := locate.WalkCache(cacheDirFlag, func(entry locate.CacheEntry) {
+			fmt.Printf("%v: %v: %v locations, created %v\n", entry.Package, entry.Key, len(entry.Locations), entry.Created.Format(time.RFC3339))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if cacheClearFlag {
+		return locate.ClearCache(cacheDirFlag)
+	}
+	return nil
+}
+
 func handleInterfaces(ctx context.Context, ifcs string, pkgs []string) error {
-	locator := locate.New()
+	locator := newLocator()
 	locator.AddPackages(pkgs...)
 	locator.AddInterfaces(ifcs)
+	if referencesFlag {
+		locator.AddCallers(pkgs...)
+	}
 	if err := locator.Do(ctx); err != nil {
 		cmdutil.Exit("locator.Do failed: %v", err)
 	}
-	locator.WalkFunctions(func(_ string, pkg *packages.Package, _ *ast.File, fn *types.Func, _ *ast.FuncDecl, implements []string) {
-		for _, ifc := range implements {
-			pos := pkg.Fset.PositionFor(fn.Pos(), false)
-			fmt.Printf("%v[%s]: %s\n", fn, ifc, pos)
+	reportDiagnostics(locator)
+	// Implementing types are listed as well as their methods since a type
+	// may implement an interface solely via embedding.
+	locator.WalkLocations(func(loc locate.Location) {
+		if loc.Kind != locate.HasFunction && loc.Kind != locate.HasImplementation {
+			return
+		}
+		for _, ifc := range loc.Implements {
+			fmt.Printf("%v[%s]: %s\n", loc.Detail, ifc, loc.Position)
 		}
 	})
+	printReferences(locator, func(string) bool { return true })
 	return nil
 }
 
 func handleComments(ctx context.Context, comments string, pkgs []string) error {
-	locator := locate.New()
+	locator := newLocator()
 	locator.AddPackages(pkgs...)
 	locator.AddComments(comments)
 	if err := locator.Do(ctx); err != nil {
 		cmdutil.Exit("locator.Do failed: %v", err)
 	}
-	locator.WalkComments(func(_, absoluteFilename string, node ast.Node, cg *ast.CommentGroup, pkg *packages.Package, _ *ast.File) {
-		pos := pkg.Fset.PositionFor(cg.Pos(), false)
-		fmt.Printf("%s: %T %s\n", absoluteFilename, node, pos)
+	reportDiagnostics(locator)
+	locator.WalkLocations(func(loc locate.Location) {
+		if loc.Kind != locate.HasComment {
+			return
+		}
+		fmt.Printf("%s: %s %s\n", loc.Position.Filename, loc.Detail, loc.Position)
 	})
 	return nil
 }
 
-func handleFunctions(ctx context.Context, functions string, pkgs []string) error {
-	re, err := regexp.Compile(functions)
+func handleDirectives(ctx context.Context, directives string, pkgs []string) error {
+	locator := newLocator(locate.Directives(true))
+	locator.AddPackages(pkgs...)
+	locator.AddComments(directives)
+	if err := locator.Do(ctx); err != nil {
+		cmdutil.Exit("locator.Do failed: %v", err)
+	}
+	reportDiagnostics(locator)
+	// Use WalkLocations rather than WalkDirectives so that cached results
+	// are included.
+	locator.WalkLocations(func(loc locate.Location) {
+		if loc.Kind != locate.HasDirective {
+			return
+		}
+		if len(loc.Decl) == 0 {
+			fmt.Printf("%s: %s\n", loc.Position, loc.Detail)
+			return
+		}
+		fmt.Printf("%s: %s [%s]\n", loc.Position, loc.Detail, loc.Decl)
+	})
+	return nil
+}
+
+func handleQuery(ctx context.Context, q string, pkgs []string) error {
+	parsed, err := query.Parse(q)
 	if err != nil {
 		return err
 	}
-	locator := locate.New()
+	// Queries are evaluated against the parsed and type checked packages
+	// and hence cannot use the cache.
+	results, err := parsed.Locate(ctx, pkgs, locatorOptions(false)...)
+	if err != nil {
+		cmdutil.Exit("locator.Do failed: %v", err)
+	}
+	switch formatFlag {
+	case "text":
+		for _, r := range results {
+			if len(r.Implements) > 0 {
+				fmt.Printf("%v %v%v: %v\n", r.Kind, r.Name, r.Implements, r.Position)
+				continue
+			}
+			fmt.Printf("%v %v: %v\n", r.Kind, r.Name, r.Position)
+		}
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	default:
+		return fmt.Errorf("unsupported format for --query: %q, must be text or json", formatFlag)
+	}
+	return nil
+}
+
+func handleUnusedExports(ctx context.Context, pkgs []string) error {
+	// References are determined from the loaded packages and hence
+	// cannot use the cache.
+	found, err := unused.Find(ctx, pkgs, locatorOptions(false)...)
+	if err != nil {
+		cmdutil.Exit("locator.Do failed: %v", err)
+	}
+	switch formatFlag {
+	case "text":
+		for _, u := range found {
+			fmt.Printf("%v %v: %v\n", u.Kind, u.Name, u.Position)
+		}
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(found)
+	default:
+		return fmt.Errorf("unsupported format for --unused-exports: %q, must be text or json", formatFlag)
+	}
+	return nil
+}
+
+func handleDeprecated(ctx context.Context, pkgs []string) error {
+	if formatFlag != "text" && formatFlag != "json" {
+		return fmt.Errorf("unsupported format for --deprecated: %q, must be text or json", formatFlag)
+	}
+	// The dependencies of the packages are loaded and hence the cache
+	// cannot be used.
+	locator := locate.New(locatorOptions(false)...)
+	locator.AddDeprecatedUses(pkgs...)
+	if err := locator.Do(ctx); err != nil {
+		cmdutil.Exit("locator.Do failed: %v", err)
+	}
+	reportDiagnostics(locator)
+	uses := []locate.Location{}
+	locator.WalkLocations(func(loc locate.Location) {
+		if loc.Kind == locate.HasDeprecatedUse {
+			uses = append(uses, loc)
+		}
+	})
+	if formatFlag == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(uses)
+	}
+	for _, loc := range uses {
+		if len(loc.Decl) == 0 {
+			fmt.Printf("%s: %s: %s\n", loc.Position, loc.Name, loc.Detail)
+			continue
+		}
+		fmt.Printf("%s: %s [%s]: %s\n", loc.Position, loc.Name, loc.Decl, loc.Detail)
+	}
+	return nil
+}
+
+func handleImports(ctx context.Context, pkgs []string) error {
+	var rules *importgraph.Rules
+	if len(importRulesFlag) > 0 {
+		var err error
+		if rules, err = importgraph.ReadRules(importRulesFlag); err != nil {
+			return err
+		}
+	}
+	var write func(*importgraph.Graph) error
+	switch formatFlag {
+	case "text":
+		write = func(g *importgraph.Graph) error { return g.WriteText(os.Stdout) }
+	case "dot":
+		write = func(g *importgraph.Graph) error { return g.WriteDOT(os.Stdout) }
+	case "json":
+		write = func(g *importgraph.Graph) error { return g.WriteJSON(os.Stdout) }
+	default:
+		return fmt.Errorf("unsupported format: %q, must be one of text, dot or json", formatFlag)
+	}
+	// The cache is not used since the packages must be loaded to
+	// determine their imports and errors are always tolerated since
+	// go list fails for packages with import cycles.
+	locator := locate.New(append(locatorOptions(false), locate.TolerateErrors())...)
 	locator.AddPackages(pkgs...)
 	if err := locator.Do(ctx); err != nil {
 		cmdutil.Exit("locator.Do failed: %v", err)
 	}
+	reportDiagnostics(locator)
+	graph := importgraph.Build(locator)
+	if err := write(graph.Internal()); err != nil {
+		return err
+	}
+	failed := false
+	for _, cycle := range graph.Cycles {
+		fmt.Fprintf(os.Stderr, "import cycle: %v\n", strings.Join(cycle, ", "))
+		failed = true
+	}
+	if rules != nil {
+		for _, v := range rules.Check(graph) {
+			fmt.Fprintf(os.Stderr, "%v: %v imports %v: violates: %v\n", v.Position, v.Importer, v.Imported, v.Rule)
+			failed = true
+		}
+	}
+	if failed {
+		return fmt.Errorf("import cycles or layering violations found")
+	}
+	return nil
+}
+
+func handleFunctions(ctx context.Context, functions string, pkgs []string) error {
+	re, err := regexp.Compile(functions)
+	if err != nil {
+		return err
+	}
 	// option for methods/functions only.
-	locator.WalkPackages(func(pkg *packages.Package) {
-		funcs := locateutil.Functions(pkg, re, false)
-		for _, fn := range funcs {
-			fmt.Printf("%v: %v\n", fn.Type.FullName(), fn.Position)
+	locator := newLocator(locate.IncludeMethods(true), locate.IgnoreMissingFuctionsEtc())
+	for _, pkg := range pkgs {
+		if len(signatureFlag) > 0 && !locate.IsExclusion(pkg) {
+			pkg += ";" + signatureFlag
+		}
+		locator.AddFunctions(pkg)
+	}
+	if referencesFlag {
+		locator.AddCallers(pkgs...)
+	}
+	if err := locator.Do(ctx); err != nil {
+		cmdutil.Exit("locator.Do failed: %v", err)
+	}
+	reportDiagnostics(locator)
+	locator.WalkLocations(func(loc locate.Location) {
+		if loc.Kind != locate.HasFunction {
+			return
 		}
+		if !matchesName(re, loc.Name) {
+			return
+		}
+		fmt.Printf("%v: %v\n", loc.Name, loc.Position)
 	})
+	printReferences(locator, func(name string) bool { return matchesName(re, name) })
+	return nil
+}
+
+// matchesName returns true if the package local component of the
+// fully qualified function or method name matches re.
+func matchesName(re *regexp.Regexp, name string) bool {
+	return re.MatchString(name[strings.LastIndex(name, ".")+1:])
+}
+
+func printReferences(locator *locate.T, match func(string) bool) {
+	locator.WalkReferences(func(ref locate.Reference) {
+		if !match(ref.Function) {
+			return
+		}
+		caller := ref.Caller
+		if len(caller) == 0 {
+			caller = "<package>"
+		}
+		via := ""
+		if len(ref.Interface) > 0 {
+			via = " via " + ref.Interface
+		}
+		fmt.Printf("%v: %v: %v%v\n", ref.Position, caller, ref.Function, via)
+	})
+}
+
+func handleCallgraph(ctx context.Context, roots string, pkgs []string) error {
+	algorithm, err := callgraph.ParseAlgorithm(algorithmFlag)
+	if err != nil {
+		return err
+	}
+	var direction callgraph.Direction
+	switch directionFlag {
+	case "callees":
+		direction = callgraph.Callees
+	case "callers":
+		direction = callgraph.Callers
+	default:
+		return fmt.Errorf("unsupported call graph direction: %q, must be one of callees or callers", directionFlag)
+	}
+	var write func(*callgraph.Result) error
+	switch formatFlag {
+	case "text":
+		write = func(r *callgraph.Result) error { return r.WriteText(os.Stdout) }
+	case "dot":
+		write = func(r *callgraph.Result) error { return r.WriteDOT(os.Stdout) }
+	case "json":
+		write = func(r *callgraph.Result) error { return r.WriteJSON(os.Stdout) }
+	default:
+		return fmt.Errorf("unsupported format: %q, must be one of text, dot or json", formatFlag)
+	}
+	// The call graph requires the dependencies of the specified packages
+	// to be loaded and the roots include methods.
+	locator := newLocator(locate.LoadDependencies(), locate.IncludeMethods(true))
+	locator.AddFunctions(roots)
+	locator.AddPackages(pkgs...)
+	if err := locator.Do(ctx); err != nil {
+		cmdutil.Exit("locator.Do failed: %v", err)
+	}
+	reportDiagnostics(locator)
+	graph, err := callgraph.Build(locator, algorithm)
+	if err != nil {
+		return err
+	}
+	return write(graph.Reachable(direction))
+}
+
+func handleServe(ctx context.Context, addr string, pkgs []string) error {
+	if allModulesFlag {
+		return fmt.Errorf("--all-modules is not supported by --serve")
+	}
+	// Requests are answered using the loaded packages and hence
+	// cannot use the cache.
+	srv := server.New(pkgs, locatorOptions(false)...)
+	if err := srv.Load(ctx); err != nil {
+		return err
+	}
+	if addr == "stdio" {
+		return srv.Serve(ctx, os.Stdin, os.Stdout)
+	}
+	network := "tcp"
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		network, addr = "unix", path
+	}
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "golocate: serving on %v\n", ln.Addr())
+	return http.Serve(ln, srv)
+}
+
+// parsePosition parses a <file>:<line>:<column> position.
+func parsePosition(pos string) (string, int, int, error) {
+	parts := strings.Split(pos, ":")
+	if len(parts) < 3 {
+		return "", 0, 0, fmt.Errorf("invalid position: %q, must be <file>:<line>:<column>", pos)
+	}
+	n := len(parts)
+	line, err := strconv.Atoi(parts[n-2])
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("invalid line in position: %q: %v", pos, err)
+	}
+	column, err := strconv.Atoi(parts[n-1])
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("invalid column in position: %q: %v", pos, err)
+	}
+	return strings.Join(parts[:n-2], ":"), line, column, nil
+}
+
+// packageDir returns a go list expression for the directory containing
+// filename relative to --dir.
+func packageDir(filename string) (string, error) {
+	base, err := filepath.Abs(dirFlag)
+	if err != nil {
+		return "", err
+	}
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(base, dir)
+	if err != nil {
+		return "", err
+	}
+	rel = filepath.ToSlash(rel)
+	if strings.HasPrefix(rel, "../") || rel == ".." {
+		return rel, nil
+	}
+	return "./" + rel, nil
+}
+
+func handleAt(ctx context.Context, pos string, pkgs []string) error {
+	filename, line, column, err := parsePosition(pos)
+	if err != nil {
+		return err
+	}
+	if len(pkgs) == 0 {
+		pkg, err := packageDir(filename)
+		if err != nil {
+			return err
+		}
+		pkgs = []string{pkg}
+	}
+	// Locate all of the interfaces and functions in the packages so that
+	// those related to the object at the position can be reported. The
+	// cache is not used since the file containing the position must be
+	// loaded.
+	locator := locate.New(append(locatorOptions(false), locate.IncludeMethods(true), locate.IgnoreMissingFuctionsEtc())...)
+	locator.AddInterfaces(pkgs...)
+	locator.AddFunctions(pkgs...)
+	locator.AddPackages(pkgs...)
+	if err := locator.Do(ctx); err != nil {
+		cmdutil.Exit("locator.Do failed: %v", err)
+	}
+	reportDiagnostics(locator)
+	offset, err := locator.Offset(filename, line, column)
+	if err != nil {
+		return err
+	}
+	sym, err := locator.At(filename, offset)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("position: %v\n", sym.Position)
+	if sym.Decl != nil {
+		fmt.Printf("decl: %v\n", sym.DeclName)
+	}
+	if sym.Ident != nil {
+		fmt.Printf("ident: %v\n", sym.Ident.Name)
+	}
+	if sym.Object != nil {
+		fmt.Printf("object: %v\n", sym.Object)
+		fmt.Printf("name: %v @ %v\n", sym.Name, sym.ObjectPosition)
+	}
+	for _, ifc := range sym.Interfaces {
+		fmt.Printf("interface: %v\n", ifc)
+	}
+	for _, fn := range sym.Functions {
+		fmt.Printf("function: %v\n", fn)
+	}
 	return nil
 }
@@ -0,0 +1,49 @@
+// Usage of gonotes:
+//
+// gonotes is a utility for tracking notes, such as TODO(owner), FIXME
+// and BUG(owner), in go source comments. Each note is reported with its
+// marker, owner, the declaration it is associated with, its position and,
+// optionally, its age as reported by git blame.
+//
+// List all TODO, FIXME and BUG notes in ./...
+//
+//	go run . ./...
+//
+// List the notes for custom markers
+//
+//	go run . --markers=TODO,HACK,XXX ./...
+//
+// List the notes owned by alice that are more than 90 days old as markdown
+//
+//	go run . --owner=alice --older-than=90d --format=markdown ./...
+//
+// A note starts at the beginning of a comment line with a marker, optionally
+// followed by an owner in parentheses and/or a colon, and continues until a
+// blank line, the start of another note or the end of the comment.
+//
+// The output format is one of text, json or markdown. --blame annotates each
+// note with the date of the line it appears on as reported by git blame,
+// which is implied by --older-than. Ages may be specified in days, eg. 30d,
+// or using go's time.Duration format.
+//
+// Command line flags:
+//
+//	-blame
+//	  	if set, annotate each note with its age as reported by git blame.
+//	-dir string
+//	  	if set, the directory in which packages are located, the current directory is used by default.
+//	-exclude-files string
+//	  	if set, files whose absolute filename matches this regular expression are excluded, eg. '/(testdata|vendor)/'.
+//	-exclude-generated
+//	  	if set, generated files are excluded.
+//	-format string
+//	  	the output format, one of text, json or markdown. (default "text")
+//	-markers string
+//	  	comma separated list of the markers for the notes to be located. (default "TODO,FIXME,BUG")
+//	-older-than string
+//	  	if set, only notes that are older than this age, eg. 30d or 72h, as reported by git blame are listed.
+//	-owner string
+//	  	if set, only notes whose owner matches this regular expression are listed.
+//	-tolerate-errors
+//	  	if set, packages that fail to load or type check are reported and otherwise ignored rather than causing gonotes to fail.
+package main
@@ -0,0 +1,46 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const usage = `
+gonotes is a utility for tracking notes, such as TODO(owner), FIXME
+and BUG(owner), in go source comments. Each note is reported with its
+marker, owner, the declaration it is associated with, its position and,
+optionally, its age as reported by git blame.
+
+List all TODO, FIXME and BUG notes in ./...
+  go run . ./...
+
+List the notes for custom markers
+  go run . --markers=TODO,HACK,XXX ./...
+
+List the notes owned by alice that are more than 90 days old as markdown
+  go run . --owner=alice --older-than=90d --format=markdown ./...
+
+A note starts at the beginning of a comment line with a marker, optionally
+followed by an owner in parentheses and/or a colon, and continues until a
+blank line, the start of another note or the end of the comment.
+
+The output format is one of text, json or markdown. --blame annotates each
+note with the date of the line it appears on as reported by git blame,
+which is implied by --older-than. Ages may be specified in days, eg. 30d,
+or using go's time.Duration format.
+`
+
+func init() {
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage of %s:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(out, "%s\nCommand line flags:\n", usage)
+		flag.PrintDefaults()
+	}
+}
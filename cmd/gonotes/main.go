@@ -0,0 +1,182 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloudeng.io/cmdutil"
+	"cloudeng.io/go/locate"
+	"cloudeng.io/go/locate/notes"
+)
+
+var (
+	markersFlag        string
+	ownerFlag          string
+	olderThanFlag      string
+	blameFlag          bool
+	formatFlag         string
+	dirFlag            string
+	tolerateErrorsFlag bool
+	excludeFilesFlag   string
+	excludeGenFlag     bool
+)
+
+func init() {
+	flag.StringVar(&markersFlag, "markers", strings.Join(notes.DefaultMarkers, ","), "comma separated list of the markers for the notes to be located.")
+	flag.StringVar(&ownerFlag, "owner", "", "if set, only notes whose owner matches this regular expression are listed.")
+	flag.StringVar(&olderThanFlag, "older-than", "", "if set, only notes that are older than this age, eg. 30d or 72h, as reported by git blame are listed.")
+	flag.BoolVar(&blameFlag, "blame", false, "if set, annotate each note with its age as reported by git blame.")
+	flag.StringVar(&formatFlag, "format", "text", "the output format, one of text, json or markdown.")
+	flag.StringVar(&dirFlag, "dir", "", "if set, the directory in which packages are located, the current directory is used by default.")
+	flag.BoolVar(&tolerateErrorsFlag, "tolerate-errors", false, "if set, packages that fail to load or type check are reported and otherwise ignored rather than causing gonotes to fail.")
+	flag.StringVar(&excludeFilesFlag, "exclude-files", "", "if set, files whose absolute filename matches this regular expression are excluded, eg. '/(testdata|vendor)/'.")
+	flag.BoolVar(&excludeGenFlag, "exclude-generated", false, "if set, generated files are excluded.")
+}
+
+// parseAge parses an age specified in days, eg. 30d, or as a
+// time.Duration.
+func parseAge(age string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(age, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age: %q", age)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(age)
+}
+
+func formatAge(age time.Duration) string {
+	return fmt.Sprintf("%dd", int(age.Hours()/24))
+}
+
+func main() {
+	ctx := context.Background()
+	flag.Parse()
+	if err := gonotes(ctx, flag.Args()); err != nil {
+		cmdutil.Exit("error: %v", err)
+	}
+}
+
+func gonotes(ctx context.Context, pkgs []string) error {
+	var owner *regexp.Regexp
+	if len(ownerFlag) > 0 {
+		var err error
+		if owner, err = regexp.Compile(ownerFlag); err != nil {
+			return fmt.Errorf("failed to compile --owner: %v", err)
+		}
+	}
+	var olderThan time.Duration
+	if len(olderThanFlag) > 0 {
+		var err error
+		if olderThan, err = parseAge(olderThanFlag); err != nil {
+			return err
+		}
+		blameFlag = true
+	}
+	opts := []locate.Option{locate.Dir(dirFlag), locate.ExcludeGenerated(excludeGenFlag)}
+	if tolerateErrorsFlag {
+		opts = append(opts, locate.TolerateErrors())
+	}
+	if len(excludeFilesFlag) > 0 {
+		re, err := regexp.Compile(excludeFilesFlag)
+		if err != nil {
+			return fmt.Errorf("failed to compile --exclude-files: %v", err)
+		}
+		opts = append(opts, locate.ExcludeFiles(re))
+	}
+	found, err := notes.Find(ctx, strings.Split(markersFlag, ","), pkgs, opts...)
+	if err != nil {
+		return err
+	}
+	if owner != nil {
+		found = filter(found, func(n notes.Note) bool { return owner.MatchString(n.Owner) })
+	}
+	now := time.Now()
+	if blameFlag {
+		if err := notes.Blame(ctx, found); err != nil {
+			return err
+		}
+	}
+	if olderThan > 0 {
+		found = filter(found, func(n notes.Note) bool { return n.Age(now) > olderThan })
+	}
+	switch formatFlag {
+	case "text":
+		writeText(os.Stdout, found, now)
+	case "json":
+		return writeJSON(os.Stdout, found)
+	case "markdown":
+		writeMarkdown(os.Stdout, found, now)
+	default:
+		return fmt.Errorf("unsupported format: %q, must be one of text, json or markdown", formatFlag)
+	}
+	return nil
+}
+
+func filter(found []notes.Note, keep func(notes.Note) bool) []notes.Note {
+	filtered := []notes.Note{}
+	for _, n := range found {
+		if keep(n) {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered
+}
+
+func marker(n notes.Note) string {
+	if len(n.Owner) == 0 {
+		return n.Marker
+	}
+	return n.Marker + "(" + n.Owner + ")"
+}
+
+func writeText(out io.Writer, found []notes.Note, now time.Time) {
+	for _, n := range found {
+		fmt.Fprintf(out, "%v: %v", n.Position, marker(n))
+		if len(n.Decl) > 0 {
+			fmt.Fprintf(out, " [%v]", n.Decl)
+		}
+		if n.Date != nil {
+			fmt.Fprintf(out, " (%v)", formatAge(n.Age(now)))
+		}
+		fmt.Fprintf(out, ": %v\n", n.Text)
+	}
+}
+
+func writeJSON(out io.Writer, found []notes.Note) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(found)
+}
+
+func writeMarkdown(out io.Writer, found []notes.Note, now time.Time) {
+	cwd, _ := os.Getwd()
+	fmt.Fprintf(out, "| Position | Marker | Owner | Declaration | Age | Note |\n")
+	fmt.Fprintf(out, "|---|---|---|---|---|---|\n")
+	for _, n := range found {
+		pos := n.Position
+		if rel, err := filepath.Rel(cwd, pos.Filename); err == nil {
+			pos.Filename = rel
+		}
+		age := ""
+		if n.Date != nil {
+			age = formatAge(n.Age(now))
+		}
+		fmt.Fprintf(out, "| %v | %v | %v | %v | %v | %v |\n",
+			pos, n.Marker, n.Owner, n.Decl, age, strings.ReplaceAll(n.Text, "|", `\|`))
+	}
+}
@@ -167,3 +167,18 @@ func HasCustomContext(signature *types.Signature, customContext string) (string,
 	}
 	return "", false
 }
+
+// NamedErrorResult returns true and the name of the last result of the
+// function if that result is a named error. It returns false for
+// unnamed results and results named _ since they cannot be assigned to.
+func NamedErrorResult(signature *types.Signature) (string, bool) {
+	results := signature.Results()
+	if results.Len() == 0 {
+		return "", false
+	}
+	v := results.At(results.Len() - 1)
+	if len(v.Name()) == 0 || v.Name() == "_" || v.Type().String() != "error" {
+		return "", false
+	}
+	return v.Name(), true
+}
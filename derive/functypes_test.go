@@ -112,3 +112,26 @@ func TestFormatting(t *testing.T) {
 	cmp(results, expectedResults)
 
 }
+
+func TestNamedErrorResult(t *testing.T) {
+	ctx := context.Background()
+	locator := locate.New()
+	locator.AddFunctions(testdata)
+	if err := locator.Do(ctx); err != nil {
+		t.Errorf("locate.Do: %v", err)
+	}
+	named := map[string]string{}
+	locator.WalkFunctions(func(_ string, _ *packages.Package, _ *ast.File, fn *types.Func, _ *ast.FuncDecl, _ []string) {
+		if name, ok := derive.NamedErrorResult(fn.Type().(*types.Signature)); ok {
+			named[fn.Name()] = name
+		}
+	})
+	// Only Error has a named error result, Unknown's error result
+	// is unnamed.
+	if got, want := len(named), 1; got != want {
+		t.Fatalf("got %v, want %v: %v", got, want, named)
+	}
+	if got, want := named["Error"], "err"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
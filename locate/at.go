@@ -0,0 +1,198 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+
+	"cloudeng.io/go/locate/locateutil"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// Symbol represents the declaration, identifier and types.Object at a
+// position within a file, see At.
+type Symbol struct {
+	Position token.Position
+	Package  *packages.Package
+	File     *ast.File
+	// Decl is the top-level declaration that encloses the position and
+	// DeclName its name as per locateutil.EnclosingDecl. Decl is nil for
+	// positions outside of any declaration.
+	Decl     ast.Decl
+	DeclName string
+	// Ident is the identifier at the position, if any, and Object the
+	// types.Object that it defines or refers to, if known.
+	Ident  *ast.Ident
+	Object types.Object
+	// Name is the fully qualified name of Object, using the same
+	// conventions as the names reported for interfaces and functions, and
+	// ObjectPosition the position of its declaration.
+	Name           string
+	ObjectPosition token.Position
+	// Interfaces lists the located interfaces that Object is, is a method
+	// of, or is implemented by Object or, for methods, by its receiver.
+	Interfaces []string
+	// Functions lists the located functions that Object is or, for
+	// interface methods, the located methods that implement it.
+	Functions []string
+}
+
+// Offset returns the byte offset of the 1-based line and column (in
+// bytes) within the specified file, which must be in a loaded package.
+func (t *T) Offset(filename string, line, column int) (int, error) {
+	tf, _, err := t.tokenFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	if line < 1 || line > tf.LineCount() {
+		return 0, fmt.Errorf("%v: line %v is out of range", filename, line)
+	}
+	// The column may refer to any byte on the line, including the
+	// terminating newline, but not to a later line.
+	end := tf.Size()
+	if line < tf.LineCount() {
+		end = tf.Offset(tf.LineStart(line+1)) - 1
+	}
+	offset := tf.Offset(tf.LineStart(line)) + column - 1
+	if column < 1 || offset > end {
+		return 0, fmt.Errorf("%v:%v: column %v is out of range", filename, line, column)
+	}
+	return offset, nil
+}
+
+func (t *T) tokenFile(filename string) (*token.File, *ast.File, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, _, pkg := t.loader.lookupFile(abs)
+	if file == nil {
+		return nil, nil, fmt.Errorf("%v: not in any of the loaded packages", filename)
+	}
+	return pkg.Fset.File(file.Pos()), file, nil
+}
+
+// At returns the Symbol at the specified byte offset within filename,
+// which must be in one of the packages loaded by Do. Type information is
+// required to determine the types.Object for the identifier at offset and
+// hence the RequireTypes option should be used if no interfaces, functions
+// or declarations are to be located. The located interfaces and functions
+// that the object relates to are determined from the results of Do.
+func (t *T) At(filename string, offset int) (Symbol, error) {
+	tf, file, err := t.tokenFile(filename)
+	if err != nil {
+		return Symbol{}, err
+	}
+	if offset < 0 || offset > tf.Size() {
+		return Symbol{}, fmt.Errorf("%v: offset %v is out of range", filename, offset)
+	}
+	_, _, pkg := t.loader.lookupFile(tf.Name())
+	pos := tf.Pos(offset)
+	sym := Symbol{
+		Position: pkg.Fset.PositionFor(pos, false),
+		Package:  pkg,
+		File:     file,
+	}
+	sym.Decl, sym.DeclName = locateutil.EnclosingDecl(file, pos)
+	path, _ := astutil.PathEnclosingInterval(file, pos, pos)
+	if len(path) == 0 {
+		return sym, nil
+	}
+	id, ok := path[0].(*ast.Ident)
+	if !ok {
+		return sym, nil
+	}
+	sym.Ident = id
+	if pkg.TypesInfo == nil {
+		return sym, nil
+	}
+	sym.Object = pkg.TypesInfo.ObjectOf(id)
+	if sym.Object == nil {
+		return sym, nil
+	}
+	sym.Name = objectName(sym.Object)
+	sym.ObjectPosition = pkg.Fset.PositionFor(sym.Object.Pos(), false)
+	sym.Interfaces, sym.Functions = t.related(sym.Object, sym.Name)
+	return sym, nil
+}
+
+// objectName returns the fully qualified name for package level objects
+// and methods and the unqualified name otherwise.
+func objectName(obj types.Object) string {
+	switch o := obj.(type) {
+	case *types.Func:
+		return o.FullName()
+	case *types.PkgName:
+		return o.Imported().Path()
+	}
+	if obj.Pkg() != nil && obj.Parent() == obj.Pkg().Scope() {
+		return obj.Pkg().Path() + "." + obj.Name()
+	}
+	return obj.Name()
+}
+
+// related returns the located interfaces and functions that obj relates
+// to.
+func (t *T) related(obj types.Object, name string) (interfaces, functions []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ifcs := map[string]bool{}
+	fns := map[string]bool{}
+	switch o := obj.(type) {
+	case *types.TypeName:
+		if _, ok := t.interfaces[name]; ok {
+			ifcs[name] = true
+		}
+		for _, impl := range t.implementations {
+			if impl.Type == name {
+				ifcs[impl.Interface] = true
+			}
+		}
+	case *types.Func:
+		if fn, ok := t.functions[name]; ok {
+			fns[name] = true
+			for _, ifc := range fn.implements {
+				ifcs[ifc] = true
+			}
+		}
+		// An interface method relates to the located interfaces that
+		// include it and to the located methods that implement them.
+		for ifcName, desc := range t.interfaces {
+			for i := 0; i < desc.ifc.NumMethods(); i++ {
+				if desc.ifc.Method(i) == o {
+					ifcs[ifcName] = true
+				}
+			}
+		}
+		if sig, ok := o.Type().(*types.Signature); ok && sig.Recv() != nil && types.IsInterface(sig.Recv().Type()) {
+			for fnName, fn := range t.functions {
+				if fn.Type.Name() != o.Name() {
+					continue
+				}
+				for _, ifc := range fn.implements {
+					if ifcs[ifc] {
+						fns[fnName] = true
+					}
+				}
+			}
+		}
+	}
+	return sortedKeys(ifcs), sortedKeys(fns)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
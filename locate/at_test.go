@@ -0,0 +1,115 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloudeng.io/go/locate"
+)
+
+func TestAt(t *testing.T) {
+	ctx := context.Background()
+	locator := locate.New(locate.IncludeMethods(true))
+	locator.AddInterfaces(here + "at")
+	locator.AddFunctions(here + "at")
+	locator.AddPackages(here + "at")
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locator.Do: %v", err)
+	}
+	filename := filepath.Join("testdata", "at", "at.go")
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsetOf := func(text string, delta int) int {
+		idx := strings.Index(string(contents), text)
+		if idx < 0 {
+			t.Fatalf("failed to find %q", text)
+		}
+		return idx + delta
+	}
+
+	for i, tc := range []struct {
+		offset     int
+		decl       string
+		name       string
+		interfaces []string
+		functions  []string
+	}{
+		// Interface methods are located as functions and relate to the
+		// located methods that implement them.
+		{offsetOf("i.Method()", 2), "Use", "(" + here + "at.Ifc).Method",
+			[]string{here + "at.Ifc"}, []string{"(" + here + "at.Ifc).Method", "(" + here + "at.Impl).Method"}},
+		{offsetOf("func (Impl) Method", 12), "Impl.Method", "(" + here + "at.Impl).Method",
+			[]string{here + "at.Ifc"}, []string{"(" + here + "at.Impl).Method"}},
+		{offsetOf("type Impl", 5), "Impl", here + "at.Impl",
+			[]string{here + "at.Ifc"}, []string{}},
+		{offsetOf("type Ifc", 5), "Ifc", here + "at.Ifc",
+			[]string{here + "at.Ifc"}, []string{}},
+		{offsetOf("c    =", 0), "c", here + "at.c",
+			[]string{}, []string{}},
+		{offsetOf("func Use(i", 9), "Use", "i",
+			[]string{}, []string{}},
+	} {
+		sym, err := locator.At(filename, tc.offset)
+		if err != nil {
+			t.Errorf("%v: %v", i, err)
+			continue
+		}
+		if got, want := sym.DeclName, tc.decl; got != want {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+		if got, want := sym.Name, tc.name; got != want {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+		if got, want := sym.Interfaces, tc.interfaces; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+		if got, want := sym.Functions, tc.functions; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+	}
+
+	// Offsets outside of any declaration or identifier.
+	sym, err := locator.At(filename, offsetOf("\n\n// Impl implements", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sym.Decl != nil || sym.Ident != nil || sym.Object != nil {
+		t.Errorf("unexpected symbol: %+v", sym)
+	}
+
+	offset, err := locator.Offset(filename, 21, 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := offset, offsetOf("i.Method()", 2); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := locator.Offset(filename, 100, 1); err == nil {
+		t.Errorf("expected an error")
+	}
+	// Line 21 is "\treturn i.Method()", the column may refer to its
+	// newline but not to any later position.
+	offset, err = locator.Offset(filename, 21, 19)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := offset, offsetOf("i.Method()", len("i.Method()")); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := locator.Offset(filename, 21, 20); err == nil || !strings.Contains(err.Error(), "column 20 is out of range") {
+		t.Errorf("missing or wrong error: %v", err)
+	}
+	if _, err := locator.At("no-such-file.go", 0); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// DoBatches is like Do except that the packages are loaded, processed and
+// then released in batches of at most size packages, in dependency order,
+// so that the memory required is bounded by the size of a batch rather
+// than by the total number of packages. The supplied function is called
+// once each batch has been processed with the packages in that batch;
+// the Walk methods report the results for that batch only whilst it runs.
+// The packages and results for a batch, other than the Locations reported
+// by WalkLocations, are released when the function returns. The packages
+// containing the interfaces specified via AddInterfaces are loaded with
+// every batch since implementations are determined relative to them, and
+// hence WalkInterfaces reports them for every batch, but they are only
+// otherwise reported as part of their own batch. AddCallers,
+// AddDeprecatedUses, LoadDependencies, CacheDir and UseSnapshot are not
+// supported since they require all of the packages to be loaded at once.
+func (t *T) DoBatches(ctx context.Context, size int, fn func(ctx context.Context, pkgs []string) error) error {
+	if size <= 0 {
+		return fmt.Errorf("invalid batch size: %v", size)
+	}
+	if len(t.callerPackages) > 0 || len(t.deprecatedPackages) > 0 || t.options.loadDependencies || len(t.options.cacheDir) > 0 || t.options.snapshot != nil {
+		return fmt.Errorf("callers, deprecated uses, dependencies, caching and snapshots are not supported when processing packages in batches")
+	}
+	if t.options.allModules {
+		cleanup, err := t.setupWorkspace(ctx)
+		defer cleanup()
+		if err != nil {
+			return err
+		}
+	}
+	req, err := t.expand(ctx)
+	if err != nil {
+		return err
+	}
+	ordered, err := t.dependencyOrder(ctx, req.all)
+	if err != nil {
+		return err
+	}
+	ifcPackages := packagesFromSpecs(req.interfaces)
+	for len(ordered) > 0 {
+		n := min(size, len(ordered))
+		batch := ordered[:n]
+		ordered = ordered[n:]
+		if err := t.doBatch(ctx, req, batch, ifcPackages, fn); err != nil {
+			return err
+		}
+	}
+	t.packages = req.packages
+	return nil
+}
+
+func (t *T) doBatch(ctx context.Context, req request, batch, ifcPackages []string, fn func(ctx context.Context, pkgs []string) error) error {
+	members := map[string]bool{}
+	for _, path := range batch {
+		members[path] = true
+	}
+	// The interface packages that are not part of the batch are loaded
+	// only to provide type information.
+	var ifcOnly []string
+	for _, path := range ifcPackages {
+		if !members[path] {
+			ifcOnly = append(ifcOnly, path)
+		}
+	}
+	t.trace("batch: %v, interfaces: %v\n", batch, ifcOnly)
+	batchReq := request{
+		interfaces:   req.interfaces,
+		functions:    filterSpecs(req.functions, members),
+		packages:     filterPackages(req.packages, members),
+		comments:     req.comments,
+		declarations: req.declarations.filter(members),
+	}
+	t.packages = batchReq.packages
+	t.loader.setContext(ifcOnly)
+	defer t.release(ifcOnly)
+	if err := t.load(ctx, req.mode, dedup(append(append([]string{}, batch...), ifcOnly...))); err != nil {
+		return err
+	}
+	if err := t.find(ctx, batchReq); err != nil {
+		return err
+	}
+	return fn(ctx, batch)
+}
+
+// release retains the locations found for the current batch, other than
+// those in the packages loaded only for type information, and then
+// discards all of the results and loaded packages.
+func (t *T) release(ifcOnly []string) {
+	skip := map[string]bool{}
+	for _, path := range ifcOnly {
+		skip[path] = true
+	}
+	for _, loc := range t.locations() {
+		if !skip[loc.Package] {
+			t.released = append(t.released, loc)
+		}
+	}
+	t.mu.Lock()
+	t.resetLocked()
+	t.mu.Unlock()
+	t.loader.release()
+}
+
+// dependencyOrder returns the specified packages ordered such that each
+// package appears after all of the others that it depends on, with ties
+// broken by the order of the package paths.
+func (t *T) dependencyOrder(ctx context.Context, paths []string) ([]string, error) {
+	listed, err := packages.Load(t.packagesConfig(ctx, packages.NeedName|packages.NeedImports), paths...)
+	if err != nil {
+		return nil, err
+	}
+	requested := map[string]bool{}
+	for _, path := range paths {
+		requested[path] = true
+	}
+	byID := map[string]*packages.Package{}
+	for _, pkg := range listed {
+		byID[pkg.ID] = pkg
+	}
+	sort.Slice(listed, func(i, j int) bool {
+		return listed[i].ID < listed[j].ID
+	})
+	var ordered []string
+	added := map[string]bool{}
+	visited := map[string]bool{}
+	var visit func(pkg *packages.Package)
+	visit = func(pkg *packages.Package) {
+		if visited[pkg.ID] {
+			return
+		}
+		visited[pkg.ID] = true
+		imports := make([]string, 0, len(pkg.Imports))
+		for path := range pkg.Imports {
+			imports = append(imports, path)
+		}
+		sort.Strings(imports)
+		for _, path := range imports {
+			if dep, ok := byID[pkg.Imports[path].ID]; ok {
+				visit(dep)
+			}
+		}
+		if requested[pkg.PkgPath] && !added[pkg.PkgPath] {
+			added[pkg.PkgPath] = true
+			ordered = append(ordered, pkg.PkgPath)
+		}
+	}
+	for _, pkg := range listed {
+		visit(pkg)
+	}
+	// Packages that could not be listed are loaded last so that any
+	// errors are reported when they are loaded.
+	for _, path := range paths {
+		if !added[path] {
+			added[path] = true
+			ordered = append(ordered, path)
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate_test
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"cloudeng.io/go/locate"
+	"golang.org/x/tools/go/packages"
+)
+
+func newBatchLocator() *locate.T {
+	locator := locate.New(locate.IncludeMethods(true))
+	locator.AddInterfaces(here + "data")
+	locator.AddPackages(here+"data", here+"impl", here+"embedded")
+	locator.AddFunctions(here + "impl")
+	locator.AddComments("^Ifc")
+	locator.AddTypes(here + "decls")
+	return locator
+}
+
+func listAllLocations(locator *locate.T) []string {
+	out := []string{}
+	locator.WalkLocations(func(loc locate.Location) {
+		out = append(out, fmt.Sprintf("%v %v %v %v %v", loc.Kind, loc.Name, loc.Detail, loc.Implements, loc.Position))
+	})
+	return out
+}
+
+func TestDoBatches(t *testing.T) {
+	ctx := context.Background()
+	locator := newBatchLocator()
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locator.Do: %v", err)
+	}
+	expected := listAllLocations(locator)
+	if len(expected) < 10 {
+		t.Fatalf("too few locations: %v", expected)
+	}
+
+	for _, size := range []int{1, 2, 10} {
+		locator := newBatchLocator()
+		batches := 0
+		err := locator.DoBatches(ctx, size, func(_ context.Context, pkgs []string) error {
+			batches++
+			// Only the packages in the batch are visible, even though the
+			// interface packages are loaded with every batch.
+			want := append([]string{}, pkgs...)
+			sort.Strings(want)
+			if got := listPackages(locator); !reflect.DeepEqual(got, want) {
+				t.Errorf("%v: got %v, want %v", size, got, want)
+			}
+			// The comment maps are those created when loading.
+			cmaps := locator.MakeCommentMaps()
+			locator.WalkFiles(func(name string, _ *packages.Package, cmap ast.CommentMap, file *ast.File, _ locate.HitMask) {
+				if reflect.ValueOf(cmaps[file]).Pointer() != reflect.ValueOf(cmap).Pointer() {
+					t.Errorf("%v: %v: comment map was recreated", size, name)
+				}
+			})
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%v: locator.DoBatches: %v", size, err)
+		}
+		if got, want := batches, (4+size-1)/size; got != want {
+			t.Errorf("%v: got %v, want %v", size, got, want)
+		}
+		if got, want := listAllLocations(locator), expected; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v\nwant %v", size, got, want)
+		}
+		if got := listPackages(locator); len(got) != 0 {
+			t.Errorf("%v: packages were not released: %v", size, got)
+		}
+	}
+}
+
+func TestDoBatchesOrder(t *testing.T) {
+	ctx := context.Background()
+	locator := locate.New()
+	locator.AddPackages(here+"deprecated", here+"deprecated/old")
+	var batches []string
+	err := locator.DoBatches(ctx, 1, func(_ context.Context, pkgs []string) error {
+		batches = append(batches, pkgs...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("locator.DoBatches: %v", err)
+	}
+	// Dependencies are processed first.
+	if got, want := batches, []string{here + "deprecated/old", here + "deprecated"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	locator = locate.New()
+	locator.AddPackages(here + "deprecated")
+	locator.AddCallers(here + "deprecated")
+	err = locator.DoBatches(ctx, 1, func(context.Context, []string) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,420 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// CacheEntry represents the cached locations for a single package.
+type CacheEntry struct {
+	// Key is derived from the contents of the package's files, those of
+	// its dependencies, the go version, build flags and the interfaces,
+	// functions, comments, types, fields, constants and variables
+	// requested, as well as the role of the package within the request,
+	// eg. whether it is searched for implementations.
+	Key       string
+	Package   string
+	Created   time.Time
+	Locations []Location
+}
+
+const cacheSuffix = ".json"
+
+type cacheState struct {
+	dir string
+	// Indexed by package path, the keys for the packages that are to
+	// be loaded and whose results are to be written to the cache.
+	keys map[string]string
+}
+
+const listLoadMode = packages.NeedName | packages.NeedFiles |
+	packages.NeedCompiledGoFiles | packages.NeedImports |
+	packages.NeedDeps | packages.NeedModule
+
+// useCache reads the cached locations for all packages whose key is
+// unchanged and returns the packages, interfaces, functions,
+// implementation packages and declarations that need to be loaded and
+// analyzed.
+func (t *T) useCache(ctx context.Context, allPackages, interfaces, functions, impls, comments []string, declarations declSpecs) (load, ifcs, fns, pkgs []string, decls declSpecs, err error) {
+	listed, err := packages.Load(t.packagesConfig(ctx, listLoadMode), allPackages...)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	goVersion, err := t.goEnvVersion(ctx)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+
+	hasher := &packageHasher{
+		hashes:  map[string]string{},
+		overlay: t.options.overlay,
+	}
+	contents := map[string][]string{}
+	for _, pkg := range listed {
+		if len(pkg.Name) == 0 || len(pkg.Errors) > 0 {
+			// Never use the cache for packages with errors.
+			continue
+		}
+		h, err := hasher.hash(pkg)
+		if err != nil {
+			return nil, nil, nil, nil, nil, err
+		}
+		contents[pkg.PkgPath] = append(contents[pkg.PkgPath], h)
+	}
+
+	query := []string{goVersion, fmt.Sprintf("tests=%v methods=%v", t.options.tests, t.options.includeMethods)}
+	query = append(query, t.options.buildFlags...)
+	query = append(query, "interfaces")
+	query = append(query, interfaces...)
+	query = append(query, "functions")
+	query = append(query, functions...)
+	query = append(query, fmt.Sprintf("comments directives=%v", t.options.directives))
+	query = append(query, comments...)
+	for _, kind := range declKinds {
+		query = append(query, kind.String())
+		query = append(query, declarations[kind]...)
+	}
+	query = append(query, fmt.Sprintf("types=%v", t.options.typeKinds))
+	query = append(query, tagFilterStrings(t.options.tagFilters)...)
+	query = append(query, exclusionStrings(t.exclusions)...)
+	for _, re := range t.options.excludeFiles {
+		query = append(query, "exclude="+re.String())
+	}
+	query = append(query, fmt.Sprintf("generated=%v", t.options.excludeGenerated))
+	if len(impls) > 0 {
+		// Implementations depend on the interfaces being located.
+		for _, path := range packagesFromSpecs(interfaces) {
+			query = append(query, contents[path]...)
+		}
+	}
+
+	isImpl := map[string]bool{}
+	for _, path := range impls {
+		isImpl[path] = true
+	}
+	keys := map[string]string{}
+	dirty := map[string]bool{}
+	for _, path := range allPackages {
+		hashes := contents[path]
+		if len(hashes) == 0 {
+			dirty[path] = true
+			continue
+		}
+		sort.Strings(hashes)
+		role := packageRole(path, isImpl[path], interfaces, functions, declarations)
+		key := hashStrings(append(append(append([]string{path}, query...), role...), hashes...))
+		keys[path] = key
+		entry, err := readCacheEntry(t.cache.dir, key)
+		if err != nil {
+			t.trace("cache: miss: %v: %v\n", path, key)
+			dirty[path] = true
+			continue
+		}
+		t.trace("cache: hit: %v: %v\n", path, key)
+		t.cached = append(t.cached, entry.Locations...)
+	}
+
+	needInterfaces := false
+	for _, path := range impls {
+		if dirty[path] {
+			needInterfaces = true
+		}
+	}
+	if needInterfaces {
+		for _, path := range packagesFromSpecs(interfaces) {
+			if !dirty[path] {
+				// Reanalyze the interface package so that its cached
+				// results can be discarded.
+				t.cached = removePackage(t.cached, path)
+				dirty[path] = true
+			}
+		}
+	}
+	t.cache.keys = map[string]string{}
+	for path := range dirty {
+		if key, ok := keys[path]; ok {
+			t.cache.keys[path] = key
+		}
+	}
+	load = filterPackages(allPackages, dirty)
+	ifcs = filterSpecs(interfaces, dirty)
+	fns = filterSpecs(functions, dirty)
+	pkgs = filterPackages(impls, dirty)
+	decls = declarations.filter(dirty)
+	return
+}
+
+// packageRole returns the role that path plays in the current query, ie.
+// whether it is searched for implementations and the interface, function
+// and declaration specs that refer to it, since the locations found for a
+// package depend on its role as well as on the query as a whole.
+func packageRole(path string, impl bool, interfaces, functions []string, declarations declSpecs) []string {
+	keep := map[string]bool{path: true}
+	role := []string{fmt.Sprintf("role: implementations=%v", impl), "role: interfaces"}
+	role = append(role, filterSpecs(interfaces, keep)...)
+	role = append(role, "role: functions")
+	role = append(role, filterSpecs(functions, keep)...)
+	for _, kind := range declKinds {
+		role = append(role, "role: "+kind.String())
+		role = append(role, filterSpecs(declarations[kind], keep)...)
+	}
+	return role
+}
+
+// writeCache writes the locations for the packages that were loaded to
+// the cache. Packages that failed to load or type check are not cached.
+func (t *T) writeCache() error {
+	byPackage := map[string][]Location{}
+	for _, loc := range t.locations() {
+		path := cachedPackage(loc.Package, t.cache.keys)
+		byPackage[path] = append(byPackage[path], loc)
+	}
+	for path, key := range t.cache.keys {
+		if t.loader.hasDiagnostics(path) || t.loader.lookupPackage(path) == nil {
+			continue
+		}
+		locs := byPackage[path]
+		sortLocations(locs)
+		entry := CacheEntry{
+			Key:       key,
+			Package:   path,
+			Created:   time.Now(),
+			Locations: locs,
+		}
+		if err := writeCacheEntry(t.cache.dir, entry); err != nil {
+			return err
+		}
+		t.trace("cache: write: %v: %v\n", path, key)
+	}
+	return nil
+}
+
+// cachedPackage maps the package paths of test variants to the package
+// under test.
+func cachedPackage(path string, keys map[string]string) string {
+	if _, ok := keys[path]; ok {
+		return path
+	}
+	for _, suffix := range []string{"_test", ".test"} {
+		if trimmed := strings.TrimSuffix(path, suffix); trimmed != path {
+			return trimmed
+		}
+	}
+	return path
+}
+
+func removePackage(locs []Location, path string) []Location {
+	filtered := locs[:0]
+	for _, loc := range locs {
+		if cachedPackage(loc.Package, nil) != path {
+			filtered = append(filtered, loc)
+		}
+	}
+	return filtered
+}
+
+func filterPackages(paths []string, keep map[string]bool) []string {
+	filtered := []string{}
+	for _, path := range paths {
+		if keep[path] {
+			filtered = append(filtered, path)
+		}
+	}
+	return filtered
+}
+
+func filterSpecs(specs []string, keep map[string]bool) []string {
+	filtered := []string{}
+	for _, spec := range specs {
+		if path, _ := parseSpecAndRegexp(spec); keep[path] {
+			filtered = append(filtered, spec)
+		}
+	}
+	return filtered
+}
+
+// packageHasher computes a hash for a package from the contents of its
+// files and the hashes of its dependencies. Standard library packages
+// are covered by the go version and packages from non-replaced modules by
+// their module version, neither is read. Files in the overlay are hashed
+// using their overlay contents.
+type packageHasher struct {
+	hashes  map[string]string
+	overlay map[string][]byte
+}
+
+func (ph *packageHasher) hash(pkg *packages.Package) (string, error) {
+	if h, ok := ph.hashes[pkg.ID]; ok {
+		return h, nil
+	}
+	var parts []string
+	switch m := pkg.Module; {
+	case m == nil && isStandardPackage(pkg.PkgPath):
+		parts = []string{pkg.PkgPath}
+	case m != nil && !m.Main && m.Replace == nil && len(m.Version) > 0:
+		parts = []string{pkg.PkgPath, m.Path + "@" + m.Version}
+	default:
+		files := append([]string{}, pkg.CompiledGoFiles...)
+		sort.Strings(files)
+		for _, file := range files {
+			h, err := ph.hashFile(file)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, file, h)
+		}
+		imports := make([]string, 0, len(pkg.Imports))
+		for path := range pkg.Imports {
+			imports = append(imports, path)
+		}
+		sort.Strings(imports)
+		for _, path := range imports {
+			h, err := ph.hash(pkg.Imports[path])
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, path, h)
+		}
+	}
+	h := hashStrings(parts)
+	ph.hashes[pkg.ID] = h
+	return h, nil
+}
+
+func isStandardPackage(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+func (ph *packageHasher) hashFile(filename string) (string, error) {
+	if buf, ok := ph.overlay[filename]; ok {
+		h := sha256.Sum256(buf)
+		return hex.EncodeToString(h[:]), nil
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashStrings(parts []string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (t *T) goEnvVersion(ctx context.Context) (string, error) {
+	cmd := t.goCommand(ctx, t.dir(), "env", "GOVERSION")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run go env GOVERSION: %v", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func readCacheEntry(dir, key string) (CacheEntry, error) {
+	var entry CacheEntry
+	buf, err := os.ReadFile(filepath.Join(dir, key+cacheSuffix))
+	if err != nil {
+		return entry, err
+	}
+	if err := json.Unmarshal(buf, &entry); err != nil {
+		return entry, err
+	}
+	if entry.Key != key {
+		return entry, fmt.Errorf("mismatched cache key: %v != %v", entry.Key, key)
+	}
+	return entry, nil
+}
+
+func writeCacheEntry(dir string, entry CacheEntry) error {
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it so that concurrent runs
+	// never see a partially written entry.
+	tmp, err := os.CreateTemp(dir, entry.Key+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, entry.Key+cacheSuffix))
+}
+
+// WalkCache calls the supplied function for every entry in the cache
+// stored in dir, ordered by package path and then creation time.
+func WalkCache(dir string, fn func(entry CacheEntry)) error {
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+cacheSuffix))
+	if err != nil {
+		return err
+	}
+	entries := make([]CacheEntry, 0, len(matches))
+	for _, match := range matches {
+		key := strings.TrimSuffix(filepath.Base(match), cacheSuffix)
+		entry, err := readCacheEntry(dir, key)
+		if err != nil {
+			return fmt.Errorf("%v: %v", match, err)
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Package == entries[j].Package {
+			return entries[i].Created.Before(entries[j].Created)
+		}
+		return entries[i].Package < entries[j].Package
+	})
+	for _, entry := range entries {
+		fn(entry)
+	}
+	return nil
+}
+
+// ClearCache removes all of the entries in the cache stored in dir.
+func ClearCache(dir string) error {
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+cacheSuffix))
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		if err := os.Remove(match); err != nil {
+			return err
+		}
+	}
+	return nil
+}
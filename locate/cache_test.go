@@ -0,0 +1,158 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloudeng.io/go/locate"
+)
+
+type cacheTrace struct {
+	sync.Mutex
+	hits, misses, writes int
+}
+
+func (ct *cacheTrace) trace(format string, args ...interface{}) {
+	ct.Lock()
+	defer ct.Unlock()
+	msg := fmt.Sprintf(format, args...)
+	switch {
+	case strings.HasPrefix(msg, "cache: hit:"):
+		ct.hits++
+	case strings.HasPrefix(msg, "cache: miss:"):
+		ct.misses++
+	case strings.HasPrefix(msg, "cache: write:"):
+		ct.writes++
+	}
+}
+
+func locateWithCache(ctx context.Context, t *testing.T, dir string, comments ...string) ([]locate.Location, *cacheTrace) {
+	ct := &cacheTrace{}
+	opts := []locate.Option{locate.Trace(ct.trace)}
+	if len(dir) > 0 {
+		opts = append(opts, locate.CacheDir(dir))
+	}
+	locator := locate.New(opts...)
+	locator.AddInterfaces(here + "data")
+	locator.AddFunctions(here + "data.Fn2$")
+	locator.AddPackages(here+"impl", here+"comments")
+	locator.AddComments(comments...)
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locate.Do: %v", err)
+	}
+	locs := []locate.Location{}
+	locator.WalkLocations(func(loc locate.Location) {
+		locs = append(locs, loc)
+	})
+	return locs, ct
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	uncached, _ := locateWithCache(ctx, t, "", ".*")
+	if len(uncached) == 0 {
+		t.Fatalf("no locations found")
+	}
+
+	first, ct := locateWithCache(ctx, t, dir, ".*")
+	if got, want := ct.hits, 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := ct.writes, 3; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(first, uncached) {
+		t.Errorf("got %v, want %v", first, uncached)
+	}
+
+	second, ct := locateWithCache(ctx, t, dir, ".*")
+	if got, want := ct.hits, 3; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := ct.writes, 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(second, uncached) {
+		t.Errorf("got %v, want %v", second, uncached)
+	}
+
+	// Changing the query invalidates the cache.
+	_, ct = locateWithCache(ctx, t, dir, "nomatch")
+	if got, want := ct.misses, 3; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	entries := map[string]int{}
+	err := locate.WalkCache(dir, func(entry locate.CacheEntry) {
+		entries[entry.Package]++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range []string{"data", "impl", "comments"} {
+		if got, want := entries[here+pkg], 2; got != want {
+			t.Errorf("%v: got %v, want %v", pkg, got, want)
+		}
+	}
+
+	if err := locate.ClearCache(dir); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	if err := locate.WalkCache(dir, func(locate.CacheEntry) { n++ }); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCachePackageRoles(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	run := func(dir string, impls ...string) []locate.Location {
+		opts := []locate.Option{locate.IncludeMethods(true)}
+		if len(dir) > 0 {
+			opts = append(opts, locate.CacheDir(dir))
+		}
+		locator := locate.New(opts...)
+		locator.AddInterfaces(here + "data")
+		locator.AddFunctions(here + "impl")
+		locator.AddPackages(impls...)
+		if err := locator.Do(ctx); err != nil {
+			t.Fatalf("locate.Do: %v", err)
+		}
+		locs := []locate.Location{}
+		locator.WalkLocations(func(loc locate.Location) {
+			locs = append(locs, loc)
+		})
+		return locs
+	}
+	// Prime the cache with impl used only as a function package and
+	// then search it for implementations.
+	run(dir, here+"comments")
+	uncached := run("", here+"impl")
+	cached := run(dir, here+"impl")
+	if !reflect.DeepEqual(cached, uncached) {
+		t.Errorf("got %v, want %v", cached, uncached)
+	}
+	implementations := 0
+	for _, loc := range cached {
+		if loc.Kind == locate.HasImplementation {
+			implementations++
+		}
+	}
+	if implementations == 0 {
+		t.Errorf("no implementations found")
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package callgraph provides support for building and querying static
+// call graphs for the packages loaded by locate.T, using one of the
+// algorithms provided by golang.org/x/tools/go/callgraph.
+package callgraph
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"cloudeng.io/go/locate"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/callgraph/static"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// Algorithm represents the algorithm used to construct a call graph.
+type Algorithm string
+
+const (
+	// Static includes only statically dispatched calls, it is the fastest
+	// but least complete algorithm.
+	Static Algorithm = "static"
+	// CHA uses class hierarchy analysis to resolve dynamic calls to every
+	// method that implements the called interface method.
+	CHA Algorithm = "cha"
+	// RTA uses rapid type analysis to resolve dynamic calls to only those
+	// types that are reachable from the roots, it is the most precise.
+	RTA Algorithm = "rta"
+)
+
+// ParseAlgorithm parses the supplied algorithm name.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch a := Algorithm(name); a {
+	case Static, CHA, RTA:
+		return a, nil
+	}
+	return "", fmt.Errorf("unsupported call graph algorithm: %q, must be one of %v, %v or %v", name, Static, CHA, RTA)
+}
+
+// Direction specifies whether callers or callees are to be returned
+// when querying a call graph.
+type Direction int
+
+const (
+	// Callees requests the functions transitively called by the roots.
+	Callees Direction = iota
+	// Callers requests the functions that transitively call the roots.
+	Callers
+)
+
+// Graph represents a call graph.
+type Graph struct {
+	prog  *ssa.Program
+	graph *callgraph.Graph
+	roots []*ssa.Function
+}
+
+// Build builds a call graph for the packages loaded by locator, which
+// must have been created with locate.LoadDependencies. The functions
+// located by locator are the roots of the graph; they are also used as the
+// entry points for RTA along with the main and init functions of any main
+// packages.
+func Build(locator *locate.T, algorithm Algorithm) (*Graph, error) {
+	var initial []*packages.Package
+	locator.WalkPackages(func(pkg *packages.Package) {
+		initial = append(initial, pkg)
+	})
+	prog, pkgs := ssautil.AllPackages(initial, ssa.InstantiateGenerics)
+	for i, pkg := range pkgs {
+		if pkg == nil {
+			return nil, fmt.Errorf("failed to build ssa for %v", initial[i].PkgPath)
+		}
+	}
+	prog.Build()
+
+	g := &Graph{prog: prog}
+	locator.WalkFunctions(func(_ string, _ *packages.Package, _ *ast.File, fn *types.Func, _ *ast.FuncDecl, _ []string) {
+		// Abstract methods have no ssa function.
+		if sfn := prog.FuncValue(fn); sfn != nil {
+			g.roots = append(g.roots, sfn)
+		}
+	})
+
+	switch algorithm {
+	case Static:
+		g.graph = static.CallGraph(prog)
+	case CHA:
+		g.graph = cha.CallGraph(prog)
+	case RTA:
+		entries := append([]*ssa.Function{}, g.roots...)
+		for _, pkg := range pkgs {
+			if pkg.Pkg.Name() != "main" {
+				continue
+			}
+			for _, name := range []string{"main", "init"} {
+				if fn := pkg.Func(name); fn != nil {
+					entries = append(entries, fn)
+				}
+			}
+		}
+		if len(entries) == 0 {
+			return nil, fmt.Errorf("rta requires at least one root or main package")
+		}
+		g.graph = rta.Analyze(entries, true).CallGraph
+	default:
+		return nil, fmt.Errorf("unsupported call graph algorithm: %q", algorithm)
+	}
+	g.graph.DeleteSyntheticNodes()
+	return g, nil
+}
+
+// Roots returns the names of the root functions of the graph.
+func (g *Graph) Roots() []string {
+	names := make([]string, len(g.roots))
+	for i, fn := range g.roots {
+		names[i] = fn.String()
+	}
+	return names
+}
+
+// Node represents a function in a call graph.
+type Node struct {
+	Name     string
+	Position token.Position
+}
+
+// Edge represents a call from Caller to Callee at Position.
+type Edge struct {
+	Caller   string
+	Callee   string
+	Position token.Position
+}
+
+// Result represents the set of functions, and the calls between them,
+// that are reachable from a set of roots.
+type Result struct {
+	Direction Direction `json:"-"`
+	Roots     []string
+	Nodes     []Node
+	Edges     []Edge
+}
+
+// Reachable returns the functions, and the calls between them, that are
+// transitively reachable from the roots of the graph in the specified
+// direction.
+func (g *Graph) Reachable(dir Direction) *Result {
+	res := &Result{Direction: dir, Roots: g.Roots()}
+	visited := map[*callgraph.Node]bool{}
+	edges := map[*callgraph.Edge]bool{}
+	var queue []*callgraph.Node
+	for _, fn := range g.roots {
+		if n := g.graph.Nodes[fn]; n != nil && !visited[n] {
+			visited[n] = true
+			queue = append(queue, n)
+		}
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		next := n.Out
+		if dir == Callers {
+			next = n.In
+		}
+		for _, e := range next {
+			edges[e] = true
+			other := e.Callee
+			if dir == Callers {
+				other = e.Caller
+			}
+			if !visited[other] {
+				visited[other] = true
+				queue = append(queue, other)
+			}
+		}
+	}
+	for n := range visited {
+		res.Nodes = append(res.Nodes, Node{
+			Name:     n.Func.String(),
+			Position: g.prog.Fset.PositionFor(n.Func.Pos(), false),
+		})
+	}
+	for e := range edges {
+		edge := Edge{
+			Caller: e.Caller.Func.String(),
+			Callee: e.Callee.Func.String(),
+		}
+		if e.Site != nil {
+			edge.Position = g.prog.Fset.PositionFor(e.Site.Pos(), false)
+		}
+		res.Edges = append(res.Edges, edge)
+	}
+	sort.Slice(res.Nodes, func(i, j int) bool {
+		return res.Nodes[i].Name < res.Nodes[j].Name
+	})
+	sort.Slice(res.Edges, func(i, j int) bool {
+		a, b := res.Edges[i], res.Edges[j]
+		if a.Caller != b.Caller {
+			return a.Caller < b.Caller
+		}
+		if a.Callee != b.Callee {
+			return a.Callee < b.Callee
+		}
+		return a.Position.Offset < b.Position.Offset
+	})
+	return res
+}
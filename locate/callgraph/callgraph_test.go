@@ -0,0 +1,125 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package callgraph_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloudeng.io/go/locate"
+	"cloudeng.io/go/locate/callgraph"
+)
+
+const here = "cloudeng.io/go/locate/callgraph/testdata/cg"
+
+func build(t *testing.T, algorithm callgraph.Algorithm, roots ...string) *callgraph.Graph {
+	ctx := context.Background()
+	locator := locate.New(locate.LoadDependencies(), locate.IncludeMethods(true))
+	locator.AddFunctions(roots...)
+	locator.AddPackages(here)
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locate.Do: %v", err)
+	}
+	g, err := callgraph.Build(locator, algorithm)
+	if err != nil {
+		t.Fatalf("callgraph.Build: %v", err)
+	}
+	return g
+}
+
+func nodes(res *callgraph.Result) []string {
+	var names []string
+	for _, n := range res.Nodes {
+		names = append(names, strings.TrimPrefix(n.Name, here+"."))
+	}
+	return names
+}
+
+func TestCallees(t *testing.T) {
+	for i, tc := range []struct {
+		algorithm callgraph.Algorithm
+		root      string
+		nodes     []string
+	}{
+		{callgraph.Static, "Root$", []string{"Helper", "Leaf", "Root"}},
+		{callgraph.CHA, "Root$", []string{
+			"(" + here + ".A).M",
+			"(" + here + ".B).M",
+			"Helper", "Leaf", "Root"}},
+		// RTA only considers types that are used by the roots.
+		{callgraph.RTA, "Root$", []string{"Helper", "Leaf", "Root"}},
+		{callgraph.RTA, "Entry$", []string{
+			"(" + here + ".A).M",
+			"Entry", "Helper", "Leaf", "Root"}},
+	} {
+		g := build(t, tc.algorithm, here+"."+tc.root)
+		res := g.Reachable(callgraph.Callees)
+		if got, want := nodes(res), tc.nodes; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: %v: got %v, want %v", i, tc.algorithm, got, want)
+		}
+	}
+}
+
+func TestCallers(t *testing.T) {
+	g := build(t, callgraph.CHA, here+".Leaf$")
+	res := g.Reachable(callgraph.Callers)
+	want := []string{
+		"(" + here + ".A).M",
+		"Entry", "Helper", "Leaf", "Root", "Unreached"}
+	if got := nodes(res); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, e := range res.Edges {
+		if e.Position.Line == 0 {
+			t.Errorf("%v -> %v: missing position", e.Caller, e.Callee)
+		}
+	}
+}
+
+func TestFormats(t *testing.T) {
+	res := build(t, callgraph.Static, here+".Helper$").Reachable(callgraph.Callees)
+	out := &bytes.Buffer{}
+	if err := res.WriteText(out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), here+".Helper -> "+here+".Leaf: "; !strings.HasPrefix(got, want) {
+		t.Errorf("got %v, want prefix %v", got, want)
+	}
+
+	out.Reset()
+	if err := res.WriteDOT(out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), `"`+here+`.Helper" -> "`+here+`.Leaf";`; !strings.Contains(got, want) {
+		t.Errorf("got %v, does not contain %v", got, want)
+	}
+
+	out.Reset()
+	if err := res.WriteJSON(out); err != nil {
+		t.Fatal(err)
+	}
+	var decoded callgraph.Result
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, res) {
+		t.Errorf("got %v, want %v", decoded, res)
+	}
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	for _, name := range []string{"static", "cha", "rta"} {
+		if a, err := callgraph.ParseAlgorithm(name); err != nil || string(a) != name {
+			t.Errorf("%v: got %v, %v", name, a, err)
+		}
+	}
+	if _, err := callgraph.ParseAlgorithm("vta"); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package callgraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WriteText writes the result as one line per call of the form
+// <caller> -> <callee>: <position>.
+func (r *Result) WriteText(w io.Writer) error {
+	for _, e := range r.Edges {
+		if _, err := fmt.Fprintf(w, "%s -> %s: %s\n", e.Caller, e.Callee, e.Position); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteDOT writes the result in graphviz's DOT format with the roots
+// highlighted.
+func (r *Result) WriteDOT(w io.Writer) error {
+	roots := map[string]bool{}
+	for _, root := range r.Roots {
+		roots[root] = true
+	}
+	if _, err := fmt.Fprintln(w, "digraph callgraph {"); err != nil {
+		return err
+	}
+	for _, n := range r.Nodes {
+		attrs := ""
+		if roots[n.Name] {
+			attrs = " [style=filled]"
+		}
+		if _, err := fmt.Fprintf(w, "\t%s%s;\n", strconv.Quote(n.Name), attrs); err != nil {
+			return err
+		}
+	}
+	for _, e := range r.Edges {
+		if _, err := fmt.Fprintf(w, "\t%s -> %s;\n", strconv.Quote(e.Caller), strconv.Quote(e.Callee)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// WriteJSON writes the result as JSON.
+func (r *Result) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
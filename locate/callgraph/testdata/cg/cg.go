@@ -0,0 +1,34 @@
+package cg
+
+type I interface {
+	M()
+}
+
+type A struct{}
+
+func (A) M() {
+	Leaf()
+}
+
+type B struct{}
+
+func (B) M() {}
+
+func Leaf() {}
+
+func Helper() {
+	Leaf()
+}
+
+func Root(i I) {
+	i.M()
+	Helper()
+}
+
+func Unreached() {
+	Leaf()
+}
+
+func Entry() {
+	Root(A{})
+}
@@ -33,6 +33,10 @@ func (t *T) findComments(_ context.Context, exprs []string) error {
 	if err := errs.Err(); err != nil {
 		return err
 	}
+	if t.options.directives {
+		t.findDirectives(regexps)
+		return nil
+	}
 	t.loader.walkFiles(func(filename string, pkg *packages.Package, cmap ast.CommentMap, file *ast.File) {
 		for k, v := range cmap {
 			for _, cg := range v {
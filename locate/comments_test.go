@@ -6,6 +6,7 @@ package locate_test
 
 import (
 	"context"
+	"fmt"
 	"go/ast"
 	"path/filepath"
 	"testing"
@@ -44,3 +45,54 @@ func TestComments(t *testing.T) {
 	}
 	compareSlices(t, positions, commentsAt)
 }
+
+func TestDirectives(t *testing.T) {
+	ctx := context.Background()
+	locator := locate.New(locate.Directives(true))
+	locator.AddComments(".*")
+	locator.AddPackages(here + "directives")
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locate.Do: %v", err)
+	}
+	found := []string{}
+	locator.WalkDirectives(func(d locate.Directive) {
+		found = append(found, fmt.Sprintf("%v: %v|%v|%v %v %q",
+			d.Position.Line, d.Key, d.Value, d.Args, d.Decl != nil, d.Name))
+	})
+	compareSlices(t, found, []string{
+		"1: go|build|!nobuild false \"\"",
+		"10: go|generate|stringer -type=Kind false \"\"",
+		"14: nolint|revive| true \"Kind\"",
+		"18: nolint|gochecknoglobals| true \"A\"",
+		"22: go|embed|directives.go true \"source\"",
+		"27: nolint|errcheck| true \"Write\"",
+		"32: go|noinline| true \"T.Method\"",
+		"36: nolint|| true \"x,y\"",
+	})
+
+	// Only the directives that match the regular expressions are located
+	// and they are not found without the Directives option.
+	for i, tc := range []struct {
+		opts []locate.Option
+		want int
+	}{
+		{[]locate.Option{locate.Directives(true)}, 5},
+		{nil, 0},
+	} {
+		locator := locate.New(tc.opts...)
+		locator.AddComments("^//(nolint|go:generate)")
+		locator.AddPackages(here + "directives")
+		if err := locator.Do(ctx); err != nil {
+			t.Fatalf("locate.Do: %v", err)
+		}
+		n := 0
+		locator.WalkLocations(func(loc locate.Location) {
+			if loc.Kind == locate.HasDirective || loc.Kind == locate.HasComment {
+				n++
+			}
+		})
+		if got, want := n, tc.want; got != want {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cloudeng.io/sync/errgroup"
+	"golang.org/x/tools/go/packages"
+)
+
+// TypeKind represents the kind of a type declaration, it is used to
+// restrict the types located via AddTypes.
+type TypeKind int
+
+const (
+	// StructType matches struct types, eg. type S struct{}.
+	StructType TypeKind = 1 << iota
+	// InterfaceType matches interface types, eg. type I interface{}.
+	InterfaceType
+	// AliasType matches type aliases, eg. type A = S.
+	AliasType
+	// BasicType matches named basic types, eg. type Celsius float64.
+	BasicType
+	// OtherType matches all other types, eg. maps, slices, functions etc.
+	OtherType
+	// AllTypes matches all types.
+	AllTypes = StructType | InterfaceType | AliasType | BasicType | OtherType
+)
+
+var typeKindNames = []string{
+	"struct",
+	"interface",
+	"alias",
+	"basic",
+	"other",
+}
+
+func (tk TypeKind) String() string {
+	parts := []string{}
+	for i, name := range typeKindNames {
+		if tk&(1<<i) != 0 {
+			parts = append(parts, name)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func typeKindOf(obj *types.TypeName) TypeKind {
+	if obj.IsAlias() {
+		return AliasType
+	}
+	switch obj.Type().Underlying().(type) {
+	case *types.Struct:
+		return StructType
+	case *types.Interface:
+		return InterfaceType
+	case *types.Basic:
+		return BasicType
+	}
+	return OtherType
+}
+
+// TagFilter represents a filter on the tags of struct fields located via
+// AddFields. A field matches the filter if its tag contains Key and the
+// value for that key matches Value, or does not match it if Negate is set.
+// A nil Value matches all values. For example, the following matches all
+// fields with a json tag that does not specify omitempty:
+//
+//	TagFilter{Key: "json", Value: regexp.MustCompile("omitempty"), Negate: true}
+type TagFilter struct {
+	Key    string
+	Value  *regexp.Regexp
+	Negate bool
+}
+
+func (tf TagFilter) matches(tag reflect.StructTag) bool {
+	val, ok := tag.Lookup(tf.Key)
+	if !ok {
+		return false
+	}
+	if tf.Value == nil {
+		return true
+	}
+	return tf.Value.MatchString(val) != tf.Negate
+}
+
+func (tf TagFilter) String() string {
+	expr := ""
+	if tf.Value != nil {
+		expr = tf.Value.String()
+	}
+	return fmt.Sprintf("%s:%q:%v", tf.Key, expr, tf.Negate)
+}
+
+// declSpecs records the specs requested via AddTypes, AddFields, AddConsts
+// and AddVars indexed by the HitMask for each.
+type declSpecs map[HitMask][]string
+
+func (ds declSpecs) all() []string {
+	var all []string
+	for _, kind := range declKinds {
+		all = append(all, ds[kind]...)
+	}
+	return all
+}
+
+func (ds declSpecs) filter(keep map[string]bool) declSpecs {
+	filtered := declSpecs{}
+	for kind, specs := range ds {
+		filtered[kind] = filterSpecs(specs, keep)
+	}
+	return filtered
+}
+
+var declKinds = []HitMask{HasType, HasField, HasConst, HasVar}
+
+type declDesc struct {
+	kind HitMask
+	// name is the package local name, ie. <name> or <type>.<field>.
+	name string
+	path string
+	pkg  *packages.Package
+	file *ast.File
+	obj  types.Object
+	// One of *ast.TypeSpec, *ast.Field or *ast.ValueSpec.
+	node     ast.Node
+	tag      reflect.StructTag
+	position token.Position
+}
+
+func (t *T) findDeclarations(ctx context.Context, specs declSpecs) error {
+	group, ctx := errgroup.WithContext(ctx)
+	group = errgroup.WithConcurrency(group, t.options.concurrency)
+	for _, kind := range declKinds {
+		for _, spec := range specs[kind] {
+			pkgPath, re, err := getPathAndRegexp(spec)
+			if err != nil {
+				return err
+			}
+			kind := kind
+			group.GoContext(ctx, func() error {
+				return t.findDeclarationsInPackage(ctx, kind, pkgPath, re)
+			})
+		}
+	}
+	return group.Wait()
+}
+
+func (t *T) findDeclarationsInPackage(_ context.Context, kind HitMask, pkgPath string, re *regexp.Regexp) error {
+	pkg, err := t.lookupTypedPackage(pkgPath, "locating "+kind.String()+"s")
+	if pkg == nil {
+		return err
+	}
+	found := 0
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				var descs []declDesc
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					descs = t.typeDeclarations(kind, pkg, spec, re)
+				case *ast.ValueSpec:
+					descs = valueDeclarations(kind, gd.Tok, pkg, spec, re)
+				}
+				for _, desc := range descs {
+					if t.excludedName(kind, pkgPath, desc.name) {
+						continue
+					}
+					desc.path, desc.pkg, desc.file = pkgPath, pkg, file
+					desc.position = pkg.Fset.PositionFor(desc.obj.Pos(), false)
+					t.addDeclaration(desc)
+					found++
+				}
+			}
+		}
+	}
+	if !t.options.ignoreMissingFunctionsEtc && found == 0 {
+		return fmt.Errorf("failed to find any exported %vs in %v for %s", kind, pkgPath, re)
+	}
+	return nil
+}
+
+func (t *T) typeDeclarations(kind HitMask, pkg *packages.Package, spec *ast.TypeSpec, re *regexp.Regexp) []declDesc {
+	if !spec.Name.IsExported() || (kind != HasType && kind != HasField) {
+		return nil
+	}
+	obj, ok := pkg.TypesInfo.Defs[spec.Name].(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	if kind == HasType {
+		kinds := t.options.typeKinds
+		if kinds == 0 {
+			kinds = AllTypes
+		}
+		if !re.MatchString(spec.Name.Name) || typeKindOf(obj)&kinds == 0 {
+			return nil
+		}
+		return []declDesc{{kind: kind, name: obj.Name(), obj: obj, node: spec}}
+	}
+	// Fields are only located in the struct literal that declares them
+	// and not in types defined in terms of that struct.
+	astStruct, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return nil
+	}
+	st := obj.Type().Underlying().(*types.Struct)
+	var descs []declDesc
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		tag := reflect.StructTag(st.Tag(i))
+		name := spec.Name.Name + "." + field.Name()
+		if !field.Exported() || !re.MatchString(name) || !t.tagsMatch(tag) {
+			continue
+		}
+		descs = append(descs, declDesc{
+			kind: kind,
+			name: name,
+			obj:  field,
+			node: findField(astStruct, field.Pos()),
+			tag:  tag,
+		})
+	}
+	return descs
+}
+
+func (t *T) tagsMatch(tag reflect.StructTag) bool {
+	for _, tf := range t.options.tagFilters {
+		if !tf.matches(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// findField returns the ast.Field that declares the field at pos.
+func findField(st *ast.StructType, pos token.Pos) *ast.Field {
+	for _, field := range st.Fields.List {
+		if pos >= field.Pos() && pos < field.End() {
+			return field
+		}
+	}
+	return nil
+}
+
+func valueDeclarations(kind HitMask, tok token.Token, pkg *packages.Package, spec *ast.ValueSpec, re *regexp.Regexp) []declDesc {
+	if !(kind == HasConst && tok == token.CONST) && !(kind == HasVar && tok == token.VAR) {
+		return nil
+	}
+	var descs []declDesc
+	for _, name := range spec.Names {
+		if !name.IsExported() || !re.MatchString(name.Name) {
+			continue
+		}
+		if obj := pkg.TypesInfo.Defs[name]; obj != nil {
+			descs = append(descs, declDesc{kind: kind, name: name.Name, obj: obj, node: spec})
+		}
+	}
+	return descs
+}
+
+func (t *T) addDeclaration(desc declDesc) {
+	if t.loader.isExcluded(desc.position.Filename) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fqn := desc.path + "." + desc.name
+	t.declarations[desc.kind][fqn] = desc
+	t.dirty[desc.position.Filename] |= desc.kind
+	t.trace("%v: %v @ %v\n", desc.kind, fqn, desc.position)
+}
+
+func (t *T) sortedDeclarations(kind HitMask) []sortByPos {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sorted := make([]sortByPos, 0, len(t.declarations[kind]))
+	for k, v := range t.declarations[kind] {
+		sorted = append(sorted, sortByPos{
+			name:    k,
+			pos:     v.position,
+			payload: v,
+		})
+	}
+	sorter(sorted)
+	return sorted
+}
+
+// WalkTypes calls the supplied function for each type located via AddTypes.
+// The function is called with the packages.Package and ast for the file
+// that contains the type, as well as its declaration and type. The function
+// is called in order of filename and then position within filename.
+func (t *T) WalkTypes(fn func(
+	fullname string,
+	pkg *packages.Package,
+	file *ast.File,
+	decl *ast.TypeSpec,
+	typ *types.TypeName)) {
+	for _, loc := range t.sortedDeclarations(HasType) {
+		desc := loc.payload.(declDesc)
+		fn(loc.name, desc.pkg, desc.file, desc.node.(*ast.TypeSpec), desc.obj.(*types.TypeName))
+	}
+}
+
+// WalkFields calls the supplied function for each struct field located via
+// AddFields. The fullname of a field is of the form
+// <package>.<type>.<field>. The function is called with the
+// packages.Package and ast for the file that contains the field, its
+// declaration, type and tag. Note that a single ast.Field may declare
+// more than one field. The function is called in order of filename and
+// then position within filename.
+func (t *T) WalkFields(fn func(
+	fullname string,
+	pkg *packages.Package,
+	file *ast.File,
+	decl *ast.Field,
+	field *types.Var,
+	tag reflect.StructTag)) {
+	for _, loc := range t.sortedDeclarations(HasField) {
+		desc := loc.payload.(declDesc)
+		fn(loc.name, desc.pkg, desc.file, desc.node.(*ast.Field), desc.obj.(*types.Var), desc.tag)
+	}
+}
+
+// WalkConsts calls the supplied function for each constant located via
+// AddConsts. The function is called with the packages.Package and ast for
+// the file that contains the constant, its declaration and type. The
+// function is called in order of filename and then position within
+// filename.
+func (t *T) WalkConsts(fn func(
+	fullname string,
+	pkg *packages.Package,
+	file *ast.File,
+	decl *ast.ValueSpec,
+	obj *types.Const)) {
+	for _, loc := range t.sortedDeclarations(HasConst) {
+		desc := loc.payload.(declDesc)
+		fn(loc.name, desc.pkg, desc.file, desc.node.(*ast.ValueSpec), desc.obj.(*types.Const))
+	}
+}
+
+// WalkVars calls the supplied function for each package level variable
+// located via AddVars. The function is called with the packages.Package and
+// ast for the file that contains the variable, its declaration and type.
+// The function is called in order of filename and then position within
+// filename.
+func (t *T) WalkVars(fn func(
+	fullname string,
+	pkg *packages.Package,
+	file *ast.File,
+	decl *ast.ValueSpec,
+	obj *types.Var)) {
+	for _, loc := range t.sortedDeclarations(HasVar) {
+		desc := loc.payload.(declDesc)
+		fn(loc.name, desc.pkg, desc.file, desc.node.(*ast.ValueSpec), desc.obj.(*types.Var))
+	}
+}
+
+// declarationLocationsLocked returns the locations for the located types,
+// fields, constants and variables.
+func (t *T) declarationLocationsLocked() []Location {
+	var locs []Location
+	for _, kind := range declKinds {
+		for name, desc := range t.declarations[kind] {
+			detail := desc.obj.Type().String()
+			if kind == HasType {
+				detail = typeKindOf(desc.obj.(*types.TypeName)).String()
+			}
+			locs = append(locs, Location{
+				Kind:     kind,
+				Package:  desc.path,
+				Module:   modulePathFor(desc.pkg),
+				Name:     name,
+				Detail:   detail,
+				Position: desc.position,
+				Tag:      string(desc.tag),
+			})
+		}
+	}
+	return locs
+}
+
+func tagFilterStrings(filters []TagFilter) []string {
+	strs := make([]string, len(filters))
+	for i, tf := range filters {
+		strs[i] = tf.String()
+	}
+	sort.Strings(strs)
+	return strs
+}
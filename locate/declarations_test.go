@@ -0,0 +1,163 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate_test
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"cloudeng.io/go/locate"
+	"golang.org/x/tools/go/packages"
+)
+
+func listTypes(locator *locate.T) []string {
+	out := []string{}
+	locator.WalkTypes(func(name string, pkg *packages.Package, _ *ast.File, decl *ast.TypeSpec, _ *types.TypeName) {
+		out = append(out, fmt.Sprintf("%v @ %v", name, pkg.Fset.PositionFor(decl.Pos(), false).Line))
+	})
+	return out
+}
+
+func listFields(locator *locate.T) []string {
+	out := []string{}
+	locator.WalkFields(func(name string, pkg *packages.Package, _ *ast.File, decl *ast.Field, field *types.Var, tag reflect.StructTag) {
+		out = append(out, fmt.Sprintf("%v %v %q @ %v", name, field.Type(), tag, pkg.Fset.PositionFor(decl.Pos(), false).Line))
+	})
+	return out
+}
+
+func listValues(locator *locate.T) []string {
+	out := []string{}
+	locator.WalkConsts(func(name string, pkg *packages.Package, _ *ast.File, _ *ast.ValueSpec, obj *types.Const) {
+		out = append(out, fmt.Sprintf("const %v = %v", name, obj.Val()))
+	})
+	locator.WalkVars(func(name string, pkg *packages.Package, _ *ast.File, _ *ast.ValueSpec, obj *types.Var) {
+		out = append(out, fmt.Sprintf("var %v %v", name, obj.Type()))
+	})
+	return out
+}
+
+func trimAll(lines []string, prefix string) []string {
+	for i, l := range lines {
+		lines[i] = strings.ReplaceAll(l, prefix, "")
+	}
+	return lines
+}
+
+func TestTypes(t *testing.T) {
+	ctx := context.Background()
+	for i, tc := range []struct {
+		kinds locate.TypeKind
+		spec  string
+		want  []string
+	}{
+		{0, "decls", []string{"Config @ 5", "Alias @ 13", "Celsius @ 15", "Handler @ 17", "Derived @ 19", "Storer @ 21"}},
+		{locate.AllTypes, "decls.C", []string{"Config @ 5", "Celsius @ 15"}},
+		{locate.StructType, "decls", []string{"Config @ 5", "Derived @ 19"}},
+		{locate.AliasType | locate.BasicType, "decls", []string{"Alias @ 13", "Celsius @ 15"}},
+		{locate.InterfaceType | locate.OtherType, "decls", []string{"Handler @ 17", "Storer @ 21"}},
+	} {
+		locator := locate.New(locate.TypeKinds(tc.kinds))
+		locator.AddTypes(here + tc.spec)
+		if err := locator.Do(ctx); err != nil {
+			t.Fatalf("locator.Do: %v", err)
+		}
+		if got, want := trimAll(listTypes(locator), here+"decls."), tc.want; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+	}
+
+	locator := locate.New(locate.TypeKinds(locate.StructType))
+	locator.AddTypes(here + "decls.Handler")
+	if err := locator.Do(ctx); err == nil || !strings.Contains(err.Error(), "failed to find any exported types") {
+		t.Errorf("missing or wrong error: %v", err)
+	}
+}
+
+func TestFields(t *testing.T) {
+	ctx := context.Background()
+	for i, tc := range []struct {
+		filters []locate.TagFilter
+		spec    string
+		want    []string
+	}{
+		{nil, "decls", []string{
+			`Config.Name string "json:\"name\"" @ 6`,
+			`Config.Count int "json:\"count,omitempty\"" @ 7`,
+			`Config.Ignored bool "json:\"-\"" @ 8`,
+			`Config.Untagged string "" @ 10`,
+			`Config.Other string "" @ 10`,
+		}},
+		{nil, `decls.Config\.(Name|Other)$`, []string{
+			`Config.Name string "json:\"name\"" @ 6`,
+			`Config.Other string "" @ 10`,
+		}},
+		{[]locate.TagFilter{{Key: "json"}}, "decls", []string{
+			`Config.Name string "json:\"name\"" @ 6`,
+			`Config.Count int "json:\"count,omitempty\"" @ 7`,
+			`Config.Ignored bool "json:\"-\"" @ 8`,
+		}},
+		{[]locate.TagFilter{
+			{Key: "json", Value: regexp.MustCompile("omitempty"), Negate: true},
+			{Key: "json", Value: regexp.MustCompile("^-$"), Negate: true},
+		}, "decls", []string{
+			`Config.Name string "json:\"name\"" @ 6`,
+		}},
+	} {
+		locator := locate.New(locate.FieldTags(tc.filters...))
+		locator.AddFields(here + tc.spec)
+		if err := locator.Do(ctx); err != nil {
+			t.Fatalf("locator.Do: %v", err)
+		}
+		if got, want := trimAll(listFields(locator), here+"decls."), tc.want; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestConstsAndVars(t *testing.T) {
+	ctx := context.Background()
+	locator := locate.New()
+	locator.AddConsts(here + "decls")
+	locator.AddVars(here + "decls.^Err")
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locator.Do: %v", err)
+	}
+	want := []string{
+		"const Boiling = 100",
+		"const MaxItems = 10",
+		"var ErrNotFound error",
+		"var ErrInvalid error",
+	}
+	if got := trimAll(listValues(locator), here+"decls."); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	files := listFiles(locator)
+	if got, want := len(files), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := files[0], "(const, var)"; !strings.HasSuffix(got, want) {
+		t.Errorf("got %v, want suffix %v", got, want)
+	}
+
+	locs := []string{}
+	locator.WalkLocations(func(loc locate.Location) {
+		locs = append(locs, fmt.Sprintf("%v: %v: %v", loc.Kind, strings.TrimPrefix(loc.Name, here+"decls."), loc.Detail))
+	})
+	if got, want := locs, []string{
+		"const: Boiling: " + here + "decls.Celsius",
+		"const: MaxItems: untyped int",
+		"var: ErrNotFound: error",
+		"var: ErrInvalid: error",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
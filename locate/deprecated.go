@@ -0,0 +1,240 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"cloudeng.io/go/locate/locateutil"
+	"cloudeng.io/sync/errgroup"
+	"golang.org/x/tools/go/packages"
+)
+
+// DeprecatedUse represents a use of a deprecated function, method, type,
+// struct field or constant, ie. one whose doc comment contains a paragraph
+// that starts with "Deprecated: ".
+type DeprecatedUse struct {
+	// Name is the fully qualified name of the deprecated object, of the
+	// form <package>.<name>, (<package>.<type>).<method> for methods or
+	// <package>.<type>.<field> for fields.
+	Name string
+	// Message is the text of the deprecation notice.
+	Message string
+	// Object is the deprecated object.
+	Object types.Object
+	// Decl is the declaration that contains the use and DeclName its
+	// name, <type>.<method> for methods. Both are empty for uses that
+	// are not within a declaration.
+	Decl     ast.Decl
+	DeclName string
+	Package  *packages.Package
+	File     *ast.File
+	Ident    *ast.Ident
+	Position token.Position
+}
+
+// deprecation records the name of a deprecated object and the text of
+// its deprecation notice.
+type deprecation struct {
+	name    string
+	message string
+}
+
+// AddDeprecatedUses adds packages that will be searched for uses of the
+// deprecated functions, methods, types, struct fields and constants
+// declared in those packages or in any of their dependencies. Uses within
+// the package that declares the deprecated object are ignored. The
+// dependencies of the packages are loaded, as per LoadDependencies, and
+// hence the cache, if any, is not used. When UseSnapshot is in effect
+// only the dependencies loaded by the snapshot, ie. none unless it was
+// created with LoadDependencies, are searched. See WalkDeprecatedUses.
+func (t *T) AddDeprecatedUses(packages ...string) {
+	t.deprecatedPackages = append(t.deprecatedPackages, packages...)
+}
+
+// findDeprecated returns the deprecated objects declared in the
+// specified packages and all of their dependencies.
+func (t *T) findDeprecated(pkgPaths []string) map[types.Object]deprecation {
+	var roots []*packages.Package
+	for _, path := range pkgPaths {
+		if pkg := t.loader.lookupPackage(path); pkg != nil {
+			roots = append(roots, pkg)
+		}
+	}
+	deprecated := map[types.Object]deprecation{}
+	packages.Visit(roots, nil, func(pkg *packages.Package) {
+		if pkg.TypesInfo == nil {
+			return
+		}
+		for _, file := range pkg.Syntax {
+			deprecatedInFile(pkg, file, deprecated)
+		}
+	})
+	return deprecated
+}
+
+func deprecatedInFile(pkg *packages.Package, file *ast.File, deprecated map[types.Object]deprecation) {
+	add := func(id *ast.Ident, name string, docs ...*ast.CommentGroup) {
+		obj := pkg.TypesInfo.Defs[id]
+		if obj == nil {
+			return
+		}
+		for _, doc := range docs {
+			if msg, ok := locateutil.Deprecation(doc); ok {
+				deprecated[obj] = deprecation{name: name, message: msg}
+				return
+			}
+		}
+	}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if obj, ok := pkg.TypesInfo.Defs[decl.Name].(*types.Func); ok {
+				add(decl.Name, obj.FullName(), decl.Doc)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					typeName := pkg.PkgPath + "." + spec.Name.Name
+					add(spec.Name, typeName, spec.Doc, decl.Doc)
+					deprecatedMembers(pkg, typeName, spec.Type, add)
+				case *ast.ValueSpec:
+					if decl.Tok != token.CONST {
+						continue
+					}
+					for _, id := range spec.Names {
+						add(id, pkg.PkgPath+"."+id.Name, spec.Doc, decl.Doc)
+					}
+				}
+			}
+		}
+	}
+}
+
+// deprecatedMembers adds the deprecated fields of a struct type and the
+// deprecated methods of an interface type.
+func deprecatedMembers(pkg *packages.Package, typeName string, expr ast.Expr, add func(*ast.Ident, string, ...*ast.CommentGroup)) {
+	switch typ := expr.(type) {
+	case *ast.StructType:
+		for _, field := range typ.Fields.List {
+			for _, id := range field.Names {
+				add(id, typeName+"."+id.Name, field.Doc)
+			}
+		}
+	case *ast.InterfaceType:
+		for _, field := range typ.Methods.List {
+			for _, id := range field.Names {
+				if fn, ok := pkg.TypesInfo.Defs[id].(*types.Func); ok {
+					add(id, fn.FullName(), field.Doc)
+				}
+			}
+		}
+	}
+}
+
+func (t *T) findDeprecatedUses(ctx context.Context, pkgPaths []string) error {
+	if len(pkgPaths) == 0 {
+		return nil
+	}
+	deprecated := t.findDeprecated(pkgPaths)
+	group, ctx := errgroup.WithContext(ctx)
+	group = errgroup.WithConcurrency(group, t.options.concurrency)
+	for _, pkg := range pkgPaths {
+		pkg := pkg
+		group.GoContext(ctx, func() error {
+			return t.findDeprecatedUsesInPackage(ctx, pkg, deprecated)
+		})
+	}
+	return group.Wait()
+}
+
+func (t *T) findDeprecatedUsesInPackage(_ context.Context, pkgPath string, deprecated map[types.Object]deprecation) error {
+	pkg, err := t.lookupTypedPackage(pkgPath, "locating deprecated uses")
+	if pkg == nil {
+		return err
+	}
+	// External test packages are treated as being part of the package
+	// they test.
+	self := strings.TrimSuffix(pkg.PkgPath, "_test")
+	for _, file := range pkg.Syntax {
+		filename := pkg.Fset.PositionFor(file.Pos(), false).Filename
+		if t.loader.isExcluded(filename) {
+			continue
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			obj := origin(pkg.TypesInfo.Uses[id])
+			if obj == nil || obj.Pkg() == nil || obj.Pkg().Path() == self {
+				return true
+			}
+			dep, ok := deprecated[obj]
+			if !ok {
+				return true
+			}
+			decl, declName := locateutil.EnclosingDecl(file, id.Pos())
+			t.addDeprecatedUse(filename, DeprecatedUse{
+				Name:     dep.name,
+				Message:  dep.message,
+				Object:   obj,
+				Decl:     decl,
+				DeclName: declName,
+				Package:  pkg,
+				File:     file,
+				Ident:    id,
+				Position: pkg.Fset.PositionFor(id.Pos(), false),
+			})
+			return true
+		})
+	}
+	return nil
+}
+
+// origin returns the generic object that obj is an instance of, if any.
+func origin(obj types.Object) types.Object {
+	switch o := obj.(type) {
+	case *types.Func:
+		return o.Origin()
+	case *types.Var:
+		return o.Origin()
+	}
+	return obj
+}
+
+func (t *T) addDeprecatedUse(filename string, use DeprecatedUse) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dirty[filename] |= HasDeprecatedUse
+	t.deprecatedUses = append(t.deprecatedUses, use)
+	t.trace("deprecated: %v @ %v\n", use.Name, use.Position)
+}
+
+// WalkDeprecatedUses calls the supplied function for each use of a
+// deprecated object found in the packages specified via
+// AddDeprecatedUses. The function is called in order of filename and
+// then position within filename.
+func (t *T) WalkDeprecatedUses(fn func(use DeprecatedUse)) {
+	t.mu.Lock()
+	uses := append([]DeprecatedUse{}, t.deprecatedUses...)
+	t.mu.Unlock()
+	sort.SliceStable(uses, func(i, j int) bool {
+		a, b := uses[i].Position, uses[j].Position
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+	for _, use := range uses {
+		fn(use)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"cloudeng.io/go/locate"
+)
+
+func TestDeprecatedUses(t *testing.T) {
+	ctx := context.Background()
+	locator := locate.New()
+	locator.AddDeprecatedUses(here+"deprecated", here+"deprecated/old")
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locator.Do: %v", err)
+	}
+	old := here + "deprecated/old"
+	var uses []string
+	locator.WalkDeprecatedUses(func(use locate.DeprecatedUse) {
+		uses = append(uses, fmt.Sprintf("%v:%v: %v [%v]: %v",
+			filepath.Base(use.Position.Filename), use.Position.Line, use.Name, use.DeclName, use.Message))
+	})
+	expected := []string{
+		"use.go:10: " + old + ".Func [Uses]: use NewFunc instead.",
+		"use.go:12: " + old + ".Type [Uses]: use NewType instead.",
+		"use.go:13: " + old + ".Type.Field [Uses]: use Other.",
+		"use.go:13: " + old + ".A [Uses]: all of these constants are old.",
+		"use.go:13: " + old + ".C [Uses]: use D.",
+		"use.go:14: (" + old + ".Type).Method [Uses]: no longer required.",
+		"use.go:15: (" + old + ".Ifc).Old [Uses]: use Current.",
+		"use.go:17: " + old + ".Generic [Uses]: use generics elsewhere.",
+		"use.go:18: strings.Title [Uses]: The rule Title uses for word boundaries does not handle Unicode punctuation properly. Use golang.org/x/text/cases instead.",
+		"use.go:21: " + old + ".B [v]: all of these constants are old.",
+	}
+	if got, want := uses, expected; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+	n := 0
+	locator.WalkLocations(func(loc locate.Location) {
+		if loc.Kind == locate.HasDeprecatedUse {
+			n++
+		}
+	})
+	if got, want := n, len(expected); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
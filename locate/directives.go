@@ -0,0 +1,112 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate
+
+import (
+	"go/ast"
+	"go/token"
+	"regexp"
+	"sort"
+
+	"cloudeng.io/go/locate/locateutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// Directive represents a directive comment, eg. //go:generate or
+// //nolint:errcheck, located via AddComments when the Directives option
+// is in effect.
+type Directive struct {
+	locateutil.Directive
+	// Regexp is the regular expression that matched the directive.
+	Regexp string
+	// Text is the raw text of the comment.
+	Text string
+	// Decl is the declaration that the directive applies to, ie. the
+	// declaration that the directive is part of the doc comment for,
+	// is contained within or trails on the same line. It is nil for
+	// file level directives such as //go:build.
+	Decl ast.Decl
+	// Name is the name of the declaration, <type>.<method> for methods
+	// and a comma separated list of names for multiple variables or
+	// constants.
+	Name     string
+	Comment  *ast.Comment
+	Package  *packages.Package
+	File     *ast.File
+	Position token.Position
+}
+
+// Directives controls whether the regular expressions specified via
+// AddComments are matched against the raw text, including the leading //,
+// of individual directive comments rather than the text of comment groups.
+// Directives are stripped from the text of comment groups and hence cannot
+// be located otherwise. The directives found are available via
+// WalkDirectives and WalkLocations.
+func Directives(val bool) Option {
+	return func(o *options) {
+		o.directives = val
+	}
+}
+
+func (t *T) findDirectives(regexps []*regexp.Regexp) {
+	t.loader.walkFiles(func(filename string, pkg *packages.Package, _ ast.CommentMap, file *ast.File) {
+		for _, cg := range file.Comments {
+			for _, c := range cg.List {
+				directive, ok := locateutil.ParseDirective(c.Text)
+				if !ok {
+					continue
+				}
+				for _, re := range regexps {
+					if !re.MatchString(c.Text) {
+						continue
+					}
+					decl, name := locateutil.CommentDecl(pkg.Fset, file, cg)
+					t.addDirective(filename, Directive{
+						Directive: directive,
+						Regexp:    re.String(),
+						Text:      c.Text,
+						Decl:      decl,
+						Name:      name,
+						Comment:   c,
+						Package:   pkg,
+						File:      file,
+						Position:  pkg.Fset.PositionFor(c.Pos(), false),
+					})
+				}
+			}
+		}
+	})
+}
+
+func (t *T) addDirective(filename string, directive Directive) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dirty[filename] |= HasDirective
+	t.directives = append(t.directives, directive)
+	t.trace("directive: %v @ %v\n", directive.Text, directive.Position)
+}
+
+// WalkDirectives calls the supplied function for each directive that was
+// matched by the regular expressions specified via AddComments when the
+// Directives option is in effect. The function is called in order of
+// filename and then position within filename.
+func (t *T) WalkDirectives(fn func(directive Directive)) {
+	t.mu.Lock()
+	directives := append([]Directive{}, t.directives...)
+	t.mu.Unlock()
+	sort.SliceStable(directives, func(i, j int) bool {
+		a, b := directives[i].Position, directives[j].Position
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		return directives[i].Regexp < directives[j].Regexp
+	})
+	for _, d := range directives {
+		fn(d)
+	}
+}
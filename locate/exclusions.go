@@ -0,0 +1,125 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"regexp"
+	"strings"
+)
+
+// IsExclusion returns true if spec is a negative spec, ie. one that starts
+// with - or !.
+func IsExclusion(spec string) bool {
+	return strings.HasPrefix(spec, "-") || strings.HasPrefix(spec, "!")
+}
+
+// splitExclusions splits specs into positive and negative specs, with the
+// leading - or ! removed from the latter.
+func splitExclusions(specs []string) (include, exclude []string) {
+	for _, spec := range specs {
+		if IsExclusion(spec) {
+			exclude = append(exclude, spec[1:])
+			continue
+		}
+		include = append(include, spec)
+	}
+	return
+}
+
+// nameExclusion represents a negative <package>.<regex> spec.
+type nameExclusion struct {
+	path string
+	re   *regexp.Regexp
+}
+
+func compileExclusions(specs []string) ([]nameExclusion, error) {
+	exclusions := make([]nameExclusion, 0, len(specs))
+	for _, spec := range specs {
+		if _, signature := splitSignature(spec); len(signature) > 0 {
+			return nil, fmt.Errorf("signature predicates are not supported for exclusions: %v", spec)
+		}
+		path, re, err := getPathAndRegexp(spec)
+		if err != nil {
+			return nil, err
+		}
+		exclusions = append(exclusions, nameExclusion{path: path, re: re})
+	}
+	return exclusions, nil
+}
+
+// excludedName returns true if the package local name in package path
+// matches any of the negative specs for the specified kind.
+func (t *T) excludedName(kind HitMask, path, name string) bool {
+	for _, ex := range t.exclusions[kind] {
+		if ex.path == path && ex.re.MatchString(name) {
+			t.trace("excluded: %v: %v.%v\n", kind, path, name)
+			return true
+		}
+	}
+	return false
+}
+
+// excludedFile returns true if the specified file is excluded via
+// ExcludeFiles or ExcludeGenerated.
+func (t *T) excludedFile(filename string, file *ast.File) bool {
+	for _, re := range t.options.excludeFiles {
+		if re.MatchString(filename) {
+			return true
+		}
+	}
+	return t.options.excludeGenerated && file != nil && ast.IsGenerated(file)
+}
+
+// excludePackages removes the packages that match the supplied negative
+// go list expressions or package paths from paths, which are treated as
+// <package>.<regex> specs if specs is set.
+func (t *T) excludePackages(ctx context.Context, paths, exclusions []string, specs bool) ([]string, error) {
+	excluded := map[string]bool{}
+	var tolist []string
+	for _, ex := range exclusions {
+		// Signatures are irrelevant when excluding entire packages.
+		ex, _ = splitSignature(ex)
+		if IsGoListPath(ex) {
+			tolist = append(tolist, ex)
+			continue
+		}
+		excluded[ex] = true
+	}
+	if len(tolist) > 0 {
+		listed, err := t.listPackages(ctx, tolist)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range listed {
+			excluded[path] = true
+		}
+	}
+	filtered := make([]string, 0, len(paths))
+	for _, path := range paths {
+		pkgPath := path
+		if specs {
+			pkgPath, _ = parseSpecAndRegexp(path)
+		}
+		if excluded[pkgPath] {
+			t.trace("excluded: package: %v\n", path)
+			continue
+		}
+		filtered = append(filtered, path)
+	}
+	return filtered, nil
+}
+
+func exclusionStrings(exclusions map[HitMask][]nameExclusion) []string {
+	var strs []string
+	for _, kind := range append([]HitMask{HasInterface, HasFunction}, declKinds...) {
+		for _, ex := range exclusions[kind] {
+			strs = append(strs, fmt.Sprintf("-%v:%v.%v", kind, ex.path, ex.re))
+		}
+	}
+	return strs
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate_test
+
+import (
+	"context"
+	"go/ast"
+	"go/types"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"cloudeng.io/go/locate"
+	"golang.org/x/tools/go/packages"
+)
+
+func functionNames(locator *locate.T) []string {
+	out := []string{}
+	locator.WalkFunctions(func(name string, _ *packages.Package, _ *ast.File, _ *types.Func, _ *ast.FuncDecl, _ []string) {
+		out = append(out, strings.TrimPrefix(name, here+"exclusions"))
+	})
+	return out
+}
+
+func TestExclusions(t *testing.T) {
+	ctx := context.Background()
+	for i, tc := range []struct {
+		opts      []locate.Option
+		functions []string
+		want      []string
+	}{
+		{nil, []string{"./testdata/exclusions/..."},
+			[]string{".Current", ".LegacyOpen", ".LegacyClose", ".Generated", "/internal.Internal"}},
+		{nil, []string{"./testdata/exclusions/...", "-./testdata/exclusions/internal/..."},
+			[]string{".Current", ".LegacyOpen", ".LegacyClose", ".Generated"}},
+		{nil, []string{"./testdata/exclusions/...", "!" + here + "exclusions/internal"},
+			[]string{".Current", ".LegacyOpen", ".LegacyClose", ".Generated"}},
+		{nil, []string{here + "exclusions", "!" + here + "exclusions.Legacy"},
+			[]string{".Current", ".Generated"}},
+		{nil, []string{here + "exclusions", "-" + here + "exclusions.Close$", "-" + here + "exclusions.^G"},
+			[]string{".Current", ".LegacyOpen"}},
+		{[]locate.Option{locate.ExcludeGenerated(true)}, []string{"./testdata/exclusions/..."},
+			[]string{".Current", ".LegacyOpen", ".LegacyClose", "/internal.Internal"}},
+		{[]locate.Option{locate.ExcludeFiles(regexp.MustCompile(`/internal/`))}, []string{"./testdata/exclusions/..."},
+			[]string{".Current", ".LegacyOpen", ".LegacyClose", ".Generated"}},
+	} {
+		locator := locate.New(tc.opts...)
+		locator.AddFunctions(tc.functions...)
+		if err := locator.Do(ctx); err != nil {
+			t.Fatalf("%v: locator.Do: %v", i, err)
+		}
+		if got, want := functionNames(locator), tc.want; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestExcludedPackagesAndFiles(t *testing.T) {
+	ctx := context.Background()
+	locator := locate.New(locate.ExcludeGenerated(true))
+	locator.AddPackages("./testdata/exclusions/...", "-./testdata/exclusions/internal")
+	locator.AddComments("is")
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locator.Do: %v", err)
+	}
+	if got, want := locator.Packages(), []string{here + "exclusions"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	files := []string{}
+	locator.WalkFiles(func(name string, _ *packages.Package, _ ast.CommentMap, _ *ast.File, _ locate.HitMask) {
+		files = append(files, name[strings.LastIndex(name, "/")+1:])
+	})
+	if got, want := files, []string{"exclusions.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	locator = locate.New()
+	locator.AddFunctions("!" + here + "exclusions.Legacy;results=error")
+	if err := locator.Do(ctx); err == nil || !strings.Contains(err.Error(), "not supported for exclusions") {
+		t.Errorf("missing or wrong error: %v", err)
+	}
+}
@@ -11,7 +11,8 @@ import (
 )
 
 // WalkFiles calls the supplied function for each file that contains
-// a located comment, interface or function, ordered by filename. The function
+// a located comment, interface, function, type, field, constant or variable,
+// ordered by filename. The function
 // is called with the absolute file name of the file, the packages.Package
 // to which it belongs and its ast. The function is called in order of
 // filename and then position within filename.
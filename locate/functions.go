@@ -10,6 +10,7 @@ import (
 	"go/ast"
 	"go/types"
 	"regexp"
+	"slices"
 	"sort"
 
 	"cloudeng.io/go/locate/locateutil"
@@ -21,24 +22,27 @@ func (t *T) findFunctions(ctx context.Context, functions []string) error {
 	group, ctx := errgroup.WithContext(ctx)
 	group = errgroup.WithConcurrency(group, t.options.concurrency)
 	for _, name := range functions {
-		pkgPath, nameRE, err := getPathAndRegexp(name)
+		pkgPath, nameRE, predicates, err := getPathRegexpAndSignature(name)
 		if err != nil {
 			return err
 		}
 		group.GoContext(ctx, func() error {
-			return t.findFunctionsInPackage(ctx, pkgPath, nameRE)
+			return t.findFunctionsInPackage(ctx, pkgPath, nameRE, predicates)
 		})
 	}
 	return group.Wait()
 }
 
-func (t *T) findFunctionsInPackage(_ context.Context, pkgPath string, fnRE *regexp.Regexp) error {
-	pkg := t.loader.lookupPackage(pkgPath)
+func (t *T) findFunctionsInPackage(_ context.Context, pkgPath string, fnRE *regexp.Regexp, predicates []locateutil.SignaturePredicate) error {
+	pkg, err := t.lookupTypedPackage(pkgPath, "locating functions")
 	if pkg == nil {
-		return fmt.Errorf("locating functions: failed to lookup: %v", pkgPath)
+		return err
 	}
-	funcs := locateutil.Functions(pkg, fnRE, !t.options.includeMethods)
+	funcs := locateutil.Functions(pkg, fnRE, !t.options.includeMethods, predicates...)
 	for _, fd := range funcs {
+		if t.excludedName(HasFunction, pkgPath, fd.Type.Name()) {
+			continue
+		}
 		t.addFunction(fd, pkgPath, "")
 	}
 	if !t.options.ignoreMissingFunctionsEtc && len(funcs) == 0 {
@@ -54,12 +58,22 @@ func (t *T) addFunction(desc locateutil.FuncDesc, path string, implements string
 }
 
 func (t *T) addFunctionLocked(desc locateutil.FuncDesc, path string, implements string) {
+	if t.loader.isExcluded(desc.Position.Filename) {
+		return
+	}
 	fqn := desc.Type.FullName()
-	var ifcs []string
+	// Functions may be found by both AddFunctions and AddInterfaces, in
+	// either order, and hence any interfaces already recorded for the
+	// function must be retained.
+	ifcs := t.functions[fqn].implements
 	if len(implements) > 0 {
-		//nolint:gocritic
-		ifcs = append(t.functions[fqn].implements, implements)
-		sort.Strings(ifcs)
+		// A method that is promoted to several types may be found to
+		// implement the same interface more than once.
+		if !slices.Contains(ifcs, implements) {
+			//nolint:gocritic
+			ifcs = append(ifcs, implements)
+			sort.Strings(ifcs)
+		}
 		t.trace("method: %v implementing %v @ %v\n", fqn, implements, desc.Position)
 	} else {
 		t.trace("function: %v @ %v\n", fqn, desc.Position)
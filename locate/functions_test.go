@@ -9,6 +9,8 @@ import (
 	"go/ast"
 	"go/types"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"cloudeng.io/go/locate"
@@ -167,3 +169,68 @@ func TestFunctionDecls(t *testing.T) {
 			"cloudeng.io/go/locate/testdata/impl",
 		})
 }
+
+func TestFunctionsAndImplementations(t *testing.T) {
+	ctx := context.Background()
+	ifc1 := here + "data.Ifc1"
+	expected := map[string][]string{
+		"(*" + here + "impl.Impl1).M1":  {ifc1},
+		"(*" + here + "impl.Impl1).M2":  {ifc1},
+		"(*" + here + "impl.impl2).M3":  {here + "data.Ifc2"},
+		"(*" + here + "impl.Impl12).M1": {ifc1, here + "data.Ifc2", here + "data.Ifc3"},
+		"(*" + here + "impl.Other).M1":  nil,
+	}
+	// The functions and implementations are found concurrently so repeat
+	// to make it likely that both orderings are exercised.
+	for i := 0; i < 10; i++ {
+		locator := locate.New(locate.IncludeMethods(true))
+		locator.AddInterfaces(here + "data")
+		locator.AddFunctions(here + "impl")
+		locator.AddPackages(here + "impl")
+		if err := locator.Do(ctx); err != nil {
+			t.Fatalf("locator.Do: %v", err)
+		}
+		found := map[string][]string{}
+		locator.WalkFunctions(func(name string, _ *packages.Package, _ *ast.File, _ *types.Func, _ *ast.FuncDecl, implements []string) {
+			found[name] = implements
+		})
+		for name, ifcs := range expected {
+			if got, want := found[name], ifcs; !slices.Equal(got, want) {
+				t.Errorf("%v: got %v, want %v", name, got, want)
+			}
+		}
+	}
+}
+
+func TestFunctionSignatures(t *testing.T) {
+	ctx := context.Background()
+	locator := locate.New(locate.IncludeMethods(true))
+	locator.AddFunctions(here + "signatures;first=context.Context")
+	locator.AddFunctions("./testdata/signatures;results=error")
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locator.Do: %v", err)
+	}
+	compareLocations(t, listFunctions(locator), []string{
+		"(*" + here + "signatures.Store).Get",
+		here + "signatures.Close",
+		here + "signatures.Query",
+		here + "signatures.QueryContext",
+	}, []string{
+		filepath.Join("signatures", "signatures.go") + ":26:1",
+		filepath.Join("signatures", "signatures.go") + ":16:1",
+		filepath.Join("signatures", "signatures.go") + ":8:1",
+		filepath.Join("signatures", "signatures.go") + ":12:1",
+	})
+
+	locator = locate.New()
+	locator.AddFunctions(here + "signatures;returns=error")
+	if err := locator.Do(ctx); err == nil || !strings.Contains(err.Error(), "unsupported signature predicate") {
+		t.Errorf("missing or wrong error: %v", err)
+	}
+
+	locator = locate.New()
+	locator.AddInterfaces(here + "signatures;results=error")
+	if err := locator.Do(ctx); err == nil || !strings.Contains(err.Error(), "only supported for functions") {
+		t.Errorf("missing or wrong error: %v", err)
+	}
+}
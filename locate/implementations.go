@@ -6,15 +6,44 @@ package locate
 
 import (
 	"context"
-	"fmt"
+	"go/ast"
+	"go/token"
 	"go/types"
 	"regexp"
+	"sort"
 
 	"cloudeng.io/go/locate/locateutil"
 	"cloudeng.io/sync/errgroup"
+	"golang.org/x/tools/go/packages"
 )
 
+// Implementation represents a named type that implements a located
+// interface.
+type Implementation struct {
+	// Interface is the fully qualified name of the interface.
+	Interface string
+	// Type is the fully qualified name of the implementing type.
+	Type string
+	// Pointer is true if only a pointer to the type implements the
+	// interface.
+	Pointer bool
+	// Promoted lists the names of the interface methods that are provided
+	// by embedded fields rather than being declared on the type itself.
+	Promoted []string
+	Package  *packages.Package
+	File     *ast.File
+	Decl     *ast.TypeSpec
+	Position token.Position
+}
+
 func (t *T) findImplementations(ctx context.Context, packages []string) error {
+	t.mu.Lock()
+	nInterfaces := len(t.interfaces)
+	t.mu.Unlock()
+	if nInterfaces == 0 {
+		// Type information is not loaded when there are no interfaces.
+		return nil
+	}
 	group, ctx := errgroup.WithContext(ctx)
 	group = errgroup.WithConcurrency(group, t.options.concurrency)
 	for _, pkg := range packages {
@@ -28,32 +57,147 @@ func (t *T) findImplementations(ctx context.Context, packages []string) error {
 
 var allfuncs = regexp.MustCompile(".*")
 
+type locatedInterface struct {
+	name string
+	ifc  *types.Interface
+}
+
+func (t *T) locatedInterfaces() []locatedInterface {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ifcs := make([]locatedInterface, 0, len(t.interfaces))
+	for name, desc := range t.interfaces {
+		ifcs = append(ifcs, locatedInterface{name: name, ifc: desc.ifc})
+	}
+	sort.Slice(ifcs, func(i, j int) bool {
+		return ifcs[i].name < ifcs[j].name
+	})
+	return ifcs
+}
+
+// findImplementationInPackage checks every named type in the package, once
+// for T and once for *T, against all of the located interfaces. Methods
+// are matched by types.Implements and hence include unexported methods
+// and those promoted from embedded fields.
 func (t *T) findImplementationInPackage(_ context.Context, pkgPath string) error {
-	pkg := t.loader.lookupPackage(pkgPath)
+	pkg, err := t.lookupTypedPackage(pkgPath, "locating interface implementations")
 	if pkg == nil {
-		return fmt.Errorf("locating interface implementations: failed to lookup: %v", pkgPath)
+		return err
 	}
-	funcs := locateutil.Functions(pkg, allfuncs, false)
-	for _, fd := range funcs {
-		if !fd.Type.Exported() || fd.Decl == nil {
-			// Ignore non-exported functions and interface function definitions
-			// which do not have a declaration.
+	ifcs := t.locatedInterfaces()
+	methods := declaredMethods(pkg)
+	scope := pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() {
 			continue
 		}
-		sig := fd.Type.Type().(*types.Signature)
-		rcv := sig.Recv()
-		if rcv == nil || locateutil.IsAbstract(fd.Type) {
-			// Ignore functions and abstract methods.
+		named, ok := obj.Type().(*types.Named)
+		if !ok || types.IsInterface(named) {
 			continue
 		}
-		// This is concrete method, check it against all interfaces.
-		t.mu.Lock()
-		for ifcPath, ifcType := range t.interfaces {
-			if types.Implements(rcv.Type(), ifcType.ifc) {
-				t.addFunctionLocked(fd, pkgPath, ifcPath)
+		ptr := types.NewPointer(named)
+		for _, ifc := range ifcs {
+			var typ types.Type
+			switch {
+			case types.Implements(named, ifc.ifc):
+				typ = named
+			case types.Implements(ptr, ifc.ifc):
+				typ = ptr
+			default:
+				continue
 			}
+			t.addImplementation(pkg, obj, typ, ifc, methods)
 		}
-		t.mu.Unlock()
 	}
 	return nil
 }
+
+// declaredMethods returns the concrete methods declared in pkg indexed by
+// their types.Func.
+func declaredMethods(pkg *packages.Package) map[*types.Func]locateutil.FuncDesc {
+	methods := map[*types.Func]locateutil.FuncDesc{}
+	for _, fd := range locateutil.Functions(pkg, allfuncs, false) {
+		if fd.Decl == nil || fd.Type.Type().(*types.Signature).Recv() == nil {
+			continue
+		}
+		methods[fd.Type] = fd
+	}
+	return methods
+}
+
+func (t *T) addImplementation(pkg *packages.Package, obj *types.TypeName, typ types.Type, ifc locatedInterface, methods map[*types.Func]locateutil.FuncDesc) {
+	position := pkg.Fset.PositionFor(obj.Pos(), false)
+	if t.loader.isExcluded(position.Filename) {
+		return
+	}
+	file, _, _ := t.loader.lookupFile(position.Filename)
+	impl := Implementation{
+		Interface: ifc.name,
+		Type:      obj.Pkg().Path() + "." + obj.Name(),
+		Pointer:   typ != obj.Type(),
+		Package:   pkg,
+		File:      file,
+		Decl:      findTypeDecl(obj.Name(), file),
+		Position:  position,
+	}
+	mset := types.NewMethodSet(typ)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// Record every exported method declared on the type as implementing
+	// the interface, as well as the interface methods, exported or not,
+	// that are promoted from embedded fields declared in this package.
+	reported := map[*types.Func]bool{}
+	for i := 0; i < mset.Len(); i++ {
+		sel := mset.At(i)
+		fn := sel.Obj().(*types.Func)
+		fd, declared := methods[fn]
+		if len(sel.Index()) == 1 && declared && fn.Exported() {
+			t.addFunctionLocked(fd, pkg.PkgPath, ifc.name)
+			reported[fn] = true
+		}
+	}
+	for i := 0; i < ifc.ifc.NumMethods(); i++ {
+		m := ifc.ifc.Method(i)
+		sel := mset.Lookup(m.Pkg(), m.Name())
+		if sel == nil {
+			continue
+		}
+		fn := sel.Obj().(*types.Func)
+		if len(sel.Index()) > 1 {
+			impl.Promoted = append(impl.Promoted, m.Name())
+		}
+		if fd, declared := methods[fn]; declared && !reported[fn] {
+			t.addFunctionLocked(fd, pkg.PkgPath, ifc.name)
+			reported[fn] = true
+		}
+	}
+	sort.Strings(impl.Promoted)
+	t.implementations = append(t.implementations, impl)
+	t.dirty[position.Filename] |= HasImplementation
+	t.trace("implementation: %v implements %v @ %v\n", types.TypeString(typ, nil), ifc.name, position)
+}
+
+// WalkImplementations calls the supplied function for every named type
+// that implements a located interface, including those that do so only
+// via methods promoted from embedded fields or via unexported methods.
+// The function is called once per type and interface in order of filename,
+// position within filename and then interface name.
+func (t *T) WalkImplementations(fn func(impl Implementation)) {
+	t.mu.Lock()
+	impls := append([]Implementation{}, t.implementations...)
+	t.mu.Unlock()
+	sort.SliceStable(impls, func(i, j int) bool {
+		a, b := impls[i].Position, impls[j].Position
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		return impls[i].Interface < impls[j].Interface
+	})
+	for _, impl := range impls {
+		fn(impl)
+	}
+}
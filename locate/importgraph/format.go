@@ -0,0 +1,65 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package importgraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WriteText writes the graph as one line per import of the form
+// <importer> -> <imported>.
+func (g *Graph) WriteText(w io.Writer) error {
+	for _, e := range g.Edges {
+		if _, err := fmt.Fprintf(w, "%s -> %s\n", e.Importer, e.Imported); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteDOT writes the graph in graphviz's DOT format with internal
+// packages highlighted and the imports that are part of a cycle drawn
+// in red.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	cycle := map[string]int{}
+	for i, c := range g.Cycles {
+		for _, name := range c {
+			cycle[name] = i + 1
+		}
+	}
+	if _, err := fmt.Fprintln(w, "digraph imports {"); err != nil {
+		return err
+	}
+	for _, n := range g.Nodes {
+		attrs := ""
+		if n.Internal {
+			attrs = " [style=filled]"
+		}
+		if _, err := fmt.Fprintf(w, "\t%s%s;\n", strconv.Quote(n.Name), attrs); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges {
+		attrs := ""
+		if c := cycle[e.Importer]; c > 0 && c == cycle[e.Imported] {
+			attrs = " [color=red]"
+		}
+		if _, err := fmt.Fprintf(w, "\t%s -> %s%s;\n", strconv.Quote(e.Importer), strconv.Quote(e.Imported), attrs); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// WriteJSON writes the graph as JSON.
+func (g *Graph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
@@ -0,0 +1,170 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package importgraph provides support for building the import graph of
+// the packages loaded by locate.T, for detecting import cycles between
+// them and for enforcing declarative layering rules, such as
+// 'api/... must not import storage/...', specified in YAML.
+package importgraph
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+
+	"cloudeng.io/go/locate"
+	"golang.org/x/tools/go/packages"
+)
+
+// Node represents a package in the import graph. Internal is true for
+// packages that were loaded by locate.T and false for those that are
+// only imported by them.
+type Node struct {
+	Name     string
+	Internal bool
+}
+
+// Edge represents an import of one package by another, Positions lists
+// the positions of all of the import specs for that import.
+type Edge struct {
+	Importer  string
+	Imported  string
+	Positions []token.Position
+}
+
+// Graph represents an import graph.
+type Graph struct {
+	Nodes []Node
+	Edges []Edge
+	// Cycles lists the import cycles between internal packages, see
+	// Build.
+	Cycles [][]string `json:",omitempty"`
+}
+
+// Build builds the import graph for the packages loaded by locator, as
+// reported by locate.T.WalkImports. The cycles in the graph are found
+// as the strongly connected components of the graph of internal packages
+// that contain more than one package. Each cycle is sorted, as is the
+// list of cycles.
+func Build(locator *locate.T) *Graph {
+	internal := map[string]bool{}
+	locator.WalkPackages(func(pkg *packages.Package) {
+		internal[pkg.PkgPath] = true
+	})
+	nodes := map[string]bool{}
+	edges := map[[2]string][]token.Position{}
+	locator.WalkImports(func(pkg *packages.Package, _ *ast.File, _ *ast.ImportSpec, imported string, pos token.Position) {
+		nodes[pkg.PkgPath], nodes[imported] = true, true
+		key := [2]string{pkg.PkgPath, imported}
+		edges[key] = append(edges[key], pos)
+	})
+	for path := range internal {
+		nodes[path] = true
+	}
+	g := &Graph{}
+	for name := range nodes {
+		g.Nodes = append(g.Nodes, Node{Name: name, Internal: internal[name]})
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].Name < g.Nodes[j].Name
+	})
+	for key, positions := range edges {
+		g.Edges = append(g.Edges, Edge{Importer: key[0], Imported: key[1], Positions: positions})
+	}
+	sortEdges(g.Edges)
+	g.Cycles = g.cycles()
+	return g
+}
+
+func sortEdges(edges []Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Importer != edges[j].Importer {
+			return edges[i].Importer < edges[j].Importer
+		}
+		return edges[i].Imported < edges[j].Imported
+	})
+}
+
+// Internal returns the subgraph of g that contains only internal
+// packages.
+func (g *Graph) Internal() *Graph {
+	internal := map[string]bool{}
+	sub := &Graph{Cycles: g.Cycles}
+	for _, n := range g.Nodes {
+		if n.Internal {
+			internal[n.Name] = true
+			sub.Nodes = append(sub.Nodes, n)
+		}
+	}
+	for _, e := range g.Edges {
+		if internal[e.Importer] && internal[e.Imported] {
+			sub.Edges = append(sub.Edges, e)
+		}
+	}
+	return sub
+}
+
+// cycles uses Tarjan's algorithm to find the strongly connected
+// components of the internal packages.
+func (g *Graph) cycles() [][]string {
+	internal := map[string]bool{}
+	for _, n := range g.Nodes {
+		internal[n.Name] = n.Internal
+	}
+	adjacent := map[string][]string{}
+	for _, e := range g.Edges {
+		if internal[e.Importer] && internal[e.Imported] {
+			adjacent[e.Importer] = append(adjacent[e.Importer], e.Imported)
+		}
+	}
+	var (
+		index   = map[string]int{}
+		lowlink = map[string]int{}
+		onStack = map[string]bool{}
+		stack   []string
+		next    int
+		cycles  [][]string
+	)
+	var connect func(string)
+	connect = func(v string) {
+		index[v], lowlink[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range adjacent[v] {
+			if _, visited := index[w]; !visited {
+				connect(w)
+				lowlink[v] = min(lowlink[v], lowlink[w])
+			} else if onStack[w] {
+				lowlink[v] = min(lowlink[v], index[w])
+			}
+		}
+		if lowlink[v] != index[v] {
+			return
+		}
+		var scc []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			scc = append(scc, w)
+			if w == v {
+				break
+			}
+		}
+		if len(scc) > 1 {
+			sort.Strings(scc)
+			cycles = append(cycles, scc)
+		}
+	}
+	for _, n := range g.Nodes {
+		if _, visited := index[n.Name]; n.Internal && !visited {
+			connect(n.Name)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return cycles
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package importgraph_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloudeng.io/go/locate"
+	"cloudeng.io/go/locate/importgraph"
+)
+
+const here = "cloudeng.io/go/locate/importgraph/testdata/"
+
+func build(t *testing.T, pkgs ...string) *importgraph.Graph {
+	locator := locate.New(locate.TolerateErrors())
+	locator.AddPackages(pkgs...)
+	if err := locator.Do(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return importgraph.Build(locator)
+}
+
+func TestGraph(t *testing.T) {
+	g := build(t, "./testdata/api", "./testdata/service", "./testdata/storage/...")
+	out := &bytes.Buffer{}
+	if err := g.Internal().WriteText(out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.ReplaceAll(out.String(), here, ""), `api -> service
+api -> storage
+api -> storage/types
+service -> storage
+storage -> storage/types
+`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	var external []string
+	for _, n := range g.Nodes {
+		if !n.Internal {
+			external = append(external, n.Name)
+		}
+	}
+	if got, want := external, []string{"fmt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(g.Cycles), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	rules, err := importgraph.ReadRules(filepath.Join("testdata", "rules.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var violations []string
+	for _, v := range rules.Check(g) {
+		violations = append(violations, fmt.Sprintf("%v: %v -> %v @ %v:%v", v.Rule,
+			strings.TrimPrefix(v.Importer, here), strings.TrimPrefix(v.Imported, here),
+			filepath.Base(v.Position.Filename), v.Position.Line))
+	}
+	if got, want := violations, []string{
+		"the api only uses storage via service: api -> storage @ api.go:5",
+		"the api only uses storage via service: api -> storage @ handlers.go:3",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCycles(t *testing.T) {
+	g := build(t, "./testdata/cycle/...", "./testdata/storage")
+	if got, want := g.Cycles, [][]string{{here + "cycle/a", here + "cycle/b"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	out := &bytes.Buffer{}
+	if err := g.Internal().WriteDOT(out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Count(out.String(), "[color=red]"), 2; got != want {
+		t.Errorf("got %v, want %v\n%s", got, want, out.String())
+	}
+}
+
+func TestRules(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, path string
+		match         bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/b", "a/b/c", false},
+		{"a/b/...", "a/b", true},
+		{"a/b/...", "a/b/c", true},
+		{"a/b/...", "a/bc", false},
+	} {
+		if got, want := importgraph.Match(tc.pattern, tc.path), tc.match; got != want {
+			t.Errorf("%v %v: got %v, want %v", tc.pattern, tc.path, got, want)
+		}
+	}
+	for _, invalid := range []string{
+		"rules:\n  - packages: [a]\n",
+		"rules:\n  - mustNotImport: [a]\n",
+		"unknown: field\n",
+	} {
+		if _, err := importgraph.ParseRules([]byte(invalid)); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+	rule := importgraph.Rule{Packages: []string{"a/..."}, MustNotImport: []string{"b"}}
+	if got, want := rule.String(), "a/... must not import b"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package importgraph
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Rules represents a set of layering rules, typically read from a YAML
+// file such as:
+//
+//	module: example.com/app
+//	rules:
+//	  - name: the api does not depend on storage
+//	    packages: [api/...]
+//	    mustNotImport: [storage/...]
+//	    except: [storage/types]
+//
+// Package patterns are import paths with a trailing /... matching the
+// package and all of the packages beneath it. If Module is set then all
+// patterns are relative to it.
+type Rules struct {
+	Module string `yaml:"module"`
+	Rules  []Rule `yaml:"rules"`
+}
+
+// Rule represents a single layering rule, ie. that none of the packages
+// matched by Packages may import any of the packages matched by
+// MustNotImport, other than those matched by Except.
+type Rule struct {
+	Name          string   `yaml:"name"`
+	Packages      []string `yaml:"packages"`
+	MustNotImport []string `yaml:"mustNotImport"`
+	Except        []string `yaml:"except"`
+}
+
+// String returns a description of the rule, its name if it has one or of
+// the form '<packages> must not import <packages>' otherwise.
+func (r Rule) String() string {
+	if len(r.Name) > 0 {
+		return r.Name
+	}
+	return strings.Join(r.Packages, ",") + " must not import " + strings.Join(r.MustNotImport, ",")
+}
+
+// Violation represents an import that violates a rule.
+type Violation struct {
+	Rule     string
+	Importer string
+	Imported string
+	Position token.Position
+}
+
+// ParseRules parses YAML formatted rules.
+func ParseRules(data []byte) (*Rules, error) {
+	rules := &Rules{}
+	if err := yaml.UnmarshalStrict(data, rules); err != nil {
+		return nil, err
+	}
+	for i, r := range rules.Rules {
+		if len(r.Packages) == 0 || len(r.MustNotImport) == 0 {
+			return nil, fmt.Errorf("rule %v: %q: both packages and mustNotImport must be specified", i, r)
+		}
+	}
+	return rules, nil
+}
+
+// ReadRules reads YAML formatted rules from the specified file.
+func ReadRules(filename string) (*Rules, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", filename, err)
+	}
+	return rules, nil
+}
+
+// Check returns the imports in g that violate any of the rules, one for
+// each import spec, in order of importer, imported package and then
+// position.
+func (r *Rules) Check(g *Graph) []Violation {
+	var violations []Violation
+	for _, e := range g.Edges {
+		for _, rule := range r.Rules {
+			if !r.matchesAny(rule.Packages, e.Importer) ||
+				!r.matchesAny(rule.MustNotImport, e.Imported) ||
+				r.matchesAny(rule.Except, e.Imported) {
+				continue
+			}
+			for _, pos := range e.Positions {
+				violations = append(violations, Violation{
+					Rule:     rule.String(),
+					Importer: e.Importer,
+					Imported: e.Imported,
+					Position: pos,
+				})
+			}
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if a.Importer != b.Importer {
+			return a.Importer < b.Importer
+		}
+		if a.Imported != b.Imported {
+			return a.Imported < b.Imported
+		}
+		if a.Position.Filename != b.Position.Filename {
+			return a.Position.Filename < b.Position.Filename
+		}
+		return a.Position.Offset < b.Position.Offset
+	})
+	return violations
+}
+
+func (r *Rules) matchesAny(patterns []string, path string) bool {
+	for _, p := range patterns {
+		if len(r.Module) > 0 {
+			p = strings.TrimSuffix(r.Module, "/") + "/" + strings.TrimPrefix(p, "/")
+		}
+		if Match(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns true if path is matched by pattern, ie. if they are
+// identical or pattern ends in /... and path is, or is beneath, the
+// package that precedes the /....
+func Match(pattern, path string) bool {
+	prefix, ok := strings.CutSuffix(pattern, "/...")
+	if !ok {
+		return pattern == path
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
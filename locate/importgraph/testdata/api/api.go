@@ -0,0 +1,11 @@
+package api
+
+import (
+	"cloudeng.io/go/locate/importgraph/testdata/service"
+	"cloudeng.io/go/locate/importgraph/testdata/storage"
+	"cloudeng.io/go/locate/importgraph/testdata/storage/types"
+)
+
+func Get() (*storage.Store, types.Record) {
+	return service.New(), types.Record{}
+}
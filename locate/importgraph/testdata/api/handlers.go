@@ -0,0 +1,5 @@
+package api
+
+import "cloudeng.io/go/locate/importgraph/testdata/storage"
+
+var _ storage.Store
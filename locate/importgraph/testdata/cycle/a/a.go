@@ -0,0 +1,5 @@
+package a
+
+import "cloudeng.io/go/locate/importgraph/testdata/cycle/b"
+
+var A = b.B
@@ -0,0 +1,5 @@
+package b
+
+import "cloudeng.io/go/locate/importgraph/testdata/cycle/a"
+
+var B = a.A
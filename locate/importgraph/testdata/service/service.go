@@ -0,0 +1,12 @@
+package service
+
+import (
+	"fmt"
+
+	"cloudeng.io/go/locate/importgraph/testdata/storage"
+)
+
+func New() *storage.Store {
+	fmt.Println("new")
+	return &storage.Store{}
+}
@@ -0,0 +1,7 @@
+package storage
+
+import "cloudeng.io/go/locate/importgraph/testdata/storage/types"
+
+type Store struct {
+	Record types.Record
+}
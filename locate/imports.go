@@ -0,0 +1,37 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// WalkImports calls the supplied function for each import declaration in
+// the files of the loaded packages, including those that were loaded only
+// via AddPackages but excluding files excluded via ExcludeFiles or
+// ExcludeGenerated. The function is called with the importing package, the
+// file and import spec, the path of the imported package and the position
+// of the import spec. The function is called in order of filename and then
+// position within filename.
+func (t *T) WalkImports(fn func(
+	pkg *packages.Package,
+	file *ast.File,
+	spec *ast.ImportSpec,
+	imported string,
+	position token.Position)) {
+	t.loader.walkFiles(func(_ string, pkg *packages.Package, _ ast.CommentMap, file *ast.File) {
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			fn(pkg, file, spec, path, pkg.Fset.PositionFor(spec.Pos(), false))
+		}
+	})
+}
@@ -60,15 +60,15 @@ func (t *T) findEmbeddedInterfaces(_ context.Context, pkgPath string, ifcType *t
 }
 
 func (t *T) findInterfacesInPackage(ctx context.Context, pkgPath string, ifcRE *regexp.Regexp) error {
-	pkg := t.loader.lookupPackage(pkgPath)
+	pkg, err := t.lookupTypedPackage(pkgPath, "locating interfaces")
 	if pkg == nil {
-		return fmt.Errorf("locating interfaces: failed to lookup: %v", pkgPath)
+		return err
 	}
 	found := 0
 	checked := pkg.TypesInfo
 	// Look in info.Defs for defined interfaces.
 	for k, obj := range checked.Defs {
-		if obj == nil || !k.IsExported() || !ifcRE.MatchString(k.Name) {
+		if obj == nil || !k.IsExported() || !ifcRE.MatchString(k.Name) || t.excludedName(HasInterface, pkgPath, k.Name) {
 			continue
 		}
 		if _, ok := obj.(*types.TypeName); !ok {
@@ -84,7 +84,7 @@ func (t *T) findInterfacesInPackage(ctx context.Context, pkgPath string, ifcRE *
 		}
 		if len(embedded) > 0 {
 			for ek, eobj := range checked.Defs {
-				if embedded[ek.Name] {
+				if embedded[ek.Name] && !t.excludedName(HasInterface, pkgPath, ek.Name) {
 					ifcType := locateutil.IsInterfaceDefinition(pkg, eobj)
 					if ifcType == nil {
 						continue
@@ -103,16 +103,20 @@ func (t *T) findInterfacesInPackage(ctx context.Context, pkgPath string, ifcRE *
 }
 
 func (t *T) addInterface(path, name string, pos token.Pos, ifcType *types.Interface) {
+	position := t.loader.position(path, pos)
+	if t.loader.isExcluded(position.Filename) {
+		return
+	}
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	position := t.loader.position(path, pos)
 	fqn := path + "." + name
 	filename := position.Filename
-	ast, _, _ := t.loader.lookupFile(filename)
+	ast, _, pkg := t.loader.lookupFile(filename)
 	t.interfaces[fqn] = interfaceDesc{
 		path:     path,
+		module:   modulePathFor(pkg),
 		ifc:      ifcType,
-		decl:     findInterfaceDecl(name, ast),
+		decl:     findTypeDecl(name, ast),
 		position: position,
 	}
 	if t.interfaces[fqn].decl == nil {
@@ -123,7 +127,7 @@ func (t *T) addInterface(path, name string, pos token.Pos, ifcType *types.Interf
 	t.trace("interface: %v @ %v\n", fqn, position)
 }
 
-func findInterfaceDecl(name string, file *ast.File) *ast.TypeSpec {
+func findTypeDecl(name string, file *ast.File) *ast.TypeSpec {
 	for _, d := range file.Decls {
 		d, ok := d.(*ast.GenDecl)
 		if !ok || d.Tok != token.TYPE {
@@ -144,6 +148,7 @@ func findInterfaceDecl(name string, file *ast.File) *ast.TypeSpec {
 
 type interfaceDesc struct {
 	path     string
+	module   string
 	ifc      *types.Interface
 	decl     *ast.TypeSpec
 	position token.Position
@@ -6,6 +6,7 @@ package locate_test
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -121,3 +122,47 @@ func TestFindImplementations(t *testing.T) {
 		filepath.Join("impl", "impls.go:") + "15:1",
 	})
 }
+
+func TestWalkImplementations(t *testing.T) {
+	ctx := context.Background()
+	locator := locate.New()
+	locator.AddInterfaces(here+"data", here+"embedded.Sealed")
+	locator.AddPackages(here + "embedded")
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locator.Do: %v", err)
+	}
+	found := []string{}
+	locator.WalkImplementations(func(impl locate.Implementation) {
+		typ := strings.TrimPrefix(impl.Type, here)
+		if impl.Pointer {
+			typ = "*" + typ
+		}
+		found = append(found, fmt.Sprintf("%v implements %v promoted %v @ %v:%v",
+			typ, strings.TrimPrefix(impl.Interface, here), impl.Promoted,
+			filepath.Base(impl.Position.Filename), impl.Position.Line))
+	})
+	compareSlices(t, found, []string{
+		"*embedded.Base implements data.Ifc1 promoted [] @ embedded.go:3",
+		"*embedded.Embedder implements data.Ifc1 promoted [M1 M2] @ embedded.go:11",
+		"embedded.Value implements data.Ifc1 promoted [M1 M2] @ embedded.go:16",
+		"*embedded.Mixed implements data.Ifc1 promoted [M1 M2] @ embedded.go:20",
+		"*embedded.Mixed implements data.Ifc2 promoted [] @ embedded.go:20",
+		"*embedded.Mixed implements data.Ifc3 promoted [M1 M2] @ embedded.go:20",
+		"embedded.Impl implements embedded.Sealed promoted [] @ embedded.go:34",
+	})
+
+	// WalkFunctions reports the methods that are promoted from embedded
+	// fields against the types that declare them.
+	ifc13 := implements("Ifc1", "Ifc3")
+	compareLocations(t, listFunctions(locator), []string{
+		"(*" + here + "embedded.Base).M2 implements " + ifc13,
+		"(*" + here + "embedded.Mixed).M3 implements " + implements("Ifc1", "Ifc2", "Ifc3"),
+		"(" + here + "embedded.Base).M1 implements " + ifc13,
+		"(" + here + "embedded.Impl).Name implements " + here + "embedded.Sealed",
+	}, []string{
+		filepath.Join("embedded", "embedded.go") + ":7:1",
+		filepath.Join("embedded", "embedded.go") + ":24:1",
+		filepath.Join("embedded", "embedded.go") + ":5:1",
+		filepath.Join("embedded", "embedded.go") + ":38:1",
+	})
+}
@@ -16,6 +16,7 @@ import (
 )
 
 type fileDesc struct {
+	excluded bool
 	name     string
 	ast      *ast.File
 	pkg      *packages.Package
@@ -28,25 +29,45 @@ type loader struct {
 	packages map[string]*packages.Package
 	// Indexed by absolute filename.
 	files map[string]fileDesc
-	trace traceFunc
+	// Indexed by package path, records the errors for packages that
+	// failed to load or type check.
+	diagnostics map[string][]packages.Error
+	// Indexed by package path, the packages that are loaded only to
+	// provide the type information required to process the packages
+	// in the current batch, see T.DoBatches. They are not reported by
+	// walkFiles or walkPackages.
+	context map[string]bool
+	trace   traceFunc
+	// exclude returns true for files that are to be excluded from
+	// walkFiles.
+	exclude func(filename string, file *ast.File) bool
 }
 
 func newLoader(trace traceFunc) *loader {
 	return &loader{
-		packages: make(map[string]*packages.Package),
-		files:    make(map[string]fileDesc),
-		trace:    trace,
+		packages:    make(map[string]*packages.Package),
+		files:       make(map[string]fileDesc),
+		diagnostics: make(map[string][]packages.Error),
+		trace:       trace,
 	}
 }
 
-func (ld *loader) loadPaths(paths []string, includeTests bool) error {
-	cfg := &packages.Config{
-		Mode: packages.NeedName | packages.NeedSyntax | packages.NeedTypes |
-			packages.NeedFiles | packages.NeedTypesInfo | packages.NeedCompiledGoFiles,
-		Tests:      includeTests,
-		BuildFlags: nil, // TODO: provide an option for buildflags.
-	}
+const (
+	// syntaxLoadMode is sufficient for locating comments and walking
+	// files and packages.
+	syntaxLoadMode = packages.NeedName | packages.NeedSyntax |
+		packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedModule
+	// typesLoadMode is required for locating interfaces, functions
+	// and implementations.
+	typesLoadMode = syntaxLoadMode | packages.NeedTypes | packages.NeedTypesInfo
+)
 
+// loadPaths loads the specified packages using the supplied config. If
+// tolerateErrors is set then packages that fail to load or type check are
+// recorded as diagnostics rather than returned as errors; those that could
+// be parsed are retained so that their syntax trees and comments are still
+// available.
+func (ld *loader) loadPaths(cfg *packages.Config, paths []string, tolerateErrors bool) error {
 	if len(paths) == 0 {
 		return nil
 	}
@@ -54,39 +75,72 @@ func (ld *loader) loadPaths(paths []string, includeTests bool) error {
 	if err != nil {
 		return err
 	}
+	return ld.addPackages(pkgs, tolerateErrors)
+}
 
+// addPackages records the supplied, already loaded, packages and their
+// files, see loadPaths for the handling of packages with errors.
+func (ld *loader) addPackages(pkgs []*packages.Package, tolerateErrors bool) error {
 	errs := &errors.M{}
+	diagnostics := map[string][]packages.Error{}
 	for _, pkg := range pkgs {
 		if len(pkg.Name) == 0 {
 			errs.Append(fmt.Errorf("failed to find: %v", pkg))
+			diagnostics[pkg.PkgPath] = packageErrors(pkg, "failed to find package")
 			continue
 		}
 		if pkg.IllTyped {
 			errs.Append(fmt.Errorf("failed to type check: %v", pkg))
+			diagnostics[pkg.PkgPath] = packageErrors(pkg, "failed to type check")
 		}
 	}
-	if err := errs.Err(); err != nil {
+	if err := errs.Err(); err != nil && !tolerateErrors {
 		return err
 	}
 	ld.Lock()
 	defer ld.Unlock()
+	for path, errs := range diagnostics {
+		ld.diagnostics[path] = errs
+		ld.trace("load: diagnostics: %v: %v\n", path, errs)
+	}
 	for _, pkg := range pkgs {
+		if len(pkg.Name) == 0 {
+			continue
+		}
 		ld.packages[pkg.PkgPath] = pkg
-		for i, filename := range pkg.CompiledGoFiles {
-			file := pkg.Syntax[i]
+		for i, file := range pkg.Syntax {
+			var filename string
+			if len(pkg.Syntax) == len(pkg.CompiledGoFiles) {
+				filename = pkg.CompiledGoFiles[i]
+			} else if tf := pkg.Fset.File(file.Pos()); tf != nil {
+				// Files that failed to parse may be missing from Syntax.
+				filename = tf.Name()
+			} else {
+				continue
+			}
 			ld.files[filename] = fileDesc{
+				excluded: ld.exclude != nil && ld.exclude(filename, file),
 				name:     filename,
 				ast:      file,
 				pkg:      pkg,
 				comments: ast.NewCommentMap(pkg.Fset, file, file.Comments),
 			}
-			ld.trace("load: file: %v\n", filename)
+			ld.trace("load: file: %v, excluded: %v\n", filename, ld.files[filename].excluded)
 		}
 		ld.trace("load: package: %v\n", pkg.PkgPath)
 	}
 	return nil
 }
 
+// packageErrors returns the errors recorded for pkg, or a single error
+// with the supplied message if there are none.
+func packageErrors(pkg *packages.Package, msg string) []packages.Error {
+	if len(pkg.Errors) > 0 {
+		return pkg.Errors
+	}
+	return []packages.Error{{Pos: "-", Msg: msg}}
+}
+
 func (ld *loader) lookupPackage(path string) *packages.Package {
 	ld.Lock()
 	defer ld.Unlock()
@@ -97,6 +151,34 @@ func (ld *loader) lookupPackage(path string) *packages.Package {
 	return nil
 }
 
+func (ld *loader) hasDiagnostics(path string) bool {
+	ld.Lock()
+	defer ld.Unlock()
+	return len(ld.diagnostics[path]) > 0
+}
+
+func (ld *loader) walkDiagnostics(fn func(path string, errs []packages.Error)) {
+	ld.Lock()
+	paths := make([]string, 0, len(ld.diagnostics))
+	for path := range ld.diagnostics {
+		paths = append(paths, path)
+	}
+	ld.Unlock()
+	sort.Strings(paths)
+	for _, path := range paths {
+		ld.Lock()
+		errs := ld.diagnostics[path]
+		ld.Unlock()
+		fn(path, errs)
+	}
+}
+
+func (ld *loader) isExcluded(filename string) bool {
+	ld.Lock()
+	defer ld.Unlock()
+	return ld.files[filename].excluded
+}
+
 func (ld *loader) lookupFile(filename string) (*ast.File, ast.CommentMap, *packages.Package) {
 	ld.Lock()
 	defer ld.Unlock()
@@ -120,11 +202,15 @@ func (ld *loader) walkFiles(fn func(filename string, pkg *packages.Package, cmap
 		files[i] = v
 		i++
 	}
+	context := ld.context
 	ld.Unlock()
 	sort.Slice(files, func(i, j int) bool {
 		return files[i].name < files[j].name
 	})
 	for _, file := range files {
+		if file.excluded || context[file.pkg.PkgPath] {
+			continue
+		}
 		fn(file.name, file.pkg, file.comments, file.ast)
 	}
 }
@@ -137,11 +223,36 @@ func (ld *loader) walkPackages(fn func(pkg *packages.Package)) {
 		pkgs[i] = v
 		i++
 	}
+	context := ld.context
 	ld.Unlock()
 	sort.Slice(pkgs, func(i, j int) bool {
 		return pkgs[i].PkgPath < pkgs[j].PkgPath
 	})
 	for _, pkg := range pkgs {
+		if context[pkg.PkgPath] {
+			continue
+		}
 		fn(pkg)
 	}
 }
+
+// setContext records the packages that are to be loaded only to provide
+// type information for the current batch.
+func (ld *loader) setContext(paths []string) {
+	ld.Lock()
+	defer ld.Unlock()
+	ld.context = map[string]bool{}
+	for _, path := range paths {
+		ld.context[path] = true
+	}
+}
+
+// release discards all of the loaded packages and files, but not the
+// diagnostics, so that they may be garbage collected.
+func (ld *loader) release() {
+	ld.Lock()
+	defer ld.Unlock()
+	ld.packages = make(map[string]*packages.Package)
+	ld.files = make(map[string]fileDesc)
+	ld.context = nil
+}
@@ -3,8 +3,9 @@
 // license that can be found in the LICENSE file.
 
 // Package locate provides a means for obtaining the location of comments,
-// functions and implementations of interfaces in go source code,
-// with a view to annotating that source code programmatically.
+// functions, implementations of interfaces and other declarations such as
+// types, struct fields, constants and variables in go source code, with a
+// view to annotating that source code programmatically.
 package locate
 
 import (
@@ -13,6 +14,8 @@ import (
 	"go/ast"
 	"go/token"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
@@ -32,6 +35,21 @@ type T struct {
 	functionPackages       []string
 	implementationPackages []string
 	commentExpressions     []string
+	callerPackages         []string
+	deprecatedPackages     []string
+	declarationSpecs       declSpecs
+	packages               []string
+	cache                  *cacheState
+	// The environment for go commands, nil to inherit the current one.
+	env     []string
+	modules []Module
+	// Locations obtained from the cache.
+	cached []Location
+	// Locations retained from the batches processed by DoBatches.
+	released []Location
+	// Negative <package>.<regex> specs, indexed by HasInterface,
+	// HasFunction, HasType etc.
+	exclusions map[HitMask][]nameExclusion
 
 	mu sync.Mutex
 
@@ -42,6 +60,18 @@ type T struct {
 	functions map[string]funcDesc
 	// GUARDED_BY(mu), indexed by the regular expression that matched them.
 	comments map[string][]commentDesc
+	// GUARDED_BY(mu), indexed by HasType, HasField, HasConst or HasVar
+	// and then by <package-path>.<name>, or <package-path>.<type>.<field>
+	// for fields.
+	declarations map[HitMask]map[string]declDesc
+	// GUARDED_BY(mu)
+	references []Reference
+	// GUARDED_BY(mu)
+	implementations []Implementation
+	// GUARDED_BY(mu)
+	directives []Directive
+	// GUARDED_BY(mu)
+	deprecatedUses []DeprecatedUse
 	// GUARDED_BY(mu), indexed by filename.
 	dirty map[string]HitMask
 }
@@ -56,6 +86,25 @@ const (
 	HasFunction
 	// HasInterface is set if the current file contains an interface.
 	HasInterface
+	// HasReference is set if the current file contains a reference to
+	// a located function or interface method.
+	HasReference
+	// HasType is set if the current file contains a type.
+	HasType
+	// HasField is set if the current file contains a struct field.
+	HasField
+	// HasConst is set if the current file contains a constant.
+	HasConst
+	// HasVar is set if the current file contains a variable.
+	HasVar
+	// HasImplementation is set if the current file contains a type that
+	// implements a located interface.
+	HasImplementation
+	// HasDirective is set if the current file contains a directive.
+	HasDirective
+	// HasDeprecatedUse is set if the current file contains a use of a
+	// deprecated object.
+	HasDeprecatedUse
 	hitSentinel
 )
 
@@ -63,6 +112,14 @@ var hitNames = []string{
 	"comment",
 	"function",
 	"interface",
+	"reference",
+	"type",
+	"field",
+	"const",
+	"var",
+	"implementation",
+	"directive",
+	"deprecated",
 }
 
 func (hm HitMask) String() string {
@@ -86,6 +143,20 @@ type options struct {
 	tests                     bool
 	ignoreMissingFunctionsEtc bool
 	includeMethods            bool
+	tolerateErrors            bool
+	requireTypes              bool
+	cacheDir                  string
+	buildFlags                []string
+	overlay                   map[string][]byte
+	dir                       string
+	allModules                bool
+	loadDependencies          bool
+	typeKinds                 TypeKind
+	tagFilters                []TagFilter
+	excludeFiles              []*regexp.Regexp
+	excludeGenerated          bool
+	directives                bool
+	snapshot                  *Snapshot
 	trace                     func(string, ...interface{})
 }
 
@@ -130,21 +201,168 @@ func IncludeMethods(val bool) Option {
 	}
 }
 
+// TolerateErrors allows packages that fail to load or type check to be
+// reported as diagnostics, via WalkDiagnostics, rather than causing Do to
+// fail. Packages that can be parsed are retained so that syntax-only
+// features, such as comments and WalkFiles, continue to work but they are
+// excluded from type-dependent features such as locating interfaces,
+// functions and implementations. 'go list' is run with -e so that packages
+// with errors, such as import cycles, are listed rather than causing
+// the expansion of go list expressions to fail.
+func TolerateErrors() Option {
+	return func(o *options) {
+		o.tolerateErrors = true
+	}
+}
+
+// RequireTypes forces type information to be loaded for all packages.
+// By default, type information is only loaded when interfaces or functions
+// are to be located since comments, files and packages only require the
+// syntax of each package and type checking is expensive. Callers that
+// access packages.Package.Types or TypesInfo directly, via WalkPackages or
+// WalkFiles for example, should specify this option.
+func RequireTypes() Option {
+	return func(o *options) {
+		o.requireTypes = true
+	}
+}
+
+// BuildFlags specifies the build flags, eg. -tags, to use when loading
+// packages.
+func BuildFlags(flags ...string) Option {
+	return func(o *options) {
+		o.buildFlags = append(o.buildFlags, flags...)
+	}
+}
+
+// Overlay specifies the contents of files that are to be used in place of
+// their on-disk contents, typically for editor buffers with unsaved changes.
+// The keys are absolute filenames. It is passed through to
+// packages.Config.Overlay and to go list, via -overlay, when expanding
+// package patterns so that packages that exist only in the overlay are
+// found.
+func Overlay(overlay map[string][]byte) Option {
+	return func(o *options) {
+		o.overlay = overlay
+	}
+}
+
+// LoadDependencies loads the syntax and type information for all of the
+// dependencies of the requested packages, as well as the packages
+// themselves, as is required for whole program analysis such as
+// building a call graph. The dependencies are reachable via the Imports
+// field of the packages returned by WalkPackages. The cache, if any, is
+// not used when dependencies are loaded.
+func LoadDependencies() Option {
+	return func(o *options) {
+		o.loadDependencies = true
+	}
+}
+
+// TypeKinds restricts the types located via AddTypes to those of the
+// specified kinds, eg. StructType|AliasType. All types are located by
+// default.
+func TypeKinds(kinds TypeKind) Option {
+	return func(o *options) {
+		o.typeKinds = kinds
+	}
+}
+
+// FieldTags restricts the fields located via AddFields to those whose
+// tags match all of the supplied filters.
+func FieldTags(filters ...TagFilter) Option {
+	return func(o *options) {
+		o.tagFilters = append(o.tagFilters, filters...)
+	}
+}
+
+// ExcludeFiles excludes files whose absolute filename matches any of the
+// supplied regular expressions, eg. /testdata/ or /vendor/. Excluded files
+// are still loaded, since they are required for type checking, but are
+// not searched for comments, functions, implementations etc. and are not
+// returned by WalkFiles.
+func ExcludeFiles(res ...*regexp.Regexp) Option {
+	return func(o *options) {
+		o.excludeFiles = append(o.excludeFiles, res...)
+	}
+}
+
+// ExcludeGenerated excludes generated files, ie. those that contain the
+// standard "Code generated ... DO NOT EDIT." comment (see ast.IsGenerated),
+// in the same manner as ExcludeFiles.
+func ExcludeGenerated(val bool) Option {
+	return func(o *options) {
+		o.excludeGenerated = val
+	}
+}
+
+// Dir specifies the directory in which go list is run and packages are
+// loaded, the current directory is used by default.
+func Dir(dir string) Option {
+	return func(o *options) {
+		o.dir = dir
+	}
+}
+
+// AllModules allows for packages from all of the modules under the
+// directory specified by Dir to be located in a single run. If that
+// directory is part of a go.work workspace then the workspace's modules
+// are used, otherwise every module found under the directory (see
+// FindModules) is used via a temporary go.work file. Relative go list
+// expressions, eg. ./..., are expanded within each module that they
+// refer to.
+func AllModules(val bool) Option {
+	return func(o *options) {
+		o.allModules = val
+	}
+}
+
+// CacheDir specifies a directory in which to cache the locations found in
+// each package. A package's cache entry is keyed by the contents of its
+// files and those of its dependencies, the go version, build flags and the
+// requested interfaces, functions and comments. Packages whose entry is
+// found in the cache are not loaded and their locations are only available
+// via WalkLocations, all other Walk methods, eg. WalkFunctions, WalkFiles
+// etc, are limited to the packages that were loaded. Packages that fail to
+// load or type check are never cached.
+func CacheDir(dir string) Option {
+	return func(o *options) {
+		o.cacheDir = dir
+	}
+}
+
 // New returns a new instance of T.
 func New(options ...Option) *T {
 	t := &T{
-		interfaces: make(map[string]interfaceDesc),
-		functions:  make(map[string]funcDesc),
-		dirty:      make(map[string]HitMask),
-		comments:   make(map[string][]commentDesc),
+		declarationSpecs: declSpecs{},
 	}
+	t.resetLocked()
 	t.loader = newLoader(t.trace)
 	for _, fn := range options {
 		fn(&t.options)
 	}
+	t.loader.exclude = t.excludedFile
 	return t
 }
 
+// resetLocked discards all of the results found so far.
+func (t *T) resetLocked() {
+	t.interfaces = make(map[string]interfaceDesc)
+	t.functions = make(map[string]funcDesc)
+	t.dirty = make(map[string]HitMask)
+	t.comments = make(map[string][]commentDesc)
+	t.declarations = map[HitMask]map[string]declDesc{
+		HasType:  {},
+		HasField: {},
+		HasConst: {},
+		HasVar:   {},
+	}
+	t.references = nil
+	t.implementations = nil
+	t.directives = nil
+	t.deprecatedUses = nil
+}
+
 func (t *T) trace(format string, args ...interface{}) {
 	if t.options.trace == nil {
 		return
@@ -170,17 +388,68 @@ func (t *T) trace(format string, args ...interface{}) {
 //	acme.com/a/b.thisInterface$
 //
 // Note that the two forms 'go list' and <package>.<regex> cannot be combined.
+//
+// Specs that start with - or ! are negative and exclude the interfaces they
+// match. A negative go list expression, eg. -./internal/..., excludes all of
+// the packages it matches and is applied after the positive go list
+// expressions are expanded. A negative <package>.<regex> spec, eg.
+// !acme.com/a/b.Legacy.*, excludes the matching interfaces in that package.
+// Negative specs are supported by all of the Add methods, with AddPackages
+// and AddCallers accepting only negative go list expressions and package
+// paths.
 func (t *T) AddInterfaces(interfaces ...string) {
 	t.interfacePackages = append(t.interfacePackages, interfaces...)
 }
 
 // AddFunctions adds functions to be located. The function names are specified
 // as fully qualified names with a regular expression being accepted for the
-// package local component as per AddInterfaces.
+// package local component as per AddInterfaces. A spec, including a go list
+// expression, may be followed by a ; and a ; separated list of predicates
+// on the signature of the functions to be located as accepted by
+// locateutil.ParseSignature. For example:
+//
+//	acme.com/a/b;param=*database/sql.DB
+//	acme.com/a/b.^Close;results=error
+//	./...;first=context.Context;variadic
+//
+// Note that predicates on receivers require IncludeMethods.
 func (t *T) AddFunctions(functions ...string) {
 	t.functionPackages = append(t.functionPackages, functions...)
 }
 
+// AddTypes adds types to be located. The type names are specified as
+// fully qualified names with a regular expression being accepted for the
+// package local component as per AddInterfaces. The kinds of type located
+// may be restricted via the TypeKinds option. See WalkTypes.
+func (t *T) AddTypes(types ...string) {
+	t.declarationSpecs[HasType] = append(t.declarationSpecs[HasType], types...)
+}
+
+// AddFields adds struct fields to be located. The fields are specified
+// as <package>.<regex> where the regular expression is matched against
+// <type>.<field>, eg. acme.com/a/b.Config\..* matches all of the fields of
+// acme.com/a/b.Config. The fields located may be restricted to those
+// with particular tags via the FieldTags option. See WalkFields.
+func (t *T) AddFields(fields ...string) {
+	t.declarationSpecs[HasField] = append(t.declarationSpecs[HasField], fields...)
+}
+
+// AddConsts adds constants to be located. The constant names are specified
+// as fully qualified names with a regular expression being accepted for the
+// package local component as per AddInterfaces. See WalkConsts.
+func (t *T) AddConsts(consts ...string) {
+	t.declarationSpecs[HasConst] = append(t.declarationSpecs[HasConst], consts...)
+}
+
+// AddVars adds package level variables to be located. The variable names
+// are specified as fully qualified names with a regular expression being
+// accepted for the package local component as per AddInterfaces. For
+// example, acme.com/a/b.^Err matches all of the exported sentinel error
+// variables that follow the usual naming convention. See WalkVars.
+func (t *T) AddVars(vars ...string) {
+	t.declarationSpecs[HasVar] = append(t.declarationSpecs[HasVar], vars...)
+}
+
 // AddPackages adds packages that will be searched for implementations
 // of interfaces specified via AddInterfaces.
 func (t *T) AddPackages(packages ...string) {
@@ -188,60 +457,205 @@ func (t *T) AddPackages(packages ...string) {
 }
 
 // AddComments adds regular expressions to be matched against the contents
-// of comments.
+// of comments, or against the raw text of directives such as //go:generate
+// if the Directives option is in effect.
 func (t *T) AddComments(comments ...string) {
 	t.commentExpressions = append(t.commentExpressions, comments...)
 }
 
+// AddCallers adds packages that will be searched for references to, and
+// calls of, the located functions, interface methods and implementations.
+// See WalkReferences.
+func (t *T) AddCallers(packages ...string) {
+	t.callerPackages = append(t.callerPackages, packages...)
+}
+
 // Do locates implementations of previously added interfaces and functions.
 func (t *T) Do(ctx context.Context) error {
+	if t.options.allModules {
+		cleanup, err := t.setupWorkspace(ctx)
+		defer cleanup()
+		if err != nil {
+			return err
+		}
+	}
+	req, err := t.expand(ctx)
+	if err != nil {
+		return err
+	}
+	// References and deprecated uses are not cached since they require
+	// the type information for the located functions and interfaces or
+	// for the dependencies of the loaded packages.
+	if len(t.options.cacheDir) > 0 && len(req.callers) == 0 && len(req.deprecated) == 0 && !t.options.loadDependencies && t.options.snapshot == nil {
+		t.cache = &cacheState{dir: t.options.cacheDir}
+		req.all, req.interfaces, req.functions, req.packages, req.declarations, err = t.useCache(ctx, req.all, req.interfaces, req.functions, req.packages, req.comments, req.declarations)
+		if err != nil {
+			return err
+		}
+	}
+	if err := t.load(ctx, req.mode, req.all); err != nil {
+		return err
+	}
+	if err := t.find(ctx, req); err != nil {
+		return err
+	}
+	if t.cache != nil {
+		return t.writeCache()
+	}
+	return nil
+}
+
+// request represents the expanded specs and packages requested via
+// the Add methods.
+type request struct {
+	interfaces   []string
+	functions    []string
+	packages     []string
+	callers      []string
+	deprecated   []string
+	comments     []string
+	declarations declSpecs
+	// all is the set of packages to be loaded and mode the mode
+	// in which they are to be loaded.
+	all  []string
+	mode packages.LoadMode
+}
+
+// expand expands the go list expressions in the specs and packages
+// requested via the Add methods and compiles any exclusions.
+func (t *T) expand(ctx context.Context) (request, error) {
+	var req request
 	errs := errors.M{}
-	interfaces, err := listPackagesOrSpecs(ctx, t.interfacePackages)
+	exclusions := map[HitMask][]string{}
+	interfaces, ifcExclusions, err := t.listPackagesOrSpecs(ctx, t.interfacePackages)
 	errs.Append(err)
-	functions, err := listPackagesOrSpecs(ctx, t.functionPackages)
+	functions, fnExclusions, err := t.listPackagesOrSpecs(ctx, t.functionPackages)
 	errs.Append(err)
+	exclusions[HasInterface], exclusions[HasFunction] = ifcExclusions, fnExclusions
+	declarations := declSpecs{}
+	for _, kind := range declKinds {
+		declarations[kind], exclusions[kind], err = t.listPackagesOrSpecs(ctx, t.declarationSpecs[kind])
+		errs.Append(err)
+	}
+	t.exclusions = map[HitMask][]nameExclusion{}
+	for kind, specs := range exclusions {
+		t.exclusions[kind], err = compileExclusions(specs)
+		errs.Append(err)
+	}
 	var packages []string
 	if len(t.implementationPackages) > 0 {
-		packages, err = listPackages(ctx, t.implementationPackages)
+		packages, err = t.listPackages(ctx, t.implementationPackages)
+		errs.Append(err)
+	}
+	var callers []string
+	if len(t.callerPackages) > 0 {
+		callers, err = t.listPackages(ctx, t.callerPackages)
+		errs.Append(err)
+	}
+	var deprecated []string
+	if len(t.deprecatedPackages) > 0 {
+		deprecated, err = t.listPackages(ctx, t.deprecatedPackages)
 		errs.Append(err)
 	}
 	if err := errs.Err(); err != nil {
-		return err
+		return req, err
 	}
-
-	packages = dedup(packages)
-	allPackages, err := packagesToLoad(ctx, interfaces, functions, packages)
+	req.interfaces = interfaces
+	req.functions = functions
+	req.declarations = declarations
+	req.packages = dedup(packages)
+	req.callers = dedup(callers)
+	req.deprecated = dedup(deprecated)
+	req.comments = dedup(t.commentExpressions)
+	t.packages = req.packages
+	req.all, err = packagesToLoad(ctx, interfaces, functions,
+		append(append(append(req.packages, req.callers...), req.deprecated...), packagesFromSpecs(declarations.all())...))
 	if err != nil {
-		return err
-	}
-	comments := dedup(t.commentExpressions)
-	if err := t.loader.loadPaths(allPackages, t.options.tests); err != nil {
-		return err
+		return req, err
 	}
-	if err := t.findInterfaces(ctx, interfaces); err != nil {
+	req.mode = t.loadMode(interfaces, functions, req.callers, req.deprecated, declarations.all())
+	return req, nil
+}
+
+// find locates the requested interfaces, functions, implementations,
+// comments, declarations, references and deprecated uses in the loaded
+// packages.
+func (t *T) find(ctx context.Context, req request) error {
+	if err := t.findInterfaces(ctx, req.interfaces); err != nil {
 		return err
 	}
-	grp, ctx := errgroup.WithContext(ctx)
-	grp.GoContext(ctx, func() error {
-		return t.findFunctions(ctx, functions)
+	grp, gctx := errgroup.WithContext(ctx)
+	grp.GoContext(gctx, func() error {
+		return t.findFunctions(gctx, req.functions)
+	})
+	grp.GoContext(gctx, func() error {
+		return t.findImplementations(gctx, req.packages)
 	})
-	grp.GoContext(ctx, func() error {
-		return t.findImplementations(ctx, packages)
+	grp.GoContext(gctx, func() error {
+		return t.findComments(gctx, req.comments)
 	})
-	grp.GoContext(ctx, func() error {
-		return t.findComments(ctx, comments)
+	grp.GoContext(gctx, func() error {
+		return t.findDeclarations(gctx, req.declarations)
 	})
-	return grp.Wait()
+	if err := grp.Wait(); err != nil {
+		return err
+	}
+	if err := t.findReferences(ctx, req.callers); err != nil {
+		return err
+	}
+	return t.findDeprecatedUses(ctx, req.deprecated)
+}
+
+// load loads the specified packages, or obtains them from the snapshot
+// specified via UseSnapshot.
+func (t *T) load(ctx context.Context, mode packages.LoadMode, paths []string) error {
+	if t.options.snapshot == nil {
+		return t.loader.loadPaths(t.packagesConfig(ctx, mode), paths, t.options.tolerateErrors)
+	}
+	pkgs, err := t.options.snapshot.packagesFor(ctx, paths)
+	if err != nil {
+		return err
+	}
+	return t.loader.addPackages(pkgs, t.options.tolerateErrors)
+}
+
+// loadMode returns the minimal packages.LoadMode required to satisfy
+// the requested interfaces, functions, callers, deprecated uses,
+// declarations and options.
+func (t *T) loadMode(interfaces, functions, callers, deprecated, declarations []string) packages.LoadMode {
+	switch {
+	case t.options.loadDependencies || len(deprecated) > 0:
+		t.trace("load: mode: types and dependencies\n")
+		return typesLoadMode | packages.NeedImports | packages.NeedDeps
+	case t.options.requireTypes || len(interfaces) > 0 || len(functions) > 0 || len(callers) > 0 || len(declarations) > 0:
+		t.trace("load: mode: types\n")
+		return typesLoadMode
+	}
+	t.trace("load: mode: syntax\n")
+	return syntaxLoadMode
 }
 
-// MakeCommentMaps creates a new ast.CommentMap for every processed file.
-// CommentMaps are expensive to create and hence should be created once and
-// reused.
+// packagesConfig returns the packages.Config to use for loading packages
+// with the specified mode.
+func (t *T) packagesConfig(ctx context.Context, mode packages.LoadMode) *packages.Config {
+	return &packages.Config{
+		Context:    ctx,
+		Mode:       mode,
+		Tests:      t.options.tests,
+		BuildFlags: t.options.buildFlags,
+		Overlay:    t.options.overlay,
+		Dir:        t.options.dir,
+		Env:        t.env,
+	}
+}
+
+// MakeCommentMaps returns the ast.CommentMap for every processed file.
+// The comment maps are those created when the packages were loaded and
+// hence should not be modified.
 func (t *T) MakeCommentMaps() map[*ast.File]ast.CommentMap {
 	cmaps := map[*ast.File]ast.CommentMap{}
-	t.WalkFiles(func(_ string, pkg *packages.Package, _ ast.CommentMap, file *ast.File, _ HitMask) {
-		cmaps[file] = ast.NewCommentMap(pkg.Fset, file, file.Comments)
-
+	t.WalkFiles(func(_ string, _ *packages.Package, cmap ast.CommentMap, file *ast.File, _ HitMask) {
+		cmaps[file] = cmap
 	})
 	return cmaps
 }
@@ -268,33 +682,153 @@ func IsGoListPath(path string) bool {
 		strings.Contains(path, "...")
 }
 
-func listPackagesOrSpecs(ctx context.Context, specs []string) ([]string, error) {
-	var expanded []string
-	var tolist []string
+// listPackagesOrSpecs expands any go list expressions in specs and applies
+// any negative go list expressions. The remaining negative specs are
+// returned as exclusions.
+func (t *T) listPackagesOrSpecs(ctx context.Context, specs []string) (expanded, exclusions []string, err error) {
+	specs, negated := splitExclusions(specs)
+	// Indexed by signature, the go list expressions to be expanded.
+	tolist := map[string][]string{}
+	var signatures []string
 	for _, spec := range specs {
-		if IsGoListPath(spec) {
-			tolist = append(tolist, spec)
+		if pattern, signature := splitSignature(spec); IsGoListPath(pattern) {
+			if _, ok := tolist[signature]; !ok {
+				signatures = append(signatures, signature)
+			}
+			tolist[signature] = append(tolist[signature], pattern)
 			continue
 		}
 		expanded = append(expanded, spec)
 	}
-	if len(tolist) > 0 {
-		listed, err := listPackages(ctx, tolist)
+	for _, signature := range signatures {
+		listed, err := t.listPackages(ctx, tolist[signature])
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, path := range listed {
+			if len(signature) > 0 {
+				path += ";" + signature
+			}
+			expanded = append(expanded, path)
+		}
+	}
+	var listExclusions []string
+	for _, spec := range negated {
+		if pattern, _ := splitSignature(spec); IsGoListPath(pattern) {
+			listExclusions = append(listExclusions, spec)
+			continue
+		}
+		exclusions = append(exclusions, spec)
+	}
+	if len(listExclusions) > 0 {
+		expanded, err = t.excludePackages(ctx, expanded, listExclusions, true)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return dedup(expanded), exclusions, nil
+}
+
+func isRelativePattern(pattern string) bool {
+	return pattern == "." || pattern == ".." || strings.HasPrefix(pattern, "./") ||
+		strings.HasPrefix(pattern, "../") || strings.HasPrefix(pattern, "...")
+}
+
+// listPackages expands the supplied go list patterns and then removes
+// the packages matched by any negative patterns.
+func (t *T) listPackages(ctx context.Context, patterns []string) ([]string, error) {
+	patterns, negated := splitExclusions(patterns)
+	paths, err := t.listPatterns(ctx, patterns)
+	if err != nil || len(negated) == 0 {
+		return paths, err
+	}
+	return t.excludePackages(ctx, paths, negated, false)
+}
+
+// listPatterns expands the supplied go list patterns. When AllModules is
+// in effect, relative patterns are expanded within each of the modules
+// that they refer to.
+func (t *T) listPatterns(ctx context.Context, patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	if len(t.modules) == 0 {
+		return t.goList(ctx, t.dir(), patterns)
+	}
+	dir, err := filepath.Abs(t.dir())
+	if err != nil {
+		return nil, err
+	}
+	byModule := map[string][]string{}
+	var others []string
+	for _, pattern := range patterns {
+		var expanded map[string]string
+		if isRelativePattern(pattern) {
+			expanded = modulePatterns(dir, pattern, t.modules)
+		}
+		if len(expanded) == 0 {
+			others = append(others, pattern)
+			continue
+		}
+		for moduleDir, p := range expanded {
+			byModule[moduleDir] = append(byModule[moduleDir], p)
+		}
+	}
+	var paths []string
+	if len(others) > 0 {
+		listed, err := t.goList(ctx, dir, others)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, listed...)
+	}
+	for _, m := range t.modules {
+		if len(byModule[m.Dir]) == 0 {
+			continue
+		}
+		listed, err := t.goList(ctx, m.Dir, byModule[m.Dir])
 		if err != nil {
 			return nil, err
 		}
-		expanded = append(expanded, listed...)
+		paths = append(paths, listed...)
 	}
-	return dedup(expanded), nil
+	return paths, nil
+}
+
+func (t *T) goList(ctx context.Context, dir string, packages []string) ([]string, error) {
+	if t.options.snapshot == nil {
+		return t.runGoList(ctx, dir, packages)
+	}
+	return t.options.snapshot.list(dir, packages, func() ([]string, error) {
+		return t.runGoList(ctx, dir, packages)
+	})
 }
 
-func listPackages(ctx context.Context, packages []string) ([]string, error) {
-	cmd := exec.CommandContext(ctx, "go", append([]string{"list"}, packages...)...)
+func (t *T) runGoList(ctx context.Context, dir string, packages []string) ([]string, error) {
+	args := []string{"list"}
+	if t.options.tolerateErrors {
+		// Packages with errors, such as import cycles, are still listed.
+		args = append(args, "-e")
+	}
+	if len(t.options.overlay) > 0 {
+		// Pass the overlay to go list, as packages.Load does, so that
+		// packages and files that exist only in the overlay are listed.
+		overlayFile, cleanup, err := writeOverlayFile(t.options.overlay)
+		defer cleanup()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "-overlay="+overlayFile)
+	}
+	cmd := t.goCommand(ctx, dir, append(args, packages...)...)
 	out, err := cmd.Output()
 	if err != nil {
 		cl := strings.Join(cmd.Args, ", ")
-		exitErr := err.(*exec.ExitError)
-		return nil, fmt.Errorf("failed to run %v: %v\n%s", cl, err, exitErr.Stderr)
+		var stderr []byte
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			stderr = exitErr.Stderr
+		}
+		return nil, fmt.Errorf("failed to run %v: %v\n%s", cl, err, stderr)
 	}
 	parts := strings.Split(string(out), "\n")
 	paths := make([]string, 0, len(parts))
@@ -307,6 +841,42 @@ func listPackages(ctx context.Context, packages []string) ([]string, error) {
 	return paths, nil
 }
 
+// Modules returns the modules in use when AllModules is specified. It is
+// only meaningful after Do has been called.
+func (t *T) Modules() []Module {
+	return t.modules
+}
+
+// Packages returns the packages specified via AddPackages with any
+// 'go list' expressions expanded. It is only meaningful after Do has
+// been called.
+func (t *T) Packages() []string {
+	return t.packages
+}
+
+// lookupTypedPackage returns the package for path provided that it was
+// successfully type checked. When errors are being tolerated, a nil package
+// and nil error are returned for packages that failed to load or type check.
+func (t *T) lookupTypedPackage(path, what string) (*packages.Package, error) {
+	if t.options.tolerateErrors && t.loader.hasDiagnostics(path) {
+		t.trace("%v: ignoring package with errors: %v\n", what, path)
+		return nil, nil
+	}
+	pkg := t.loader.lookupPackage(path)
+	if pkg == nil {
+		return nil, fmt.Errorf("%v: failed to lookup: %v", what, path)
+	}
+	return pkg, nil
+}
+
+// WalkDiagnostics calls the supplied function for each package that failed
+// to load or type check when TolerateErrors is in effect. The function is
+// called in lexicographic order of package path with the errors reported
+// for that package, including their positions.
+func (t *T) WalkDiagnostics(fn func(pkgPath string, errs []packages.Error)) {
+	t.loader.walkDiagnostics(fn)
+}
+
 // WalkPackages calls the supplied function for each package loaded. The
 // function is called in lexicographic order of package path.
 func (t *T) WalkPackages(fn func(pkg *packages.Package)) {
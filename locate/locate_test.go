@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"go/ast"
 	"go/types"
+	"path/filepath"
+	"reflect"
 	"sort"
 	"strings"
 	"testing"
@@ -142,6 +144,21 @@ func TestMultiPackageError(t *testing.T) {
 	}
 }
 
+func TestPackages(t *testing.T) {
+	ctx := context.Background()
+	locator := locate.New()
+	locator.AddInterfaces(here + "data")
+	locator.AddPackages(here+"data", "./testdata/impl", here+"impl")
+	if got := locator.Packages(); len(got) != 0 {
+		t.Errorf("unexpected packages before Do: %v", got)
+	}
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locator.Do: %v", err)
+	}
+	// The go list expression is expanded and duplicates removed.
+	compareSlices(t, locator.Packages(), []string{here + "data", here + "impl"})
+}
+
 func TestHitMask(t *testing.T) {
 	for i, tc := range []struct {
 		hm  locate.HitMask
@@ -158,3 +175,165 @@ func TestHitMask(t *testing.T) {
 		}
 	}
 }
+
+func TestTolerateErrors(t *testing.T) {
+	ctx := context.Background()
+	locator := locate.New(locate.TolerateErrors())
+	locator.AddInterfaces(here+"data", here+"typeerror")
+	locator.AddPackages(here+"data", here+"parseerror", here+"typeerror")
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locate.Do: %v", err)
+	}
+
+	diagnostics := []string{}
+	locator.WalkDiagnostics(func(pkgPath string, errs []packages.Error) {
+		for _, err := range errs {
+			if len(err.Pos) > 0 {
+				diagnostics = append(diagnostics, fmt.Sprintf("%v: %v", pkgPath, err.Pos))
+			}
+		}
+	})
+	if got, want := len(diagnostics), 2; got != want {
+		t.Fatalf("got %v, want %v: %v", got, want, diagnostics)
+	}
+	for i, suffix := range []string{
+		filepath.Join("parseerror", "error.go:3:1"),
+		filepath.Join("typeerror", "error.go:3:13"),
+	} {
+		if got := diagnostics[i]; !strings.HasSuffix(got, suffix) {
+			t.Errorf("%v: got %v does not end with %v", i, got, suffix)
+		}
+	}
+
+	// Syntax is still available for the broken packages.
+	files := []string{}
+	locator.WalkFiles(func(filename string, _ *packages.Package, _ ast.CommentMap, _ *ast.File, _ locate.HitMask) {
+		if strings.Contains(filename, "error") {
+			files = append(files, filename)
+		}
+	})
+	if got, want := len(files), 2; got != want {
+		t.Errorf("got %v, want %v: %v", got, want, files)
+	}
+
+	// But type dependent features are not.
+	locator.WalkFunctions(func(name string, _ *packages.Package, _ *ast.File, _ *types.Func, _ *ast.FuncDecl, _ []string) {
+		if strings.Contains(name, "error") {
+			t.Errorf("unexpected function: %v", name)
+		}
+	})
+	if got := listInterfaces(locator); len(got) == 0 {
+		t.Errorf("failed to find any interfaces")
+	}
+}
+
+func TestLoadMode(t *testing.T) {
+	ctx := context.Background()
+	for i, tc := range []struct {
+		opts       []locate.Option
+		interfaces []string
+		typed      bool
+	}{
+		{nil, nil, false},
+		{[]locate.Option{locate.RequireTypes()}, nil, true},
+		{nil, []string{here + "data"}, true},
+	} {
+		locator := locate.New(tc.opts...)
+		locator.AddInterfaces(tc.interfaces...)
+		locator.AddPackages(here+"data", here+"comments")
+		locator.AddComments(".*")
+		if err := locator.Do(ctx); err != nil {
+			t.Fatalf("%v: locate.Do: %v", i, err)
+		}
+		npkgs := 0
+		locator.WalkPackages(func(pkg *packages.Package) {
+			npkgs++
+			if len(pkg.Syntax) == 0 {
+				t.Errorf("%v: %v: no syntax", i, pkg.PkgPath)
+			}
+			if got, want := pkg.Types != nil, tc.typed; got != want {
+				t.Errorf("%v: %v: got %v, want %v", i, pkg.PkgPath, got, want)
+			}
+			if got, want := pkg.TypesInfo != nil, tc.typed; got != want {
+				t.Errorf("%v: %v: got %v, want %v", i, pkg.PkgPath, got, want)
+			}
+		})
+		if npkgs == 0 {
+			t.Errorf("%v: no packages", i)
+		}
+		ncomments := 0
+		locator.WalkComments(func(string, string, ast.Node, *ast.CommentGroup, *packages.Package, *ast.File) {
+			ncomments++
+		})
+		if ncomments == 0 {
+			t.Errorf("%v: no comments", i)
+		}
+	}
+}
+
+func TestAllModules(t *testing.T) {
+	ctx := context.Background()
+	root := filepath.Join("testdata", "modules")
+	modules, err := locate.FindModules(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{}
+	for _, m := range modules {
+		paths = append(paths, m.Path)
+	}
+	if got, want := strings.Join(paths, ","), "example.com/a,example.com/b"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	locator := locate.New(locate.Dir(root), locate.AllModules(true))
+	locator.AddInterfaces("example.com/a/x")
+	locator.AddPackages("./...")
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locate.Do: %v", err)
+	}
+	if got, want := strings.Join(locator.Packages(), ","), "example.com/a/x,example.com/b/y"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	found := []string{}
+	locator.WalkLocations(func(loc locate.Location) {
+		found = append(found, fmt.Sprintf("%v: %v %v", loc.Module, loc.Name, loc.Implements))
+	})
+	if got, want := found, []string{
+		"example.com/a: example.com/a/x.I []",
+		"example.com/b: example.com/b/y.T [example.com/a/x.I]",
+		"example.com/b: (example.com/b/y.T).M [example.com/a/x.I]",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	ctx := context.Background()
+	locator := locate.New()
+	locator.AddFunctions(here + "data.Fn")
+	locator.AddInterfaces(here + "data.Ifc1")
+	locator.AddPackages(here + "impl")
+	locator.AddCallers(here + "callers")
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locate.Do: %v", err)
+	}
+	found := []string{}
+	locator.WalkReferences(func(ref locate.Reference) {
+		found = append(found, fmt.Sprintf("%v:%v: %v %v call=%v in %v",
+			filepath.Base(ref.Position.Filename), ref.Position.Line,
+			ref.Function, ref.Interface, ref.Call, ref.Caller))
+	})
+	d, i, c := here+"data", here+"impl", here+"callers"
+	if got, want := found, []string{
+		"callers.go:8: " + d + ".Fn2  call=false in ",
+		"callers.go:11: " + d + ".Fn1  call=true in " + c + ".Direct",
+		"callers.go:16: (*" + i + ".Impl1).M1 " + d + ".Ifc1 call=true in " + c + ".ViaInterface",
+		"callers.go:16: (*" + i + ".Impl12).M1 " + d + ".Ifc1 call=true in " + c + ".ViaInterface",
+		"callers.go:16: (" + d + ".Ifc1).M1  call=true in " + c + ".ViaInterface",
+		"callers.go:17: (*" + i + ".Impl1).M2  call=true in " + c + ".ViaInterface",
+		"callers.go:21: " + d + ".Fn1  call=false in " + c + ".Indirect",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v\nwant %v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"go/ast"
 	"go/token"
 	"strings"
+	"unicode"
 )
 
 // CommentGroupsContain returns if any of the supplied CommentGroups
@@ -43,3 +44,80 @@ func CommentGroupBounds(comments []*ast.CommentGroup) (first, last token.Pos) {
 	}
 	return
 }
+
+// Directive represents a directive comment of the form
+// //<key>:<value> <args>, eg. //go:generate stringer -type=Kind,
+// //go:build linux or //nolint:errcheck // explanation.
+type Directive struct {
+	// Key is the text before the colon, eg. go, nolint or lint.
+	Key string
+	// Value is the text following the colon up to the first white
+	// space, eg. generate, build or errcheck,gosec.
+	Value string
+	// Args is the remainder of the directive with leading and trailing
+	// white space removed.
+	Args string
+}
+
+// String returns the directive as it would appear in a comment.
+func (d Directive) String() string {
+	out := "//" + d.Key
+	if len(d.Value) > 0 {
+		out += ":" + d.Value
+	}
+	if len(d.Args) > 0 {
+		out += " " + d.Args
+	}
+	return out
+}
+
+// ParseDirective parses the raw text of a single // comment, as found in
+// ast.Comment.Text, as a directive. Following the go toolchain's
+// convention, a directive has no space after the // and is of the form
+// <key>:<value> where key and the first character of value are lower case
+// letters or digits. A bare //nolint, which applies to all linters, is
+// also accepted. It returns false if text is not a directive.
+func ParseDirective(text string) (Directive, bool) {
+	text, ok := strings.CutPrefix(text, "//")
+	if !ok {
+		return Directive{}, false
+	}
+	directive, args := text, ""
+	if idx := strings.IndexFunc(text, unicode.IsSpace); idx >= 0 {
+		directive, args = text[:idx], strings.TrimSpace(text[idx:])
+	}
+	if directive == "nolint" {
+		return Directive{Key: directive, Args: args}, true
+	}
+	key, value, ok := strings.Cut(directive, ":")
+	if !ok || len(key) == 0 || len(value) == 0 || !isDirectiveChar(value[0]) {
+		return Directive{}, false
+	}
+	for i := 0; i < len(key); i++ {
+		if !isDirectiveChar(key[i]) {
+			return Directive{}, false
+		}
+	}
+	return Directive{Key: key, Value: value, Args: args}, true
+}
+
+func isDirectiveChar(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('0' <= c && c <= '9')
+}
+
+// Deprecation returns the text of the deprecation notice in the supplied
+// doc comment, following the go convention of a paragraph that starts with
+// "Deprecated: ". The text is the remainder of the paragraph with its lines
+// joined by spaces. It returns false if there is no such paragraph.
+func Deprecation(doc *ast.CommentGroup) (string, bool) {
+	if doc == nil {
+		return "", false
+	}
+	for _, para := range strings.Split(doc.Text(), "\n\n") {
+		text, ok := strings.CutPrefix(strings.TrimSpace(para), "Deprecated: ")
+		if ok {
+			return strings.Join(strings.Fields(text), " "), true
+		}
+	}
+	return "", false
+}
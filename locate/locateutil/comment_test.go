@@ -66,3 +66,59 @@ func TestComments(t *testing.T) {
 		}
 	}
 }
+
+func TestParseDirective(t *testing.T) {
+	for i, tc := range []struct {
+		text string
+		ok   bool
+		want locateutil.Directive
+	}{
+		{"//go:generate stringer -type=Kind", true, locateutil.Directive{Key: "go", Value: "generate", Args: "stringer -type=Kind"}},
+		{"//go:build linux && amd64", true, locateutil.Directive{Key: "go", Value: "build", Args: "linux && amd64"}},
+		{"//nolint:errcheck,gosec // explanation", true, locateutil.Directive{Key: "nolint", Value: "errcheck,gosec", Args: "// explanation"}},
+		{"//nolint", true, locateutil.Directive{Key: "nolint"}},
+		{"//nolint\tfor everything", true, locateutil.Directive{Key: "nolint", Args: "for everything"}},
+		{"//lint:ignore U1000 unused", true, locateutil.Directive{Key: "lint", Value: "ignore", Args: "U1000 unused"}},
+		{"//go:embed", true, locateutil.Directive{Key: "go", Value: "embed"}},
+		{"// go:generate with a space", false, locateutil.Directive{}},
+		{"//Note: not a directive", false, locateutil.Directive{}},
+		{"//http://example.com", false, locateutil.Directive{}},
+		{"/* go:generate */", false, locateutil.Directive{}},
+	} {
+		got, ok := locateutil.ParseDirective(tc.text)
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("%v: %q: got %#v, %v, want %#v, %v", i, tc.text, got, ok, tc.want, tc.ok)
+		}
+		if ok && got.String() != strings.Join(strings.Fields(tc.text), " ") {
+			t.Errorf("%v: got %v, want %v", i, got.String(), tc.text)
+		}
+	}
+}
+
+func TestDeprecation(t *testing.T) {
+	group := func(lines ...string) *ast.CommentGroup {
+		cg := &ast.CommentGroup{}
+		for _, l := range lines {
+			cg.List = append(cg.List, &ast.Comment{Text: l})
+		}
+		return cg
+	}
+	for i, tc := range []struct {
+		doc  *ast.CommentGroup
+		ok   bool
+		want string
+	}{
+		{nil, false, ""},
+		{group("// F does something."), false, ""},
+		{group("// Deprecated: use G."), true, "use G."},
+		{group("// F does something.", "//", "// Deprecated: use G", "// instead.", "//", "// More."), true, "use G instead."},
+		{group("/*\nF does something.\n\nDeprecated: use G.\n*/"), true, "use G."},
+		{group("// F is not Deprecated: in this case."), false, ""},
+		{group("// Deprecated:without a space."), false, ""},
+	} {
+		got, ok := locateutil.Deprecation(tc.doc)
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("%v: got %q, %v, want %q, %v", i, got, ok, tc.want, tc.ok)
+		}
+	}
+}
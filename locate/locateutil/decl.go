@@ -0,0 +1,132 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locateutil
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// CommentDecl returns the declaration that the comment group applies to,
+// ie. the declaration that it is the doc comment for, is contained within
+// or trails on the same line, and its name. The name is <type>.<method>
+// for methods, the names of the variables, constants or types that the
+// comment applies to, comma separated, for grouped declarations, and the
+// import path for imports. It returns nil for comments that are not
+// associated with a declaration, such as those that precede the package
+// clause.
+func CommentDecl(fset *token.FileSet, file *ast.File, cg *ast.CommentGroup) (ast.Decl, string) {
+	line := fset.PositionFor(cg.Pos(), false).Line
+	for _, decl := range file.Decls {
+		var doc *ast.CommentGroup
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			doc = d.Doc
+		case *ast.GenDecl:
+			doc = d.Doc
+		}
+		if doc == cg || (cg.Pos() >= decl.Pos() && cg.End() <= decl.End()) ||
+			(cg.Pos() > decl.End() && fset.PositionFor(decl.End(), false).Line == line) {
+			return decl, declName(decl, func(spec ast.Spec) bool {
+				return specComment(fset, spec, cg)
+			})
+		}
+	}
+	return nil, ""
+}
+
+// EnclosingDecl returns the top-level declaration in file that contains
+// pos and its name as per CommentDecl, except that for grouped declarations
+// the name is that of the spec that contains pos.
+func EnclosingDecl(file *ast.File, pos token.Pos) (ast.Decl, string) {
+	for _, decl := range file.Decls {
+		if pos < decl.Pos() || pos >= decl.End() {
+			continue
+		}
+		return decl, declName(decl, func(spec ast.Spec) bool {
+			return pos >= spec.Pos() && pos < spec.End()
+		})
+	}
+	return nil, ""
+}
+
+// declName returns the name of decl, using only the specs for which
+// inSpec returns true for grouped declarations.
+func declName(decl ast.Decl, inSpec func(ast.Spec) bool) string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv == nil || len(d.Recv.List) == 0 {
+			return d.Name.Name
+		}
+		return receiverName(d.Recv.List[0].Type) + "." + d.Name.Name
+	case *ast.GenDecl:
+		// Use the spec that the comment applies to for grouped
+		// declarations and all of the specs otherwise.
+		names := []string{}
+		for _, spec := range d.Specs {
+			if d.Lparen.IsValid() && !inSpec(spec) {
+				continue
+			}
+			names = append(names, specNames(spec)...)
+		}
+		if len(names) == 0 {
+			for _, spec := range d.Specs {
+				names = append(names, specNames(spec)...)
+			}
+		}
+		return strings.Join(names, ",")
+	}
+	return ""
+}
+
+// specComment returns true if cg is the doc or line comment for spec or
+// is contained within it.
+func specComment(fset *token.FileSet, spec ast.Spec, cg *ast.CommentGroup) bool {
+	var doc, comment *ast.CommentGroup
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		doc, comment = s.Doc, s.Comment
+	case *ast.ValueSpec:
+		doc, comment = s.Doc, s.Comment
+	case *ast.ImportSpec:
+		doc, comment = s.Doc, s.Comment
+	}
+	if cg == doc || cg == comment {
+		return true
+	}
+	return (cg.Pos() >= spec.Pos() && cg.End() <= spec.End()) ||
+		fset.PositionFor(spec.End(), false).Line == fset.PositionFor(cg.Pos(), false).Line
+}
+
+func receiverName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(e.X)
+	case *ast.IndexExpr:
+		return receiverName(e.X)
+	case *ast.IndexListExpr:
+		return receiverName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+func specNames(spec ast.Spec) []string {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return []string{s.Name.Name}
+	case *ast.ValueSpec:
+		names := make([]string, len(s.Names))
+		for i, n := range s.Names {
+			names[i] = n.Name
+		}
+		return names
+	case *ast.ImportSpec:
+		return []string{s.Path.Value}
+	}
+	return nil
+}
@@ -28,15 +28,16 @@ type FuncDesc struct {
 }
 
 // Functions returns the functions in the supplied package that match the
-// regular expression. If noMethods is false then methods are also returned.
-func Functions(pkg *packages.Package, re *regexp.Regexp, noMethods bool) []FuncDesc {
+// regular expression and all of the supplied signature predicates, if any.
+// If noMethods is false then methods are also returned.
+func Functions(pkg *packages.Package, re *regexp.Regexp, noMethods bool, predicates ...SignaturePredicate) []FuncDesc {
 	descs := []FuncDesc{}
 	for k, obj := range pkg.TypesInfo.Defs {
 		if obj == nil || !k.IsExported() || !re.MatchString(k.Name) {
 			continue
 		}
 		fn, ok := obj.(*types.Func)
-		if !ok {
+		if !ok || !MatchesSignature(fn, predicates...) {
 			continue
 		}
 		recv := fn.Type().(*types.Signature).Recv()
@@ -85,13 +86,9 @@ func findFuncOrMethodDecl(fn *types.Func, file *ast.File) *ast.FuncDecl {
 	return nil
 }
 
-type funcVisitor struct {
-	callname *regexp.Regexp
-	deferred bool
-	nodes    []ast.Node
-}
-
-func callMatches(callexpr *ast.CallExpr, callname *regexp.Regexp) bool {
+// CallMatches returns true if the function called by callexpr, of the form
+// <name> or <ident>.<name>, matches callname.
+func CallMatches(callexpr *ast.CallExpr, callname *regexp.Regexp) bool {
 	switch id := callexpr.Fun.(type) {
 	case *ast.Ident:
 		return callname.MatchString(id.String())
@@ -100,52 +97,150 @@ func callMatches(callexpr *ast.CallExpr, callname *regexp.Regexp) bool {
 			return callname.MatchString(sel.String() + "." + id.Sel.String())
 		}
 	case *ast.CallExpr:
-		r := callMatches(id, callname)
+		r := CallMatches(id, callname)
 		return r
 	}
 	return false
 }
 
-func (v *funcVisitor) Visit(node ast.Node) ast.Visitor {
+// CallStatementKind identifies the kinds of statement that contain
+// function calls.
+type CallStatementKind int
+
+const (
+	// CallStatement is a function call used as a statement, eg. fn(...).
+	CallStatement CallStatementKind = 1 << iota
+	// DeferStatement is a deferred function call, eg. defer fn(...).
+	DeferStatement
+	// AssignStatement is an assignment, or short variable declaration,
+	// whose right hand side is a single function call, eg. a, b := fn(...).
+	AssignStatement
+)
+
+type callVisitor struct {
+	callname *regexp.Regexp
+	kinds    CallStatementKind
+	stmts    []ast.Stmt
+}
+
+func (v *callVisitor) Visit(node ast.Node) ast.Visitor {
 	if node == nil {
 		return nil
 	}
+	var kind CallStatementKind
+	var call ast.Expr
 	switch n := node.(type) {
 	case *ast.DeferStmt:
-		if !v.deferred {
-			return nil
-		}
-		if callMatches(n.Call, v.callname) {
-			v.nodes = append(v.nodes, node)
-		}
+		kind, call = DeferStatement, n.Call
 	case *ast.ExprStmt:
-		if v.deferred {
-			return nil
-		}
-		call, ok := n.X.(*ast.CallExpr)
-		if !ok {
-			return nil
-		}
-		if callMatches(call, v.callname) {
-			v.nodes = append(v.nodes, node)
+		kind, call = CallStatement, n.X
+	case *ast.AssignStmt:
+		if len(n.Rhs) != 1 {
+			return v
 		}
+		kind, call = AssignStatement, n.Rhs[0]
+	default:
+		return v
+	}
+	if v.kinds&kind == 0 {
+		return v
+	}
+	if callexpr, ok := call.(*ast.CallExpr); ok && CallMatches(callexpr, v.callname) {
+		v.stmts = append(v.stmts, node.(ast.Stmt))
 	}
 	return v
 }
 
+// CallStatements returns the statements of the specified kinds that call
+// 'callname' where callname is either a function name or a selector (eg. foo.bar).
+// Statements anywhere within the function body, including nested blocks
+// and function literals, are returned.
+func CallStatements(decl *ast.FuncDecl, callname *regexp.Regexp, kinds CallStatementKind) []ast.Stmt {
+	if decl.Body == nil || len(decl.Body.List) == 0 {
+		return nil
+	}
+	v := &callVisitor{
+		callname: callname,
+		kinds:    kinds,
+	}
+	ast.Walk(v, decl.Body)
+	return v.stmts
+}
+
 // FunctionCalls determines if the supplied function declaration contains a call
 // 'callname' where callname is either a function name or a selector (eg. foo.bar).
 // If deferred is true the function call must be defer'ed.
 func FunctionCalls(decl *ast.FuncDecl, callname *regexp.Regexp, deferred bool) []ast.Node {
-	if len(decl.Body.List) == 0 {
+	kind := CallStatement
+	if deferred {
+		kind = DeferStatement
+	}
+	var nodes []ast.Node
+	for _, stmt := range CallStatements(decl, callname, kind) {
+		nodes = append(nodes, stmt)
+	}
+	return nodes
+}
+
+// AssignedVars returns the variables assigned to, or declared, by the
+// supplied assignment statement. Blank identifiers are ignored.
+func AssignedVars(info *types.Info, assign *ast.AssignStmt) []types.Object {
+	var vars []types.Object
+	for _, lhs := range assign.Lhs {
+		id, ok := lhs.(*ast.Ident)
+		if !ok || id.Name == "_" {
+			continue
+		}
+		if obj := info.Defs[id]; obj != nil {
+			vars = append(vars, obj)
+			continue
+		}
+		if obj := info.Uses[id]; obj != nil {
+			vars = append(vars, obj)
+		}
+	}
+	return vars
+}
+
+// MethodCallStatements returns the call and defer statements within decl,
+// that follow pos, and that call a method whose name matches method on
+// one of the supplied variables, eg. defer span.End().
+func MethodCallStatements(info *types.Info, decl *ast.FuncDecl, pos token.Pos, vars []types.Object, method *regexp.Regexp) []ast.Stmt {
+	if decl.Body == nil || len(vars) == 0 {
 		return nil
 	}
-	v := &funcVisitor{
-		callname: callname,
-		deferred: deferred,
+	receivers := map[types.Object]bool{}
+	for _, v := range vars {
+		receivers[v] = true
 	}
-	ast.Walk(v, decl)
-	return v.nodes
+	var stmts []ast.Stmt
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		if node == nil || node.Pos() <= pos {
+			return true
+		}
+		var call ast.Expr
+		switch n := node.(type) {
+		case *ast.DeferStmt:
+			call = n.Call
+		case *ast.ExprStmt:
+			call = n.X
+		default:
+			return true
+		}
+		callexpr, ok := call.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := callexpr.Fun.(*ast.SelectorExpr)
+		if !ok || !method.MatchString(sel.Sel.Name) {
+			return true
+		}
+		if id, ok := sel.X.(*ast.Ident); ok && receivers[info.Uses[id]] {
+			stmts = append(stmts, node.(ast.Stmt))
+		}
+		return true
+	})
+	return stmts
 }
 
 // FunctionStatements returns number of top-level statements in a function.
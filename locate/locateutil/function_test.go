@@ -143,3 +143,55 @@ func TestFunctionCalls(t *testing.T) {
 		}
 	}
 }
+
+func TestCallStatements(t *testing.T) {
+	pkgs, err := packages.Load(packagesConfig,
+		"cloudeng.io/go/locate/testdata/calls",
+	)
+	if err != nil {
+		t.Errorf("pkg.Load: %v", err)
+	}
+	pkg := pkgs[0]
+	fns := locateutil.Functions(pkg, regexp.MustCompile("Nested|Assigned"), true)
+	if got, want := len(fns), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	nested, assigned := fns[0].Decl, fns[1].Decl
+
+	traceRE := regexp.MustCompile("^trace$")
+	for i, tc := range []struct {
+		kinds locateutil.CallStatementKind
+		calls int
+	}{
+		{locateutil.CallStatement, 2},
+		{locateutil.DeferStatement, 1},
+		{locateutil.CallStatement | locateutil.DeferStatement, 3},
+		{locateutil.AssignStatement, 0},
+	} {
+		stmts := locateutil.CallStatements(nested, traceRE, tc.kinds)
+		if got, want := len(stmts), tc.calls; got != want {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+	}
+
+	stmts := locateutil.CallStatements(assigned, regexp.MustCompile("^start$"), locateutil.AssignStatement)
+	if got, want := len(stmts), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	assign := stmts[0].(*ast.AssignStmt)
+	vars := locateutil.AssignedVars(pkg.TypesInfo, assign)
+	names := []string{}
+	for _, v := range vars {
+		names = append(names, v.Name())
+	}
+	if got, want := strings.Join(names, ","), "ctx,sp"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	paired := locateutil.MethodCallStatements(pkg.TypesInfo, assigned, assign.End(), vars, regexp.MustCompile("^End$"))
+	if got, want := len(paired), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if _, ok := paired[0].(*ast.DeferStmt); !ok {
+		t.Errorf("expected a defer statement, got %T", paired[0])
+	}
+}
@@ -36,3 +36,22 @@ func IsImportedByFile(file *ast.File, path string) bool {
 	}
 	return false
 }
+
+// ImportSpecs returns the import specs, and the declarations that contain
+// them, in the import statement or import block for the supplied file.
+func ImportSpecs(file *ast.File) ([]*ast.GenDecl, []*ast.ImportSpec) {
+	start, end := ImportBlock(file)
+	var decls []*ast.GenDecl
+	var specs []*ast.ImportSpec
+	for _, d := range file.Decls {
+		d, ok := d.(*ast.GenDecl)
+		if !ok || d.Pos() < start || d.End() > end {
+			continue
+		}
+		for _, spec := range d.Specs {
+			decls = append(decls, d)
+			specs = append(specs, spec.(*ast.ImportSpec))
+		}
+	}
+	return decls, specs
+}
@@ -36,4 +36,15 @@ func TestImports(t *testing.T) {
 	if got, want := pkg.Fset.Position(end).String(), "blocks.go:8:2"; !strings.HasSuffix(got, want) {
 		t.Errorf("got %v, doesn't have suffix %v\n", got, want)
 	}
+	decls, specs := locateutil.ImportSpecs(fns[0].File)
+	paths := []string{}
+	for i, spec := range specs {
+		if decls[i].Lparen == 0 {
+			t.Errorf("%v: expected an import block", spec.Path.Value)
+		}
+		paths = append(paths, spec.Path.Value)
+	}
+	if got, want := strings.Join(paths, " "), `"fmt" "strconv" "cloudeng.io/errors"`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
 }
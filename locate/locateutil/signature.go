@@ -0,0 +1,236 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locateutil
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+	"unicode"
+)
+
+// SignaturePredicate represents a condition on the signature of a function
+// or method.
+type SignaturePredicate func(fn *types.Func) bool
+
+// Types are specified using their fully qualified names as returned by
+// types.TypeString, eg. *database/sql.DB, context.Context, error or
+// []string. White space is ignored and any may be used in place of
+// interface{}.
+func normalizeType(typ string) string {
+	typ = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, typ)
+	return strings.ReplaceAll(typ, "interface{}", "any")
+}
+
+func typeMatches(typ types.Type, want string) bool {
+	return normalizeType(types.TypeString(typ, nil)) == want
+}
+
+func tupleMatches(tuple *types.Tuple, want []string) bool {
+	if tuple.Len() != len(want) {
+		return false
+	}
+	for i := 0; i < tuple.Len(); i++ {
+		if !typeMatches(tuple.At(i).Type(), want[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func tupleContains(tuple *types.Tuple, want string) bool {
+	for i := 0; i < tuple.Len(); i++ {
+		if typeMatches(tuple.At(i).Type(), want) {
+			return true
+		}
+	}
+	return false
+}
+
+func signature(fn *types.Func) *types.Signature {
+	return fn.Type().(*types.Signature)
+}
+
+func normalizeTypes(typs []string) []string {
+	normalized := make([]string, len(typs))
+	for i, typ := range typs {
+		normalized[i] = normalizeType(typ)
+	}
+	return normalized
+}
+
+// HasParam returns a predicate that is true for functions with at least
+// one parameter of the specified type. The final parameter of a variadic
+// function, eg. ...string, has type []string.
+func HasParam(typ string) SignaturePredicate {
+	typ = normalizeType(typ)
+	return func(fn *types.Func) bool {
+		return tupleContains(signature(fn).Params(), typ)
+	}
+}
+
+// HasResult returns a predicate that is true for functions with at least
+// one result of the specified type.
+func HasResult(typ string) SignaturePredicate {
+	typ = normalizeType(typ)
+	return func(fn *types.Func) bool {
+		return tupleContains(signature(fn).Results(), typ)
+	}
+}
+
+// Params returns a predicate that is true for functions whose parameters
+// are exactly the specified types, no types matches functions with
+// no parameters.
+func Params(typs ...string) SignaturePredicate {
+	typs = normalizeTypes(typs)
+	return func(fn *types.Func) bool {
+		return tupleMatches(signature(fn).Params(), typs)
+	}
+}
+
+// Results returns a predicate that is true for functions whose results
+// are exactly the specified types, eg. Results("error") matches functions
+// that return only an error and no types matches functions with no
+// results.
+func Results(typs ...string) SignaturePredicate {
+	typs = normalizeTypes(typs)
+	return func(fn *types.Func) bool {
+		return tupleMatches(signature(fn).Results(), typs)
+	}
+}
+
+// FirstParam returns a predicate that is true for functions whose first
+// parameter is of the specified type, eg. context.Context.
+func FirstParam(typ string) SignaturePredicate {
+	typ = normalizeType(typ)
+	return func(fn *types.Func) bool {
+		params := signature(fn).Params()
+		return params.Len() > 0 && typeMatches(params.At(0).Type(), typ)
+	}
+}
+
+// Variadic returns a predicate that is true for variadic functions.
+func Variadic() SignaturePredicate {
+	return func(fn *types.Func) bool {
+		return signature(fn).Variadic()
+	}
+}
+
+// Receiver returns a predicate that is true for methods whose receiver is
+// of the specified type. A type without a leading * matches both pointer
+// and value receivers, whereas one with a leading * matches only pointer
+// receivers. Type parameters are not included in the receiver type,
+// ie. pkg.List rather than pkg.List[T].
+func Receiver(typ string) SignaturePredicate {
+	typ = normalizeType(typ)
+	return func(fn *types.Func) bool {
+		recv := signature(fn).Recv()
+		if recv == nil {
+			return false
+		}
+		rtyp := recv.Type()
+		ptr, isPtr := rtyp.(*types.Pointer)
+		if isPtr {
+			rtyp = ptr.Elem()
+		}
+		name := normalizeType(types.TypeString(rtyp, nil))
+		if named, ok := rtyp.(*types.Named); ok {
+			name = named.Obj().Pkg().Path() + "." + named.Obj().Name()
+		}
+		if isPtr && "*"+name == typ {
+			return true
+		}
+		return name == typ
+	}
+}
+
+// ParseSignature parses a ; separated list of signature predicates of the
+// following forms:
+//
+//	param=<type>              see HasParam
+//	result=<type>             see HasResult
+//	params=<type>,<type>...   see Params
+//	results=<type>,<type>...  see Results
+//	first=<type>              see FirstParam
+//	recv=<type>               see Receiver
+//	variadic                  see Variadic
+//
+// For example, param=*database/sql.DB;results=error.
+func ParseSignature(spec string) ([]SignaturePredicate, error) {
+	var predicates []SignaturePredicate
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+		if part == "variadic" {
+			predicates = append(predicates, Variadic())
+			continue
+		}
+		name, typ, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid signature predicate: %q, must be variadic or of the form <predicate>=<type>", part)
+		}
+		if len(typ) == 0 && name != "params" && name != "results" {
+			return nil, fmt.Errorf("invalid signature predicate: %q: missing type", part)
+		}
+		switch name {
+		case "param":
+			predicates = append(predicates, HasParam(typ))
+		case "result":
+			predicates = append(predicates, HasResult(typ))
+		case "params":
+			predicates = append(predicates, Params(splitTypes(typ)...))
+		case "results":
+			predicates = append(predicates, Results(splitTypes(typ)...))
+		case "first":
+			predicates = append(predicates, FirstParam(typ))
+		case "recv":
+			predicates = append(predicates, Receiver(typ))
+		default:
+			return nil, fmt.Errorf("unsupported signature predicate: %q", name)
+		}
+	}
+	return predicates, nil
+}
+
+// splitTypes splits a comma separated list of types, ignoring commas
+// within brackets, eg. func(int, string) or pkg.Map[K, V].
+func splitTypes(list string) []string {
+	var typs []string
+	depth, start := 0, 0
+	for i, r := range list {
+		switch r {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				typs = append(typs, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(list[start:]); len(last) > 0 || len(typs) > 0 {
+		typs = append(typs, list[start:])
+	}
+	return typs
+}
+
+// MatchesSignature returns true if fn satisfies all of the predicates.
+func MatchesSignature(fn *types.Func, predicates ...SignaturePredicate) bool {
+	for _, p := range predicates {
+		if !p(fn) {
+			return false
+		}
+	}
+	return true
+}
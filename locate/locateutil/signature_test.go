@@ -0,0 +1,59 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locateutil_test
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"cloudeng.io/go/locate/locateutil"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestSignatures(t *testing.T) {
+	pkgs, err := packages.Load(packagesConfig, "cloudeng.io/go/locate/testdata/signatures")
+	if err != nil {
+		t.Fatalf("pkg.Load: %v", err)
+	}
+	for i, tc := range []struct {
+		signature string
+		names     []string
+	}{
+		{"", []string{"Query", "QueryContext", "Close", "Printf", "Lookup", "Get", "Len", "Append"}},
+		{"param=*database/sql.DB", []string{"Query", "QueryContext"}},
+		{"results=error", []string{"Query", "Close"}},
+		{"result=error", []string{"Query", "QueryContext", "Close", "Get"}},
+		{"results=", []string{"Printf", "Lookup", "Append"}},
+		{"params=", []string{"Close", "Len"}},
+		{"params=string, []any", []string{"Printf"}},
+		{"params=map[string]func(int, string) error", []string{"Lookup"}},
+		{"first=context.Context", []string{"QueryContext", "Get"}},
+		{"first=context.Context;results=string,error", []string{"Get"}},
+		{"variadic", []string{"Printf"}},
+		{"recv=cloudeng.io/go/locate/testdata/signatures.Store", []string{"Get", "Len"}},
+		{"recv=*cloudeng.io/go/locate/testdata/signatures.Store", []string{"Get"}},
+		{"recv=*cloudeng.io/go/locate/testdata/signatures.List", []string{"Append"}},
+	} {
+		predicates, err := locateutil.ParseSignature(tc.signature)
+		if err != nil {
+			t.Errorf("%v: %v", i, err)
+			continue
+		}
+		names := []string{}
+		for _, fn := range locateutil.Functions(pkgs[0], regexp.MustCompile(".*"), false, predicates...) {
+			names = append(names, fn.Type.Name())
+		}
+		if got, want := names, tc.names; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: %v: got %v, want %v", i, tc.signature, got, want)
+		}
+	}
+
+	for _, invalid := range []string{"param", "param=", "returns=error"} {
+		if _, err := locateutil.ParseSignature(invalid); err == nil {
+			t.Errorf("%v: expected an error", invalid)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Location represents a single result, ie. an interface, function,
+// comment, type, field, constant, variable, directive or use of a
+// deprecated object located by T. Unlike the results provided by
+// WalkInterfaces, WalkFunctions and WalkComments, a Location does not
+// refer to the parsed or type checked representation of the code and
+// hence may be cached and reused without having to reload the package it
+// refers to.
+type Location struct {
+	// Kind is one of HasInterface, HasFunction, HasComment, HasType,
+	// HasField, HasConst, HasVar, HasImplementation, HasDirective or
+	// HasDeprecatedUse.
+	Kind HitMask
+	// Package is the path of the package containing the location.
+	Package string
+	// Module is the path of the module containing the package, if any.
+	Module string `json:",omitempty"`
+	// Name is the fully qualified name of an interface, function, type,
+	// field, constant, variable, implementing type or deprecated object,
+	// or the regular expression that matched a comment or directive.
+	Name string
+	// Detail is the types.Func.String() representation of a function,
+	// the type of the ast.Node that a comment is associated with, the
+	// TypeKind of a type, the type of a field, constant or variable, the
+	// type (T or *T) that implements an interface, the raw text of a
+	// directive or the deprecation notice of a deprecated object.
+	Detail string
+	// Position is the position of the interface, function or comment.
+	Position token.Position
+	// Implements lists the interfaces implemented by a method or type.
+	Implements []string `json:",omitempty"`
+	// Tag is the tag of a struct field.
+	Tag string `json:",omitempty"`
+	// Decl is the name of the declaration that a directive applies to
+	// or that contains the use of a deprecated object.
+	Decl string `json:",omitempty"`
+}
+
+// locations returns the locations for the results obtained from the
+// packages that were loaded.
+func (t *T) locations() []Location {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	locs := []Location{}
+	for name, ifc := range t.interfaces {
+		locs = append(locs, Location{
+			Kind:     HasInterface,
+			Package:  ifc.path,
+			Module:   ifc.module,
+			Name:     name,
+			Position: ifc.position,
+		})
+	}
+	for name, fn := range t.functions {
+		locs = append(locs, Location{
+			Kind:       HasFunction,
+			Package:    fn.path,
+			Module:     modulePathFor(fn.Package),
+			Name:       name,
+			Detail:     fn.Type.String(),
+			Position:   fn.Position,
+			Implements: fn.implements,
+		})
+	}
+	for re, comments := range t.comments {
+		for _, c := range comments {
+			locs = append(locs, Location{
+				Kind:     HasComment,
+				Package:  c.pkg.PkgPath,
+				Module:   modulePathFor(c.pkg),
+				Name:     re,
+				Detail:   fmt.Sprintf("%T", c.node),
+				Position: c.pkg.Fset.PositionFor(c.cg.Pos(), false),
+			})
+		}
+	}
+	for _, d := range t.directives {
+		locs = append(locs, Location{
+			Kind:     HasDirective,
+			Package:  d.Package.PkgPath,
+			Module:   modulePathFor(d.Package),
+			Name:     d.Regexp,
+			Detail:   d.Text,
+			Position: d.Position,
+			Decl:     d.Name,
+		})
+	}
+	for _, u := range t.deprecatedUses {
+		locs = append(locs, Location{
+			Kind:     HasDeprecatedUse,
+			Package:  u.Package.PkgPath,
+			Module:   modulePathFor(u.Package),
+			Name:     u.Name,
+			Detail:   u.Message,
+			Position: u.Position,
+			Decl:     u.DeclName,
+		})
+	}
+	locs = append(locs, t.implementationLocationsLocked()...)
+	return append(locs, t.declarationLocationsLocked()...)
+}
+
+// implementationLocationsLocked returns a single location for each
+// implementing type (T or *T) that lists all of the interfaces it
+// implements.
+func (t *T) implementationLocationsLocked() []Location {
+	locs := []Location{}
+	index := map[string]int{}
+	for _, impl := range t.implementations {
+		detail := impl.Type
+		if impl.Pointer {
+			detail = "*" + detail
+		}
+		if i, ok := index[detail]; ok {
+			locs[i].Implements = append(locs[i].Implements, impl.Interface)
+			continue
+		}
+		index[detail] = len(locs)
+		locs = append(locs, Location{
+			Kind:       HasImplementation,
+			Package:    impl.Package.PkgPath,
+			Module:     modulePathFor(impl.Package),
+			Name:       impl.Type,
+			Detail:     detail,
+			Position:   impl.Position,
+			Implements: []string{impl.Interface},
+		})
+	}
+	for i := range locs {
+		sort.Strings(locs[i].Implements)
+	}
+	return locs
+
+}
+
+func modulePathFor(pkg *packages.Package) string {
+	if pkg == nil || pkg.Module == nil {
+		return ""
+	}
+	return pkg.Module.Path
+}
+
+func sortLocations(locs []Location) {
+	sort.Slice(locs, func(i, j int) bool {
+		a, b := locs[i].Position, locs[j].Position
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		if locs[i].Kind != locs[j].Kind {
+			return locs[i].Kind < locs[j].Kind
+		}
+		if locs[i].Name != locs[j].Name {
+			return locs[i].Name < locs[j].Name
+		}
+		return locs[i].Detail < locs[j].Detail
+	})
+}
+
+// WalkLocations calls the supplied function for every location found,
+// including those obtained from the cache when CacheDir is in effect and
+// those from the batches already processed by DoBatches. The function is
+// called in order of filename and then position within filename.
+func (t *T) WalkLocations(fn func(loc Location)) {
+	locs := append(append(t.locations(), t.cached...), t.released...)
+	sortLocations(locs)
+	for _, loc := range locs {
+		fn(loc)
+	}
+}
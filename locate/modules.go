@@ -0,0 +1,259 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Module represents a go module.
+type Module struct {
+	// Path is the module path as specified in its go.mod file.
+	Path string
+	// Dir is the absolute directory containing the module's go.mod file.
+	Dir string
+}
+
+// FindModules returns all of the modules at or below root, ie. every
+// directory that contains a go.mod file. Hidden directories, those that
+// start with an _, testdata and vendor directories are ignored as per
+// the go command. The modules are returned in lexicographic order of
+// directory.
+func FindModules(root string) ([]Module, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	var modules []Module
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "go.mod" {
+			return nil
+		}
+		modPath, err := modulePath(path)
+		if err != nil {
+			return err
+		}
+		modules = append(modules, Module{Path: modPath, Dir: filepath.Dir(path)})
+		return nil
+	})
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Dir < modules[j].Dir
+	})
+	return modules, err
+}
+
+// FindEnclosingModule returns the module that contains the specified file
+// or directory by searching for a go.mod file in it and its parents.
+func FindEnclosingModule(path string) (Module, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return Module{}, err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	for {
+		gomod := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(gomod); err == nil {
+			modPath, err := modulePath(gomod)
+			return Module{Path: modPath, Dir: dir}, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return Module{}, fmt.Errorf("no go.mod found for %v", path)
+		}
+		dir = parent
+	}
+}
+
+// modulePath returns the module path from the module directive in gomod.
+func modulePath(gomod string) (string, error) {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`+"`"), nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%v: no module directive", gomod)
+}
+
+// dir returns the directory in which go commands are to be run.
+func (t *T) dir() string {
+	if len(t.options.dir) > 0 {
+		return t.options.dir
+	}
+	return "."
+}
+
+// goCommand returns a command that runs go, with the supplied args, in
+// dir and in the environment in effect for t.
+func (t *T) goCommand(ctx context.Context, dir string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Env = t.env
+	return cmd
+}
+
+// setupWorkspace discovers the modules to be used when AllModules is
+// specified. An existing go.work file is used as is, otherwise a
+// temporary one that uses every module under the current directory is
+// created so that all of the modules are loaded, and type checked,
+// together.
+func (t *T) setupWorkspace(ctx context.Context) (func(), error) {
+	nop := func() {}
+	dir, err := filepath.Abs(t.dir())
+	if err != nil {
+		return nop, err
+	}
+	out, err := t.goCommand(ctx, dir, "env", "GOWORK").Output()
+	if err != nil {
+		return nop, fmt.Errorf("failed to run go env GOWORK: %v", err)
+	}
+	if gowork := strings.TrimSpace(string(out)); len(gowork) > 0 && gowork != "off" {
+		t.trace("modules: using %v\n", gowork)
+		t.modules, err = t.workspaceModules(ctx, dir)
+		return nop, err
+	}
+	modules, err := FindModules(dir)
+	if err != nil {
+		return nop, err
+	}
+	if len(modules) == 0 {
+		return nop, fmt.Errorf("no modules found in %v", dir)
+	}
+	goVersion, err := t.goEnvVersion(ctx)
+	if err != nil {
+		return nop, err
+	}
+	gowork, err := writeWorkFile(goVersion, modules)
+	if err != nil {
+		return nop, err
+	}
+	t.trace("modules: created %v\n", gowork)
+	t.env = append(os.Environ(), "GOWORK="+gowork, "GOFLAGS="+withoutModFlag(os.Getenv("GOFLAGS")))
+	t.modules = modules
+	return func() { os.Remove(gowork) }, nil
+}
+
+func (t *T) workspaceModules(ctx context.Context, dir string) ([]Module, error) {
+	out, err := t.goCommand(ctx, dir, "list", "-m", "-f", "{{.Path}}\t{{.Dir}}").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace modules: %v", err)
+	}
+	var modules []Module
+	for _, line := range strings.Split(string(out), "\n") {
+		if path, dir, ok := strings.Cut(line, "\t"); ok {
+			modules = append(modules, Module{Path: path, Dir: dir})
+		}
+	}
+	return modules, nil
+}
+
+func writeWorkFile(goVersion string, modules []Module) (string, error) {
+	version := strings.TrimPrefix(strings.Fields(goVersion)[0], "go")
+	out := &strings.Builder{}
+	fmt.Fprintf(out, "go %s\n\nuse (\n", version)
+	for _, m := range modules {
+		fmt.Fprintf(out, "\t%s\n", m.Dir)
+	}
+	out.WriteString(")\n")
+	f, err := os.CreateTemp("", "locate-*.work")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(out.String()); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// withoutModFlag removes any -mod flag, which is not allowed in
+// workspace mode, from goflags.
+func withoutModFlag(goflags string) string {
+	var flags []string
+	for _, flag := range strings.Fields(goflags) {
+		if !strings.HasPrefix(flag, "-mod=") {
+			flags = append(flags, flag)
+		}
+	}
+	return strings.Join(flags, " ")
+}
+
+// modulePatterns maps a relative go list pattern, eg. ./..., to the
+// equivalent pattern, if any, for each of the supplied modules. This is
+// required since the go command does not match patterns across module
+// boundaries, even in workspace mode.
+func modulePatterns(dir, pattern string, modules []Module) map[string]string {
+	recursive := pattern == "..." || strings.HasSuffix(pattern, "/...")
+	base := filepath.Join(dir, strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/"))
+	patterns := map[string]string{}
+	var enclosing *Module
+	for i, m := range modules {
+		if _, ok := within(m.Dir, base); ok {
+			// Nested modules are excluded from their parent, so use the
+			// innermost module that contains base.
+			if enclosing == nil || len(m.Dir) > len(enclosing.Dir) {
+				enclosing = &modules[i]
+			}
+			continue
+		}
+		if _, ok := within(base, m.Dir); ok && recursive {
+			patterns[m.Dir] = "./..."
+		}
+	}
+	if enclosing != nil {
+		rel, _ := within(enclosing.Dir, base)
+		switch {
+		case rel == "." && recursive:
+			patterns[enclosing.Dir] = "./..."
+		case rel == ".":
+			patterns[enclosing.Dir] = "."
+		case recursive:
+			patterns[enclosing.Dir] = "./" + filepath.ToSlash(rel) + "/..."
+		default:
+			patterns[enclosing.Dir] = "./" + filepath.ToSlash(rel)
+		}
+	}
+	return patterns
+}
+
+// within returns the path of path relative to dir if path is dir or
+// is below it.
+func within(dir, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
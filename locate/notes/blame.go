@@ -0,0 +1,77 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package notes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloudeng.io/errors"
+)
+
+// Blame sets the Date of each of the supplied notes to the author date
+// of the line containing it as reported by git blame. Lines that have not
+// been committed are reported as having been authored now and the Date of
+// notes in files that are not tracked by git, or are not within a git
+// repository, is left unchanged. git blame is run once per file.
+func Blame(ctx context.Context, notes []Note) error {
+	dates := map[string][]time.Time{}
+	errs := &errors.M{}
+	for i := range notes {
+		filename := notes[i].Position.Filename
+		lines, ok := dates[filename]
+		if !ok {
+			var err error
+			lines, err = blameFile(ctx, filename)
+			errs.Append(err)
+			dates[filename] = lines
+		}
+		if line := notes[i].Position.Line; line > 0 && line <= len(lines) {
+			date := lines[line-1]
+			notes[i].Date = &date
+		}
+	}
+	return errs.Err()
+}
+
+// blameFile returns the author date of every line in filename.
+func blameFile(ctx context.Context, filename string) ([]time.Time, error) {
+	cmd := exec.CommandContext(ctx, "git", "blame", "--line-porcelain", "--", filepath.Base(filename))
+	cmd.Dir = filepath.Dir(filename)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := stderr.String(); strings.Contains(msg, "no such path") || strings.Contains(msg, "not a git repository") {
+			// The file is not tracked by git.
+			return nil, nil
+		}
+		return nil, fmt.Errorf("git blame %v: %v: %s", filename, err, strings.TrimSpace(stderr.String()))
+	}
+	var dates []time.Time
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		// Each line is described by a set of headers, in a fixed order,
+		// followed by its contents which is preceded by a tab.
+		value, ok := strings.CutPrefix(sc.Text(), "author-time ")
+		if !ok {
+			continue
+		}
+		secs, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("git blame %v: invalid author-time: %q", filename, value)
+		}
+		dates = append(dates, time.Unix(secs, 0))
+	}
+	return dates, sc.Err()
+}
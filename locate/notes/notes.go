@@ -0,0 +1,182 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package notes provides support for locating notes, such as those
+// marked with TODO(owner) or BUG(owner), in go comments, along with the
+// declaration that they are associated with and, optionally, their age as
+// reported by git blame.
+package notes
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"cloudeng.io/go/locate"
+	"cloudeng.io/go/locate/locateutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// DefaultMarkers are the markers used when none are specified.
+var DefaultMarkers = []string{"TODO", "FIXME", "BUG"}
+
+// Note represents a single note.
+type Note struct {
+	// Marker is the marker for the note, eg. TODO.
+	Marker string
+	// Owner is the owner specified as MARKER(owner), if any.
+	Owner string `json:",omitempty"`
+	// Text is the text of the note, including any continuation lines.
+	Text string
+	// Package is the path of the package containing the note.
+	Package string
+	// Decl is the name of the declaration that the note is associated
+	// with, if any, see locateutil.CommentDecl.
+	Decl     string `json:",omitempty"`
+	Position token.Position
+	// Date is the author date of the line containing the note as
+	// reported by git blame, it is only set by Blame and is nil if the
+	// date is not known.
+	Date *time.Time `json:",omitempty"`
+}
+
+// Age returns the age of the note relative to now or zero if its Date is
+// not known.
+func (n Note) Age(now time.Time) time.Duration {
+	if n.Date == nil {
+		return 0
+	}
+	return now.Sub(*n.Date)
+}
+
+// Parser parses notes from comments.
+type Parser struct {
+	markers string
+	line    *regexp.Regexp
+}
+
+// NewParser returns a parser for the specified markers, DefaultMarkers
+// are used if none are specified. A note starts at the beginning of a
+// comment line with one of the markers, optionally followed by an owner in
+// parentheses and/or a colon, for example, a line that starts with the
+// text "BUG(owner): ...". A note continues until a blank line, the start
+// of another note or the end of the comment.
+func NewParser(markers ...string) *Parser {
+	if len(markers) == 0 {
+		markers = DefaultMarkers
+	}
+	quoted := make([]string, len(markers))
+	for i, m := range markers {
+		quoted[i] = regexp.QuoteMeta(m)
+	}
+	alt := strings.Join(quoted, "|")
+	return &Parser{
+		markers: alt,
+		line:    regexp.MustCompile(`^\s*(` + alt + `)(?:\(([^)]*)\))?(?::|\s|$)\s*(.*)$`),
+	}
+}
+
+// Pattern returns a regular expression, suitable for use with
+// locate.T.AddComments, that matches comments that contain a note.
+func (p *Parser) Pattern() string {
+	return `(?m)^\s*(` + p.markers + `)(?:\(|:|\s|$)`
+}
+
+// Parse returns the notes in the supplied comment group. The positions
+// of the notes are those of the line on which they start.
+func (p *Parser) Parse(fset *token.FileSet, cg *ast.CommentGroup) []Note {
+	var notes []Note
+	var current *Note
+	flush := func() {
+		if current != nil {
+			current.Text = strings.TrimSpace(current.Text)
+			notes = append(notes, *current)
+			current = nil
+		}
+	}
+	for _, c := range cg.List {
+		for _, line := range commentLines(c) {
+			if m := p.line.FindStringSubmatch(line.text); m != nil {
+				flush()
+				current = &Note{
+					Marker:   m[1],
+					Owner:    strings.TrimSpace(m[2]),
+					Text:     m[3],
+					Position: fset.PositionFor(line.pos, false),
+				}
+				continue
+			}
+			if current == nil {
+				continue
+			}
+			if len(strings.TrimSpace(line.text)) == 0 {
+				flush()
+				continue
+			}
+			current.Text += " " + strings.TrimSpace(line.text)
+		}
+	}
+	flush()
+	return notes
+}
+
+type commentLine struct {
+	text string
+	pos  token.Pos
+}
+
+// commentLines returns the lines of text in c, without the comment
+// markers, along with the position of the start of each line.
+func commentLines(c *ast.Comment) []commentLine {
+	if text, ok := strings.CutPrefix(c.Text, "//"); ok {
+		return []commentLine{{text: text, pos: c.Pos()}}
+	}
+	text := strings.TrimSuffix(strings.TrimPrefix(c.Text, "/*"), "*/")
+	var lines []commentLine
+	offset := token.Pos(len("/*"))
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, commentLine{
+			text: strings.TrimPrefix(strings.TrimSpace(line), "*"),
+			pos:  c.Pos() + offset,
+		})
+		offset += token.Pos(len(line) + 1)
+	}
+	return lines
+}
+
+// Find locates the notes with the specified markers in the specified
+// packages using a locate.T created with the supplied options. The notes
+// are returned in order of filename and then position within filename.
+// The locate.CacheDir option should not be used since notes are parsed
+// from the comments in packages that are loaded rather than cached.
+func Find(ctx context.Context, markers []string, pkgs []string, opts ...locate.Option) ([]Note, error) {
+	parser := NewParser(markers...)
+	locator := locate.New(opts...)
+	locator.AddPackages(pkgs...)
+	locator.AddComments(parser.Pattern())
+	if err := locator.Do(ctx); err != nil {
+		return nil, err
+	}
+	notes := []Note{}
+	locator.WalkComments(func(_, _ string, _ ast.Node, cg *ast.CommentGroup, pkg *packages.Package, file *ast.File) {
+		_, decl := locateutil.CommentDecl(pkg.Fset, file, cg)
+		for _, note := range parser.Parse(pkg.Fset, cg) {
+			note.Package = pkg.PkgPath
+			note.Decl = decl
+			notes = append(notes, note)
+		}
+	})
+	sort.SliceStable(notes, func(i, j int) bool {
+		a, b := notes[i].Position, notes[j].Position
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+	return notes, nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package notes_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudeng.io/go/locate/notes"
+)
+
+const here = "cloudeng.io/go/locate/notes/testdata/"
+
+func list(found []notes.Note) []string {
+	out := []string{}
+	for _, n := range found {
+		out = append(out, fmt.Sprintf("%v:%v: %v(%v) %q [%v]",
+			filepath.Base(n.Position.Filename), n.Position.Line, n.Marker, n.Owner, n.Text, n.Decl))
+	}
+	return out
+}
+
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+	found, err := notes.Find(ctx, nil, []string{here + "notes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := list(found), []string{
+		`notes.go:3: TODO(alice) "a package level note." []`,
+		`notes.go:8: FIXME() "a note that continues on the next line." [Fn]`,
+		`notes.go:13: BUG(bob) "within a function." [Fn]`,
+		`notes.go:14: TODO() "another note in the same comment." [Fn]`,
+		`notes.go:20: TODO(carol) "trailing note." [T.Method]`,
+		`notes.go:24: TODO(dave) "in a block comment." [V]`,
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+	if got, want := found[0].Package, here+"notes"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	found, err = notes.Find(ctx, []string{"XXX"}, []string{here + "notes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := list(found), []string{
+		// TODO is not a marker here and hence continues the note.
+		`notes.go:23: XXX() "not a default marker. TODO(dave): in a block comment." [V]`,
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestBlame(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+			"GIT_AUTHOR_DATE=2020-01-02T03:04:05Z", "GIT_COMMITTER_DATE=2020-01-02T03:04:05Z",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	filename := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(filename, []byte("package a\n\n// TODO: committed.\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	git("init", "-q")
+	git("add", "a.go")
+	git("commit", "-q", "-m", "initial")
+
+	found := []notes.Note{
+		{Marker: "TODO", Position: token.Position{Filename: filename, Line: 3}},
+	}
+	if err := notes.Blame(ctx, found); err != nil {
+		t.Fatal(err)
+	}
+	when := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if got, want := found[0].Date, when; got == nil || !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := found[0].Age(when.Add(time.Hour)), time.Hour; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Untracked files are ignored.
+	untracked := filepath.Join(dir, "b.go")
+	if err := os.WriteFile(untracked, []byte("package a\n\n// TODO: untracked.\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	found = []notes.Note{{Marker: "TODO", Position: token.Position{Filename: untracked, Line: 3}}}
+	if err := notes.Blame(ctx, found); err != nil {
+		t.Fatal(err)
+	}
+	if found[0].Date != nil {
+		t.Errorf("unexpected date: %v", found[0].Date)
+	}
+
+	// As are files that are not in a git repository.
+	outside := filepath.Join(t.TempDir(), "c.go")
+	if err := os.WriteFile(outside, []byte("package a\n\n// TODO: outside.\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	found = []notes.Note{{Marker: "TODO", Position: token.Position{Filename: outside, Line: 3}}}
+	if err := notes.Blame(ctx, found); err != nil {
+		t.Fatal(err)
+	}
+	if found[0].Date != nil {
+		t.Errorf("unexpected date: %v", found[0].Date)
+	}
+
+	found[0].Position.Filename = filepath.Join(dir, "missing", "missing.go")
+	if err := notes.Blame(ctx, found); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestNoteJSON(t *testing.T) {
+	n := notes.Note{Marker: "TODO", Text: "text"}
+	buf, err := json.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(buf), "Date") {
+		t.Errorf("unexpected Date in %s", buf)
+	}
+	when := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	n.Date = &when
+	buf, err = json.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(buf), `"Date":"2020-01-02T03:04:05Z"`) {
+		t.Errorf("missing Date in %s", buf)
+	}
+}
@@ -0,0 +1,26 @@
+// Package notes is used for testing.
+//
+// TODO(alice): a package level note.
+package notes
+
+// Fn is a function.
+//
+// FIXME: a note that continues
+// on the next line.
+//
+// Not part of the note.
+func Fn() {
+	// BUG(bob): within a function.
+	// TODO another note in the same comment.
+}
+
+type T struct{}
+
+// TODOs are not notes.
+func (T) Method() {} // TODO(carol): trailing note.
+
+/*
+XXX: not a default marker.
+TODO(dave): in a block comment.
+*/
+var V = 1
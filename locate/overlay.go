@@ -0,0 +1,82 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ReadOverlayFile reads an overlay in the JSON format used by
+// 'go build -overlay', ie. {"Replace": {"<file>": "<replacement>"}}, and
+// returns the contents of each replacement keyed by the absolute filename
+// of the file it replaces. Relative filenames are interpreted relative to
+// the current directory. Deleting files, via an empty replacement, is not
+// supported.
+func ReadOverlayFile(filename string) (map[string][]byte, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var overlay struct {
+		Replace map[string]string
+	}
+	if err := json.Unmarshal(buf, &overlay); err != nil {
+		return nil, fmt.Errorf("failed to parse overlay file %v: %v", filename, err)
+	}
+	contents := make(map[string][]byte, len(overlay.Replace))
+	for file, replacement := range overlay.Replace {
+		if len(replacement) == 0 {
+			return nil, fmt.Errorf("%v: deleting %v is not supported", filename, file)
+		}
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		buf, err := os.ReadFile(replacement)
+		if err != nil {
+			return nil, err
+		}
+		contents[abs] = buf
+	}
+	return contents, nil
+}
+
+// writeOverlayFile writes overlay to a temporary directory in the JSON
+// format, and with the replacement files, expected by the go command's
+// -overlay flag. It returns the name of the JSON file and a function that
+// removes the temporary directory.
+func writeOverlayFile(overlay map[string][]byte) (string, func(), error) {
+	nop := func() {}
+	dir, err := os.MkdirTemp("", "locate-overlay-")
+	if err != nil {
+		return "", nop, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	replace := make(map[string]string, len(overlay))
+	i := 0
+	for file, contents := range overlay {
+		replacement := filepath.Join(dir, fmt.Sprintf("%d-%s", i, filepath.Base(file)))
+		if err := os.WriteFile(replacement, contents, 0600); err != nil {
+			cleanup()
+			return "", nop, err
+		}
+		replace[file] = replacement
+		i++
+	}
+	buf, err := json.Marshal(struct{ Replace map[string]string }{replace})
+	if err != nil {
+		cleanup()
+		return "", nop, err
+	}
+	filename := filepath.Join(dir, "overlay.json")
+	if err := os.WriteFile(filename, buf, 0600); err != nil {
+		cleanup()
+		return "", nop, err
+	}
+	return filename, cleanup, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"cloudeng.io/go/locate"
+)
+
+const overlayContents = `package comments
+
+// Unsaved is only present in the overlay.
+func Unsaved() {}
+`
+
+func TestOverlay(t *testing.T) {
+	ctx := context.Background()
+	tmpdir := t.TempDir()
+	funcs, err := filepath.Abs(filepath.Join("testdata", "comments", "funcs.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacement := filepath.Join(tmpdir, "funcs.go")
+	if err := os.WriteFile(replacement, []byte(overlayContents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := json.Marshal(map[string]map[string]string{
+		"Replace": {funcs: replacement},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlayFile := filepath.Join(tmpdir, "overlay.json")
+	if err := os.WriteFile(overlayFile, buf, 0600); err != nil {
+		t.Fatal(err)
+	}
+	overlay, err := locate.ReadOverlayFile(overlayFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(overlay[funcs]), overlayContents; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	locator := locate.New(locate.Overlay(overlay))
+	locator.AddFunctions(here + "comments")
+	locator.AddComments("overlay")
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locate.Do: %v", err)
+	}
+	found := []string{}
+	locator.WalkLocations(func(loc locate.Location) {
+		found = append(found, loc.Name+"@"+filepath.Base(loc.Position.Filename))
+	})
+	if got, want := found, []string{
+		here + "comments.Commented@doc.go",
+		"overlay@funcs.go",
+		here + "comments.Unsaved@funcs.go",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestOverlayPackages(t *testing.T) {
+	ctx := context.Background()
+	// The unsaved package exists only in the overlay and hence must be
+	// found when ... patterns are expanded.
+	unsaved, err := filepath.Abs(filepath.Join("testdata", "comments", "unsaved", "unsaved.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlay := map[string][]byte{
+		unsaved: []byte("package unsaved\n\n// Unsaved is only present in the overlay.\nfunc Unsaved() {}\n"),
+	}
+	locator := locate.New(locate.Overlay(overlay))
+	locator.AddFunctions(here + "comments/unsaved.Unsaved$")
+	locator.AddPackages("./testdata/comments/...")
+	if err := locator.Do(ctx); err != nil {
+		t.Fatalf("locate.Do: %v", err)
+	}
+	if got, want := locator.Packages(), []string{here + "comments", here + "comments/unsaved"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := listFunctions(locator), []string{here + "comments/unsaved.Unsaved @ " + unsaved + ":4:1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
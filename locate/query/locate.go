@@ -0,0 +1,347 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cloudeng.io/go/locate"
+	"cloudeng.io/go/locate/locateutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// Result represents a single declaration that satisfies a query.
+type Result struct {
+	Kind Kind
+	// Name is the fully qualified name of the declaration as reported by
+	// locate.T.
+	Name     string
+	Package  string
+	Position token.Position
+	// Implements lists the interfaces, requested via implements:, that
+	// a method or type implements.
+	Implements []string `json:",omitempty"`
+}
+
+// candidate represents a located declaration before the query's filters
+// have been applied.
+type candidate struct {
+	Result
+	local string // the package local name
+	doc   *ast.CommentGroup
+	node  ast.Node // the declaration, used for comment: predicates
+	file  *ast.File
+	fn    *types.Func
+}
+
+// Locate evaluates the query using a locate.T created with the supplied
+// options. The packages specified via pkg: predicates are searched, or
+// pkgs if there are none. The results are returned in order of filename
+// and then position within filename. The locate.CacheDir option should not
+// be used since queries are evaluated against the packages that are loaded
+// rather than those that are cached.
+func (q *Query) Locate(ctx context.Context, pkgs []string, opts ...locate.Option) ([]Result, error) {
+	if len(q.Packages) > 0 {
+		pkgs = q.Packages
+	}
+	opts = append([]locate.Option{locate.IgnoreMissingFuctionsEtc()}, opts...)
+	if q.HasKind(Method) {
+		opts = append(opts, locate.IncludeMethods(true))
+	}
+	locator := locate.New(opts...)
+	if len(q.Implements) > 0 {
+		for _, ifc := range q.Implements {
+			locator.AddInterfaces(interfaceSpec(ifc))
+		}
+		locator.AddPackages(pkgs...)
+	} else {
+		q.addSpecs(locator, pkgs)
+	}
+	if err := locator.Do(ctx); err != nil {
+		return nil, err
+	}
+	results := []Result{}
+	for _, c := range q.candidates(locator) {
+		if q.matches(c) {
+			results = append(results, c.Result)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i].Position, results[j].Position
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+	return results, nil
+}
+
+// addSpecs adds the <package>.<regex> specs for the requested kinds,
+// go list expressions are added as is and hence the name predicate is
+// applied as a filter as well as being used in the specs.
+func (q *Query) addSpecs(locator *locate.T, pkgs []string) {
+	name := ".*"
+	if q.Name != nil {
+		name = q.Name.String()
+	}
+	specs := make([]string, len(pkgs))
+	for i, pkg := range pkgs {
+		specs[i] = pkg
+		if !locate.IsGoListPath(pkg) && !locate.IsExclusion(pkg) {
+			specs[i] = pkg + "." + name
+		}
+	}
+	if q.HasKind(Function) || q.HasKind(Method) {
+		fns := specs
+		if len(q.Signature) > 0 {
+			fns = make([]string, len(specs))
+			for i, spec := range specs {
+				fns[i] = spec
+				if !locate.IsExclusion(spec) {
+					fns[i] += ";" + q.Signature
+				}
+			}
+		}
+		locator.AddFunctions(fns...)
+	}
+	if q.HasKind(Interface) {
+		locator.AddInterfaces(specs...)
+	}
+	if q.HasKind(Type) {
+		locator.AddTypes(specs...)
+	}
+	if q.HasKind(Field) {
+		locator.AddFields(specs...)
+	}
+	if q.HasKind(Const) {
+		locator.AddConsts(specs...)
+	}
+	if q.HasKind(Var) {
+		locator.AddVars(specs...)
+	}
+}
+
+// interfaceSpec returns a spec that matches only the named interface
+// rather than all of those whose names contain it.
+func interfaceSpec(ifc string) string {
+	idx := strings.LastIndex(ifc, ".")
+	if idx < 0 {
+		return ifc
+	}
+	return ifc[:idx] + ".^" + regexp.QuoteMeta(ifc[idx+1:]) + "$"
+}
+
+func newCandidate(kind Kind, name string, pkg *packages.Package, file *ast.File, node ast.Node) candidate {
+	return candidate{
+		Result: Result{
+			Kind:     kind,
+			Name:     name,
+			Package:  pkg.PkgPath,
+			Position: pkg.Fset.PositionFor(node.Pos(), false),
+		},
+		local: strings.TrimPrefix(name, pkg.PkgPath+"."),
+		node:  node,
+		file:  file,
+	}
+}
+
+func (q *Query) candidates(locator *locate.T) []candidate {
+	var cs []candidate
+	if q.HasKind(Function) || q.HasKind(Method) {
+		locator.WalkFunctions(func(name string, pkg *packages.Package, file *ast.File, fn *types.Func, decl *ast.FuncDecl, implements []string) {
+			if decl == nil {
+				// Interface methods are located via kind:interface.
+				return
+			}
+			kind := Function
+			if fn.Type().(*types.Signature).Recv() != nil {
+				kind = Method
+			}
+			c := newCandidate(kind, name, pkg, file, decl)
+			c.local, c.doc, c.fn = fn.Name(), decl.Doc, fn
+			c.Implements = implements
+			cs = append(cs, c)
+		})
+	}
+	if len(q.Implements) > 0 && q.HasKind(Type) {
+		// Implementations are reported once per interface.
+		index := map[string]int{}
+		locator.WalkImplementations(func(impl locate.Implementation) {
+			if i, ok := index[impl.Type]; ok {
+				cs[i].Implements = append(cs[i].Implements, impl.Interface)
+				return
+			}
+			index[impl.Type] = len(cs)
+			c := newCandidate(Type, impl.Type, impl.Package, impl.File, impl.Decl)
+			c.Position = impl.Position
+			c.doc = typeDoc(impl.File, impl.Decl)
+			c.Implements = []string{impl.Interface}
+			cs = append(cs, c)
+		})
+	}
+	if len(q.Implements) > 0 {
+		return cs
+	}
+	if q.HasKind(Interface) {
+		locator.WalkInterfaces(func(name string, pkg *packages.Package, file *ast.File, decl *ast.TypeSpec, _ *types.Interface) {
+			c := newCandidate(Interface, name, pkg, file, decl)
+			c.doc = typeDoc(file, decl)
+			cs = append(cs, c)
+		})
+	}
+	if q.HasKind(Type) {
+		locator.WalkTypes(func(name string, pkg *packages.Package, file *ast.File, decl *ast.TypeSpec, _ *types.TypeName) {
+			c := newCandidate(Type, name, pkg, file, decl)
+			c.doc = typeDoc(file, decl)
+			cs = append(cs, c)
+		})
+	}
+	if q.HasKind(Field) {
+		locator.WalkFields(func(name string, pkg *packages.Package, file *ast.File, decl *ast.Field, field *types.Var, _ reflect.StructTag) {
+			c := newCandidate(Field, name, pkg, file, decl)
+			c.Position = pkg.Fset.PositionFor(field.Pos(), false)
+			c.doc = decl.Doc
+			cs = append(cs, c)
+		})
+	}
+	if q.HasKind(Const) {
+		locator.WalkConsts(func(name string, pkg *packages.Package, file *ast.File, decl *ast.ValueSpec, obj *types.Const) {
+			cs = append(cs, valueCandidate(Const, name, pkg, file, decl, obj))
+		})
+	}
+	if q.HasKind(Var) {
+		locator.WalkVars(func(name string, pkg *packages.Package, file *ast.File, decl *ast.ValueSpec, obj *types.Var) {
+			cs = append(cs, valueCandidate(Var, name, pkg, file, decl, obj))
+		})
+	}
+	return cs
+}
+
+func valueCandidate(kind Kind, name string, pkg *packages.Package, file *ast.File, decl *ast.ValueSpec, obj types.Object) candidate {
+	c := newCandidate(kind, name, pkg, file, decl)
+	c.Position = pkg.Fset.PositionFor(obj.Pos(), false)
+	c.doc = decl.Doc
+	if c.doc == nil {
+		c.doc = genDeclDoc(file, decl)
+	}
+	return c
+}
+
+func typeDoc(file *ast.File, decl *ast.TypeSpec) *ast.CommentGroup {
+	if decl == nil {
+		return nil
+	}
+	if decl.Doc != nil {
+		return decl.Doc
+	}
+	return genDeclDoc(file, decl)
+}
+
+// genDeclDoc returns the documentation of the ungrouped declaration that
+// contains spec, since the parser associates the documentation for such
+// declarations with the ast.GenDecl rather than the spec.
+func genDeclDoc(file *ast.File, spec ast.Spec) *ast.CommentGroup {
+	if file == nil {
+		return nil
+	}
+	for _, d := range file.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Lparen.IsValid() || len(gd.Specs) != 1 || gd.Specs[0] != spec {
+			continue
+		}
+		return gd.Doc
+	}
+	return nil
+}
+
+func (q *Query) matches(c candidate) bool {
+	if !q.HasKind(c.Kind) {
+		return false
+	}
+	if q.Name != nil && !q.Name.MatchString(c.local) {
+		return false
+	}
+	if len(q.Receiver) > 0 && (c.fn == nil || !receiverMatches(c.fn, q.Receiver)) {
+		return false
+	}
+	for _, ifc := range q.Implements {
+		if !contains(c.Implements, ifc) {
+			return false
+		}
+	}
+	if q.File != nil && !q.File.MatchString(c.Position.Filename) {
+		return false
+	}
+	switch q.Doc {
+	case DocMissing:
+		if c.doc != nil && len(strings.TrimSpace(c.doc.Text())) > 0 {
+			return false
+		}
+	case DocPresent:
+		if c.doc == nil || len(strings.TrimSpace(c.doc.Text())) == 0 {
+			return false
+		}
+	case DocMatches:
+		if c.doc == nil || !q.DocRegexp.MatchString(c.doc.Text()) {
+			return false
+		}
+	}
+	if q.Comment != nil && !q.commentMatches(c) {
+		return false
+	}
+	return true
+}
+
+// receiverMatches returns true if the receiver of fn, qualified relative to
+// its package, matches typ as per locateutil.Receiver.
+func receiverMatches(fn *types.Func, typ string) bool {
+	if fn.Pkg() == nil {
+		return false
+	}
+	return locateutil.Receiver(qualified(fn.Pkg().Path(), typ))(fn)
+}
+
+func qualified(pkg, typ string) string {
+	if ptr, ok := strings.CutPrefix(typ, "*"); ok {
+		return "*" + qualified(pkg, ptr)
+	}
+	if strings.Contains(typ, ".") {
+		return typ
+	}
+	return pkg + "." + typ
+}
+
+func contains(strs []string, s string) bool {
+	for _, str := range strs {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
+
+// commentMatches returns true if any comment that documents or is
+// contained within the candidate matches the query's comment predicate.
+func (q *Query) commentMatches(c candidate) bool {
+	if c.doc != nil && q.Comment.MatchString(c.doc.Text()) {
+		return true
+	}
+	if c.file == nil || c.node == nil {
+		return false
+	}
+	for _, cg := range c.file.Comments {
+		if cg.Pos() >= c.node.Pos() && cg.End() <= c.node.End() && q.Comment.MatchString(cg.Text()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package query provides a simple query language for locating go
+// declarations that combines predicates on their kind, name, receiver,
+// the interfaces they implement, their signature, documentation, file
+// and comments. A query is compiled into requests on a locate.T followed
+// by filters on the results. For example:
+//
+//	kind:method receiver:*Server implements:io.Closer name:/^Handle/ pkg:./api/... doc:missing
+//
+// A query consists of white space separated predicates of the form
+// <key>:<value> where a value may be a literal, a "quoted" literal or a
+// /regular expression/. All predicates must be satisfied for a
+// declaration to be included in the results, except that repeated kind,
+// pkg and implements predicates, and comma separated values for kind, are
+// alternatives. The supported predicates are:
+//
+//	kind:<kind>         one of function, method, interface, type, field, const or var,
+//	                    the default is function and method, or method only
+//	                    when implements: is specified.
+//	name:<name>         the package local name of the declaration, <type>.<field>
+//	                    for fields.
+//	receiver:<type>     the receiver type of a method, with the same semantics
+//	                    as locateutil.Receiver, ie. Server matches both value
+//	                    and pointer receivers whereas *Server matches only
+//	                    pointer receivers.
+//	implements:<ifc>    types that implement the fully qualified interface,
+//	                    eg. io.Closer, or the methods of such types.
+//	signature:<preds>   signature predicates as per locateutil.ParseSignature,
+//	                    eg. signature:"first=context.Context;results=error".
+//	doc:<doc>           one of missing, present or a regular expression to be
+//	                    matched against the documentation.
+//	pkg:<package>       a package path or go list expression, the packages
+//	                    supplied to Locate are used if none are specified.
+//	file:<file>         the filename containing the declaration.
+//	comment:<text>      the text of any comment in, or documenting, the
+//	                    declaration.
+//
+// Literal values are matched exactly, except for file and comment which
+// are matched as substrings.
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Kind represents the kind of a declaration.
+type Kind string
+
+const (
+	// Function matches functions, but not methods.
+	Function Kind = "function"
+	// Method matches methods.
+	Method Kind = "method"
+	// Interface matches interface types.
+	Interface Kind = "interface"
+	// Type matches type declarations.
+	Type Kind = "type"
+	// Field matches struct fields.
+	Field Kind = "field"
+	// Const matches constants.
+	Const Kind = "const"
+	// Var matches variables.
+	Var Kind = "var"
+)
+
+var allKinds = []Kind{Function, Method, Interface, Type, Field, Const, Var}
+
+// DocPredicate represents a condition on the documentation of a
+// declaration.
+type DocPredicate int
+
+const (
+	// DocAny matches any declaration.
+	DocAny DocPredicate = iota
+	// DocMissing matches declarations with no documentation.
+	DocMissing
+	// DocPresent matches declarations with documentation.
+	DocPresent
+	// DocMatches matches declarations whose documentation matches
+	// Query.DocRegexp.
+	DocMatches
+)
+
+// Query represents a parsed query.
+type Query struct {
+	Kinds      []Kind
+	Name       *regexp.Regexp
+	Receiver   string
+	Implements []string
+	Signature  string
+	Doc        DocPredicate
+	DocRegexp  *regexp.Regexp
+	Packages   []string
+	File       *regexp.Regexp
+	Comment    *regexp.Regexp
+}
+
+// Parse parses the supplied query.
+func Parse(query string) (*Query, error) {
+	terms, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+	q := &Query{}
+	for _, term := range terms {
+		if err := q.apply(term); err != nil {
+			return nil, err
+		}
+	}
+	if len(q.Kinds) == 0 {
+		q.Kinds = []Kind{Function, Method}
+		if len(q.Implements) > 0 {
+			q.Kinds = []Kind{Method}
+		}
+	}
+	if len(q.Implements) > 0 {
+		for _, k := range q.Kinds {
+			if k != Method && k != Type {
+				return nil, fmt.Errorf("implements: is only supported for methods and types, not %v", k)
+			}
+		}
+	}
+	if len(q.Signature) > 0 && !q.HasKind(Function) && !q.HasKind(Method) {
+		return nil, fmt.Errorf("signature: is only supported for functions and methods")
+	}
+	return q, nil
+}
+
+// HasKind returns true if the query includes the specified kind.
+func (q *Query) HasKind(kind Kind) bool {
+	for _, k := range q.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+type term struct {
+	key, value string
+	regexp     bool
+}
+
+func (t term) String() string {
+	if t.regexp {
+		return t.key + ":/" + t.value + "/"
+	}
+	return t.key + ":" + t.value
+}
+
+// compile returns a regular expression for the term's value. Literals
+// are matched exactly, or as substrings if substring is set.
+func (t term) compile(substring bool) (*regexp.Regexp, error) {
+	expr := t.value
+	if !t.regexp {
+		expr = regexp.QuoteMeta(expr)
+		if !substring {
+			expr = "^" + expr + "$"
+		}
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", t, err)
+	}
+	return re, nil
+}
+
+func (q *Query) apply(t term) error {
+	var err error
+	switch t.key {
+	case "kind":
+		if t.regexp {
+			return fmt.Errorf("%v: regular expressions are not supported for kind", t)
+		}
+		for _, k := range strings.Split(t.value, ",") {
+			kind, err := parseKind(k)
+			if err != nil {
+				return err
+			}
+			q.Kinds = append(q.Kinds, kind)
+		}
+	case "name":
+		q.Name, err = t.compile(false)
+	case "receiver":
+		if t.regexp {
+			return fmt.Errorf("%v: regular expressions are not supported for receiver", t)
+		}
+		q.Receiver = t.value
+	case "implements":
+		q.Implements = append(q.Implements, t.value)
+	case "signature":
+		q.Signature = t.value
+	case "doc":
+		switch {
+		case t.regexp:
+			q.Doc = DocMatches
+			q.DocRegexp, err = t.compile(true)
+		case t.value == "missing":
+			q.Doc = DocMissing
+		case t.value == "present":
+			q.Doc = DocPresent
+		default:
+			return fmt.Errorf("%v: must be missing, present or a /regular expression/", t)
+		}
+	case "pkg":
+		q.Packages = append(q.Packages, t.value)
+	case "file":
+		q.File, err = t.compile(true)
+	case "comment":
+		q.Comment, err = t.compile(true)
+	default:
+		return fmt.Errorf("unsupported predicate: %q", t.key)
+	}
+	return err
+}
+
+func parseKind(k string) (Kind, error) {
+	if k == "func" {
+		return Function, nil
+	}
+	for _, kind := range allKinds {
+		if string(kind) == k {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported kind: %q", k)
+}
+
+// tokenize splits a query into <key>:<value> terms, allowing for white
+// space within "quoted" literals and /regular expressions/. A / may be
+// included in a regular expression as \/.
+func tokenize(query string) ([]term, error) {
+	var terms []term
+	rest := strings.TrimSpace(query)
+	for len(rest) > 0 {
+		key, value, ok := strings.Cut(rest, ":")
+		if !ok || len(key) == 0 || strings.ContainsAny(key, " \t\n") {
+			return nil, fmt.Errorf("invalid predicate: %q, must be of the form <key>:<value>", firstField(rest))
+		}
+		t := term{key: key}
+		switch {
+		case strings.HasPrefix(value, "/"):
+			end := closing(value[1:], '/')
+			if end < 0 {
+				return nil, fmt.Errorf("%v: unterminated regular expression", key)
+			}
+			t.value = strings.ReplaceAll(value[1:end+1], `\/`, "/")
+			t.regexp = true
+			rest = value[end+2:]
+		case strings.HasPrefix(value, `"`):
+			end := closing(value[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("%v: unterminated quoted string", key)
+			}
+			t.value = strings.ReplaceAll(value[1:end+1], `\"`, `"`)
+			rest = value[end+2:]
+		default:
+			t.value = firstField(value)
+			rest = value[len(t.value):]
+		}
+		if len(rest) > 0 && !strings.ContainsAny(rest[:1], " \t\n") {
+			return nil, fmt.Errorf("%v: missing white space after value", key)
+		}
+		if len(t.value) == 0 {
+			return nil, fmt.Errorf("%v: missing value", key)
+		}
+		terms = append(terms, t)
+		rest = strings.TrimSpace(rest)
+	}
+	return terms, nil
+}
+
+// closing returns the index of the first unescaped delim in s.
+func closing(s string, delim byte) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case delim:
+			return i
+		}
+	}
+	return -1
+}
+
+func firstField(s string) string {
+	if idx := strings.IndexAny(s, " \t\n"); idx >= 0 {
+		return s[:idx]
+	}
+	return s
+}
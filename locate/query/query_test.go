@@ -0,0 +1,166 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package query_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloudeng.io/go/locate/query"
+)
+
+const api = "cloudeng.io/go/locate/query/testdata/api"
+
+func TestParse(t *testing.T) {
+	q, err := query.Parse(`kind:method,type receiver:*Server implements:io.Closer name:/^Handle/ pkg:./api/... doc:missing file:api.go comment:"a deadline" signature:"first=context.Context;results=error"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Kinds, []query.Kind{query.Method, query.Type}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := q.Receiver, "*Server"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := q.Implements, []string{"io.Closer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := q.Name.String(), "^Handle"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := q.Packages, []string{"./api/..."}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := q.Doc, query.DocMissing; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := q.File.String(), `api\.go`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := q.Comment.String(), "a deadline"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := q.Signature, "first=context.Context;results=error"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	q, err = query.Parse(`name:Close doc:/with \/ slash/`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Kinds, []query.Kind{query.Function, query.Method}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := q.Name.String(), "^Close$"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := q.DocRegexp.String(), "with / slash"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, tc := range []struct {
+		query, err string
+	}{
+		{"kind", "invalid predicate"},
+		{"kind:struct", "unsupported kind"},
+		{"size:10", "unsupported predicate"},
+		{"name:/(/", "missing closing"},
+		{"name:/abc", "unterminated regular expression"},
+		{`comment:"abc`, "unterminated quoted string"},
+		{`name:/a/b`, "missing white space"},
+		{"doc:sometimes", "must be missing, present"},
+		{"kind:field implements:io.Closer", "only supported for methods and types"},
+		{"kind:type signature:variadic", "only supported for functions and methods"},
+	} {
+		_, err := query.Parse(tc.query)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v: unexpected or missing error: %v", tc.query, err)
+		}
+	}
+}
+
+func locate(t *testing.T, q string) []string {
+	parsed, err := query.Parse(q)
+	if err != nil {
+		t.Fatalf("%v: %v", q, err)
+	}
+	results, err := parsed.Locate(context.Background(), []string{api})
+	if err != nil {
+		t.Fatalf("%v: %v", q, err)
+	}
+	out := []string{}
+	for _, r := range results {
+		line := fmt.Sprintf("%v %v @ %v:%v", r.Kind, strings.ReplaceAll(r.Name, api, "api"),
+			filepath.Base(r.Position.Filename), r.Position.Line)
+		if len(r.Implements) > 0 {
+			line += fmt.Sprintf(" %v", r.Implements)
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+func TestLocate(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{"name:/^Handle/", []string{
+			"method (*api.Server).HandleGet @ api.go:14",
+			"method (*api.Server).HandlePut @ api.go:18",
+			"method (api.Server).HandleValue @ api.go:23",
+			"function api.Handle @ api.go:36",
+		}},
+		{"kind:method receiver:*Server name:/^Handle/ doc:missing", []string{
+			"method (*api.Server).HandlePut @ api.go:18",
+		}},
+		{"kind:method receiver:Server name:/^Handle/ doc:present", []string{
+			"method (*api.Server).HandleGet @ api.go:14",
+		}},
+		{"implements:io.Closer", []string{
+			"method (*api.Server).HandleGet @ api.go:14 [io.Closer]",
+			"method (*api.Server).HandlePut @ api.go:18 [io.Closer]",
+			"method (api.Server).HandleValue @ api.go:23 [io.Closer]",
+			"method (*api.Server).Close @ api.go:25 [io.Closer]",
+			"method (*api.Client).Close @ api.go:31 [io.Closer]",
+		}},
+		{"kind:method receiver:*Server implements:io.Closer name:/^Handle/ doc:missing", []string{
+			"method (*api.Server).HandlePut @ api.go:18 [io.Closer]",
+		}},
+		{"kind:type implements:io.Closer", []string{
+			"type api.Server @ api.go:7 [io.Closer]",
+			"type api.Client @ api.go:29 [io.Closer]",
+		}},
+		{`signature:"first=context.Context;results=error" kind:function`, []string{
+			"function api.Handle @ api.go:36",
+		}},
+		{`comment:deadline`, []string{
+			"method (*api.Server).HandlePut @ api.go:18",
+		}},
+		{"kind:interface,type doc:/an interface/", []string{
+			"interface api.Handler @ api.go:41",
+			"type api.Handler @ api.go:41",
+		}},
+		{"kind:field doc:missing", []string{
+			"field api.Server.Timeout @ api.go:10",
+		}},
+		{"kind:const,var doc:missing", []string{
+			"const api.MinSize @ api.go:48",
+			"var api.ErrNotFound @ api.go:51",
+		}},
+		{"kind:const file:/other\\.go$/", []string{}},
+		{"kind:type name:Server pkg:" + api, []string{
+			"type api.Server @ api.go:7",
+		}},
+	} {
+		if got, want := locate(t, tc.query), tc.want; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %#v, want %#v", tc.query, got, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package api is used for testing queries.
+package api
+
+import "context"
+
+// Server is documented.
+type Server struct {
+	// Addr is documented.
+	Addr    string
+	Timeout int
+}
+
+// HandleGet is documented.
+func (s *Server) HandleGet(ctx context.Context, path string) error {
+	return nil
+}
+
+func (s *Server) HandlePut(ctx context.Context, path string) error {
+	// a comment mentioning a deadline.
+	return nil
+}
+
+func (s Server) HandleValue() {}
+
+func (s *Server) Close() error {
+	return nil
+}
+
+type Client struct{}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+// Handle is a function, not a method.
+func Handle(ctx context.Context) error {
+	return nil
+}
+
+// Handler is an interface.
+type Handler interface {
+	Handle(ctx context.Context) error
+}
+
+const (
+	// MaxSize is documented.
+	MaxSize = 10
+	MinSize = 1
+)
+
+var ErrNotFound = context.Canceled
@@ -0,0 +1,225 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"cloudeng.io/sync/errgroup"
+	"golang.org/x/tools/go/packages"
+)
+
+// Reference represents a use, typically a call, of a located function,
+// interface method or interface implementation.
+type Reference struct {
+	// Function is the fully qualified name of the function or method
+	// that is referred to.
+	Function string
+	// Interface is set to the interface whose method is used when
+	// the reference is to an implementation of that interface method
+	// rather than to the implementation itself.
+	Interface string
+	// Call is true if the reference is the function of a call expression.
+	Call bool
+	// Caller is the fully qualified name of the function that contains the
+	// reference and Decl its declaration. Both are empty for references
+	// that are outside of any function, eg. in a variable declaration.
+	Caller   string
+	Decl     *ast.FuncDecl
+	Package  *packages.Package
+	File     *ast.File
+	Ident    *ast.Ident
+	Position token.Position
+}
+
+// targets records the functions and interface methods whose uses are
+// to be found.
+type targets struct {
+	// Indexed by located function or method.
+	functions map[*types.Func]string
+	// Indexed by the method of a located interface, records the
+	// fully qualified names of the interfaces that contain it, which
+	// may be more than one due to embedding.
+	interfaceMethods map[*types.Func][]string
+	// Indexed by interface and method name, the located methods that
+	// implement that interface.
+	implementations map[string]map[string][]string
+}
+
+func (t *T) referenceTargets() targets {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tg := targets{
+		functions:        map[*types.Func]string{},
+		interfaceMethods: map[*types.Func][]string{},
+		implementations:  map[string]map[string][]string{},
+	}
+	for name, fd := range t.functions {
+		tg.functions[fd.Type] = name
+		for _, ifc := range fd.implements {
+			if tg.implementations[ifc] == nil {
+				tg.implementations[ifc] = map[string][]string{}
+			}
+			tg.implementations[ifc][fd.Type.Name()] = append(tg.implementations[ifc][fd.Type.Name()], name)
+		}
+	}
+	for name, ifc := range t.interfaces {
+		for i := 0; i < ifc.ifc.NumMethods(); i++ {
+			m := ifc.ifc.Method(i)
+			tg.interfaceMethods[m] = append(tg.interfaceMethods[m], name)
+		}
+	}
+	for _, ifcs := range tg.interfaceMethods {
+		sort.Strings(ifcs)
+	}
+	for _, byMethod := range tg.implementations {
+		for _, impls := range byMethod {
+			sort.Strings(impls)
+		}
+	}
+	return tg
+}
+
+func (t *T) findReferences(ctx context.Context, callers []string) error {
+	tg := t.referenceTargets()
+	group, ctx := errgroup.WithContext(ctx)
+	group = errgroup.WithConcurrency(group, t.options.concurrency)
+	for _, pkg := range callers {
+		pkg := pkg
+		group.GoContext(ctx, func() error {
+			return t.findReferencesInPackage(ctx, pkg, tg)
+		})
+	}
+	return group.Wait()
+}
+
+func (t *T) findReferencesInPackage(_ context.Context, pkgPath string, tg targets) error {
+	pkg, err := t.lookupTypedPackage(pkgPath, "locating references")
+	if pkg == nil {
+		return err
+	}
+	for _, file := range pkg.Syntax {
+		if t.loader.isExcluded(pkg.Fset.PositionFor(file.Pos(), false).Filename) {
+			continue
+		}
+		calls := callIdents(file)
+		ast.Inspect(file, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			fn, ok := pkg.TypesInfo.Uses[id].(*types.Func)
+			if !ok {
+				return true
+			}
+			fn = fn.Origin()
+			ref := Reference{
+				Call:     calls[id],
+				Package:  pkg,
+				File:     file,
+				Ident:    id,
+				Position: pkg.Fset.PositionFor(id.Pos(), false),
+			}
+			ref.Decl, ref.Caller = enclosingFunc(pkg, file, id.Pos())
+			if name, ok := tg.functions[fn]; ok {
+				ref.Function = name
+				t.addReference(ref)
+			}
+			if ifcs, ok := tg.interfaceMethods[fn]; ok {
+				// Record the use of the interface method and resolve it
+				// to all of the located implementations.
+				ref.Function = fn.FullName()
+				t.addReference(ref)
+				for _, ifc := range ifcs {
+					for _, impl := range tg.implementations[ifc][fn.Name()] {
+						ref.Function, ref.Interface = impl, ifc
+						t.addReference(ref)
+					}
+				}
+			}
+			return true
+		})
+	}
+	return nil
+}
+
+// callIdents returns the identifiers that are used as the function in
+// call expressions.
+func callIdents(file *ast.File) map[*ast.Ident]bool {
+	calls := map[*ast.Ident]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		fun := ast.Unparen(call.Fun)
+		switch f := fun.(type) {
+		case *ast.IndexExpr:
+			fun = f.X
+		case *ast.IndexListExpr:
+			fun = f.X
+		}
+		switch f := fun.(type) {
+		case *ast.Ident:
+			calls[f] = true
+		case *ast.SelectorExpr:
+			calls[f.Sel] = true
+		}
+		return true
+	})
+	return calls
+}
+
+func enclosingFunc(pkg *packages.Package, file *ast.File, pos token.Pos) (*ast.FuncDecl, string) {
+	for _, decl := range file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || pos < fd.Pos() || pos >= fd.End() {
+			continue
+		}
+		if fn, ok := pkg.TypesInfo.Defs[fd.Name].(*types.Func); ok {
+			return fd, fn.FullName()
+		}
+		return fd, fd.Name.Name
+	}
+	return nil, ""
+}
+
+func (t *T) addReference(ref Reference) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.references = append(t.references, ref)
+	t.dirty[ref.Position.Filename] |= HasReference
+	t.trace("reference: %v @ %v\n", ref.Function, ref.Position)
+}
+
+// WalkReferences calls the supplied function for every reference to a
+// located function, interface method or implementation found in the
+// packages specified via AddCallers. The function is called in order of
+// filename and then position within filename.
+func (t *T) WalkReferences(fn func(ref Reference)) {
+	t.mu.Lock()
+	refs := append([]Reference{}, t.references...)
+	t.mu.Unlock()
+	sort.SliceStable(refs, func(i, j int) bool {
+		a, b := refs[i].Position, refs[j].Position
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		if refs[i].Function != refs[j].Function {
+			return refs[i].Function < refs[j].Function
+		}
+		return refs[i].Interface < refs[j].Interface
+	})
+	for _, ref := range refs {
+		fn(ref)
+	}
+}
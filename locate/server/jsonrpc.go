@@ -0,0 +1,185 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// The JSON-RPC 2.0 error codes used by the server.
+const (
+	ParseError     = -32700
+	InvalidRequest = -32600
+	MethodNotFound = -32601
+	InvalidParams  = -32602
+	InternalError  = -32603
+)
+
+// Call represents a JSON-RPC 2.0 request. The supported methods are
+// interfaces, functions, comments, directives, query, reload and packages,
+// which correspond to the methods of Server, and the parameters are
+// specified as a Request.
+type Call struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Error represents a JSON-RPC 2.0 error.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v (%v)", e.Message, e.Code)
+}
+
+// Reply represents a JSON-RPC 2.0 response.
+type Reply struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  *Response       `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+func (s *Server) method(name string) func(context.Context, Request) (*Response, error) {
+	switch name {
+	case "interfaces":
+		return s.Interfaces
+	case "functions":
+		return s.Functions
+	case "comments":
+		return s.Comments
+	case "directives":
+		return s.Directives
+	case "query":
+		return s.Query
+	case "reload":
+		return s.Reload
+	case "packages":
+		return s.Packages
+	}
+	return nil
+}
+
+// Handle processes a single JSON-RPC 2.0 request. It returns nil for
+// notifications, ie. requests without an ID, since they do not receive a
+// reply.
+func (s *Server) Handle(ctx context.Context, data []byte) *Reply {
+	var call Call
+	if err := json.Unmarshal(data, &call); err != nil {
+		return errorReply(nil, ParseError, err)
+	}
+	reply := s.call(ctx, call)
+	if len(call.ID) == 0 {
+		return nil
+	}
+	return reply
+}
+
+func (s *Server) call(ctx context.Context, call Call) *Reply {
+	if call.JSONRPC != "2.0" {
+		return errorReply(call.ID, InvalidRequest, fmt.Errorf("unsupported jsonrpc version: %q", call.JSONRPC))
+	}
+	fn := s.method(call.Method)
+	if fn == nil {
+		return errorReply(call.ID, MethodNotFound, fmt.Errorf("unsupported method: %q", call.Method))
+	}
+	var req Request
+	if len(call.Params) > 0 {
+		if err := json.Unmarshal(call.Params, &req); err != nil {
+			return errorReply(call.ID, InvalidParams, err)
+		}
+	}
+	resp, err := fn(ctx, req)
+	if err != nil {
+		return errorReply(call.ID, InternalError, err)
+	}
+	return &Reply{JSONRPC: "2.0", ID: call.ID, Result: resp}
+}
+
+func errorReply(id json.RawMessage, code int, err error) *Reply {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &Reply{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   &Error{Code: code, Message: err.Error()},
+	}
+}
+
+// Serve reads newline delimited JSON-RPC 2.0 requests from rd and writes
+// a newline delimited reply for each of them to wr. Requests are processed
+// concurrently and hence replies may be written in a different order to
+// that of the requests. Serve returns when rd is exhausted and all
+// outstanding requests have been replied to.
+func (s *Server) Serve(ctx context.Context, rd io.Reader, wr io.Writer) error {
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		enc = json.NewEncoder(wr)
+		// Only the first error encountered when writing is reported.
+		werr error
+	)
+	for sc.Scan() {
+		line := append([]byte{}, sc.Bytes()...)
+		if len(line) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reply := s.Handle(ctx, line)
+			if reply == nil {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err := enc.Encode(reply); err != nil && werr == nil {
+				werr = err
+			}
+		}()
+	}
+	wg.Wait()
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return werr
+}
+
+// ServeHTTP implements http.Handler for JSON-RPC 2.0 requests sent as the
+// body of POST requests.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reply := s.Handle(r.Context(), data)
+	if reply == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(reply); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
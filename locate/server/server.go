@@ -0,0 +1,253 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package server provides support for answering repeated requests to
+// locate interfaces, functions, comments, directives, references and query
+// results using packages that are loaded once and then shared by all
+// requests, see locate.Snapshot. The packages are reloaded only when
+// requested, typically after files have been edited. Requests may be made
+// directly via the methods of Server or as JSON-RPC 2.0 calls over a
+// stream, such as stdin/stdout, or HTTP.
+package server
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"regexp"
+	"strings"
+
+	"cloudeng.io/go/locate"
+	"cloudeng.io/go/locate/query"
+	"golang.org/x/tools/go/packages"
+)
+
+// Request represents the parameters for a request.
+type Request struct {
+	// Packages are the packages to be searched, specified as for
+	// locate.T.AddPackages. The packages supplied to New are used if
+	// none are specified.
+	Packages []string `json:",omitempty"`
+	// Spec is interpreted according to the request: an interface spec
+	// for Interfaces, a regular expression for the names of functions for
+	// Functions, a regular expression for Comments and Directives and a
+	// query, see cloudeng.io/go/locate/query, for Query.
+	Spec string `json:",omitempty"`
+	// Signature restricts Functions to those whose signatures satisfy
+	// these predicates, see locateutil.ParseSignature.
+	Signature string `json:",omitempty"`
+	// References requests that the references to the functions, interface
+	// methods and implementations located by Interfaces or Functions be
+	// located in the requested packages.
+	References bool `json:",omitempty"`
+	// Force requests that Reload reloads all packages, regardless of
+	// whether any have changed.
+	Force bool `json:",omitempty"`
+}
+
+// Reference represents a use of a located function, interface method or
+// implementation, see locate.Reference.
+type Reference struct {
+	Function  string
+	Interface string `json:",omitempty"`
+	Call      bool
+	Caller    string `json:",omitempty"`
+	Position  token.Position
+}
+
+// Response represents the results of a request.
+type Response struct {
+	Locations  []locate.Location `json:",omitempty"`
+	References []Reference       `json:",omitempty"`
+	Results    []query.Result    `json:",omitempty"`
+	// Changed lists the packages that were found to have changed by Reload.
+	Changed []string `json:",omitempty"`
+	// Packages lists the packages that are currently loaded, it is set by
+	// Reload and Packages.
+	Packages []string `json:",omitempty"`
+	// Diagnostics lists the errors for packages that failed to load or
+	// type check when the locate.TolerateErrors option is in effect.
+	Diagnostics []string `json:",omitempty"`
+}
+
+// Server answers requests using packages obtained from a locate.Snapshot.
+type Server struct {
+	snapshot *locate.Snapshot
+	packages []string
+	opts     []locate.Option
+}
+
+// New returns a new Server that searches the specified packages by
+// default. The supplied options are used for the snapshot and for every
+// request.
+func New(pkgs []string, opts ...locate.Option) *Server {
+	s := &Server{
+		snapshot: locate.NewSnapshot(opts...),
+		packages: pkgs,
+	}
+	s.opts = append(append([]locate.Option{}, opts...), locate.UseSnapshot(s.snapshot))
+	return s
+}
+
+// Snapshot returns the snapshot used by the server.
+func (s *Server) Snapshot() *locate.Snapshot {
+	return s.snapshot
+}
+
+// Load loads the server's default packages so that subsequent requests
+// for them are answered without delay.
+func (s *Server) Load(ctx context.Context) error {
+	locator := s.newLocator(locate.RequireTypes())
+	locator.AddPackages(s.packages...)
+	return locator.Do(ctx)
+}
+
+func (s *Server) newLocator(opts ...locate.Option) *locate.T {
+	return locate.New(append(append([]locate.Option{}, s.opts...), opts...)...)
+}
+
+func (s *Server) packagesFor(req Request) []string {
+	if len(req.Packages) > 0 {
+		return req.Packages
+	}
+	return s.packages
+}
+
+// Interfaces locates the interfaces that match req.Spec and their
+// implementations in the requested packages.
+func (s *Server) Interfaces(ctx context.Context, req Request) (*Response, error) {
+	if len(req.Spec) == 0 {
+		return nil, fmt.Errorf("an interface spec must be specified")
+	}
+	pkgs := s.packagesFor(req)
+	locator := s.newLocator()
+	locator.AddPackages(pkgs...)
+	locator.AddInterfaces(req.Spec)
+	if req.References {
+		locator.AddCallers(pkgs...)
+	}
+	if err := locator.Do(ctx); err != nil {
+		return nil, err
+	}
+	return newResponse(locator, func(locate.Location) bool { return true }), nil
+}
+
+// Functions locates the functions and methods in the requested packages
+// whose package local names match the regular expression req.Spec.
+func (s *Server) Functions(ctx context.Context, req Request) (*Response, error) {
+	re, err := regexp.Compile(req.Spec)
+	if err != nil {
+		return nil, err
+	}
+	pkgs := s.packagesFor(req)
+	locator := s.newLocator(locate.IncludeMethods(true), locate.IgnoreMissingFuctionsEtc())
+	for _, pkg := range pkgs {
+		if len(req.Signature) > 0 && !locate.IsExclusion(pkg) {
+			pkg += ";" + req.Signature
+		}
+		locator.AddFunctions(pkg)
+	}
+	if req.References {
+		locator.AddCallers(pkgs...)
+	}
+	if err := locator.Do(ctx); err != nil {
+		return nil, err
+	}
+	resp := newResponse(locator, func(loc locate.Location) bool {
+		return loc.Kind == locate.HasFunction && matchesName(re, loc.Name)
+	})
+	refs := resp.References[:0]
+	for _, ref := range resp.References {
+		if matchesName(re, ref.Function) {
+			refs = append(refs, ref)
+		}
+	}
+	resp.References = refs
+	return resp, nil
+}
+
+// matchesName returns true if the package local component of the
+// fully qualified function or method name matches re.
+func matchesName(re *regexp.Regexp, name string) bool {
+	return re.MatchString(name[strings.LastIndex(name, ".")+1:])
+}
+
+// Comments locates the comments in the requested packages that match the
+// regular expression req.Spec.
+func (s *Server) Comments(ctx context.Context, req Request) (*Response, error) {
+	return s.comments(ctx, req, false)
+}
+
+// Directives locates the directives in the requested packages whose raw
+// text matches the regular expression req.Spec, see locate.Directives.
+func (s *Server) Directives(ctx context.Context, req Request) (*Response, error) {
+	return s.comments(ctx, req, true)
+}
+
+func (s *Server) comments(ctx context.Context, req Request, directives bool) (*Response, error) {
+	if len(req.Spec) == 0 {
+		return nil, fmt.Errorf("a regular expression must be specified")
+	}
+	locator := s.newLocator(locate.Directives(directives))
+	locator.AddPackages(s.packagesFor(req)...)
+	locator.AddComments(req.Spec)
+	if err := locator.Do(ctx); err != nil {
+		return nil, err
+	}
+	return newResponse(locator, func(loc locate.Location) bool {
+		return loc.Kind == locate.HasComment || loc.Kind == locate.HasDirective
+	}), nil
+}
+
+// Query evaluates the query req.Spec against the requested packages.
+func (s *Server) Query(ctx context.Context, req Request) (*Response, error) {
+	q, err := query.Parse(req.Spec)
+	if err != nil {
+		return nil, err
+	}
+	results, err := q.Locate(ctx, s.packagesFor(req), s.opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Results: results}, nil
+}
+
+// Reload reloads the loaded packages if any of them have changed, or
+// regardless of any changes if req.Force is set, see locate.Snapshot.Reload.
+func (s *Server) Reload(ctx context.Context, req Request) (*Response, error) {
+	changed, err := s.snapshot.Reload(ctx, req.Force)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Changed: changed, Packages: s.snapshot.Packages()}, nil
+}
+
+// Packages returns the packages that are currently loaded.
+func (s *Server) Packages(_ context.Context, _ Request) (*Response, error) {
+	return &Response{Packages: s.snapshot.Packages()}, nil
+}
+
+func newResponse(locator *locate.T, include func(locate.Location) bool) *Response {
+	resp := &Response{}
+	locator.WalkLocations(func(loc locate.Location) {
+		if include(loc) {
+			resp.Locations = append(resp.Locations, loc)
+		}
+	})
+	locator.WalkReferences(func(ref locate.Reference) {
+		resp.References = append(resp.References, Reference{
+			Function:  ref.Function,
+			Interface: ref.Interface,
+			Call:      ref.Call,
+			Caller:    ref.Caller,
+			Position:  ref.Position,
+		})
+	})
+	locator.WalkDiagnostics(func(pkgPath string, errs []packages.Error) {
+		for _, err := range errs {
+			resp.Diagnostics = append(resp.Diagnostics, fmt.Sprintf("%v: %v", pkgPath, err))
+		}
+	})
+	return resp
+}
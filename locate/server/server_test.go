@@ -0,0 +1,222 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudeng.io/go/locate"
+	"cloudeng.io/go/locate/server"
+)
+
+const source = `package srv
+
+// I is an interface.
+type I interface {
+	M()
+}
+
+// T implements I.
+type T struct{}
+
+// M implements I.
+func (T) M() {}
+
+// Use calls I.M.
+func Use(i I) {
+	// TODO: remove this.
+	i.M()
+}
+`
+
+func writeModule(t *testing.T, dir string, contents string) {
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/srv\n\ngo 1.21\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "srv.go")
+	if err := os.WriteFile(filename, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// Make sure that the modification time changes regardless of the
+	// resolution of the filesystem's timestamps.
+	when := time.Now().Add(time.Duration(len(contents)) * time.Second)
+	if err := os.Chtimes(filename, when, when); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func names(locs []locate.Location) []string {
+	var n []string
+	for _, loc := range locs {
+		n = append(n, loc.Name)
+	}
+	sort.Strings(n)
+	return n
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeModule(t, dir, source)
+	srv := server.New([]string{"example.com/srv"}, locate.Dir(dir))
+	if err := srv.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := srv.Interfaces(ctx, server.Request{Spec: "example.com/srv.I", References: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(resp.Locations), []string{"(example.com/srv.T).M", "example.com/srv.I", "example.com/srv.T"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// The call i.M() refers to both the interface method and its
+	// implementation.
+	var refs []string
+	for _, ref := range resp.References {
+		refs = append(refs, ref.Caller+": "+ref.Function+" "+ref.Interface)
+	}
+	sort.Strings(refs)
+	if got, want := refs, []string{
+		"example.com/srv.Use: (example.com/srv.I).M ",
+		"example.com/srv.Use: (example.com/srv.T).M example.com/srv.I",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	resp, err = srv.Functions(ctx, server.Request{Spec: "^Use$"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(resp.Locations), []string{"example.com/srv.Use"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	resp, err = srv.Comments(ctx, server.Request{Spec: "TODO"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(resp.Locations), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	resp, err = srv.Query(ctx, server.Request{Spec: "kind:method doc:present"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(resp.Results), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := resp.Results[0].Name, "(example.com/srv.T).M"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// All of the above are answered using the packages loaded by Load.
+	if got, want := srv.Snapshot().Loads(), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	resp, err = srv.Reload(ctx, server.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(resp.Changed), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := resp.Packages, []string{"example.com/srv"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := srv.Snapshot().Loads(), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	writeModule(t, dir, source+"\nfunc Added() {}\n")
+	resp, err = srv.Reload(ctx, server.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resp.Changed, []string{"example.com/srv"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := srv.Snapshot().Loads(), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	resp, err = srv.Functions(ctx, server.Request{Spec: "^Added$"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(resp.Locations), []string{"example.com/srv.Added"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestJSONRPC(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeModule(t, dir, source)
+	srv := server.New([]string{"example.com/srv"}, locate.Dir(dir))
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"functions","params":{"Spec":"^Use$"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"unknown"}`,
+		`{"jsonrpc":"2.0","method":"packages"}`,
+		`not json`,
+	}, "\n")
+	out := &bytes.Buffer{}
+	if err := srv.Serve(ctx, strings.NewReader(input), out); err != nil {
+		t.Fatal(err)
+	}
+	replies := map[string]server.Reply{}
+	dec := json.NewDecoder(out)
+	for dec.More() {
+		var reply server.Reply
+		if err := dec.Decode(&reply); err != nil {
+			t.Fatal(err)
+		}
+		replies[string(reply.ID)] = reply
+	}
+	if got, want := len(replies), 3; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if r := replies["1"]; r.Error != nil || len(r.Result.Locations) != 1 {
+		t.Errorf("unexpected reply: %+v", r)
+	}
+	if r := replies["2"]; r.Error == nil || r.Error.Code != server.MethodNotFound {
+		t.Errorf("unexpected reply: %+v", r)
+	}
+	if r := replies["null"]; r.Error == nil || r.Error.Code != server.ParseError {
+		t.Errorf("unexpected reply: %+v", r)
+	}
+
+	hs := httptest.NewServer(srv)
+	defer hs.Close()
+	body := `{"jsonrpc":"2.0","id":"a","method":"interfaces","params":{"Spec":"example.com/srv.I"}}`
+	hr, err := http.Post(hs.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hr.Body.Close()
+	var reply server.Reply
+	if err := json.NewDecoder(hr.Body).Decode(&reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Error != nil || string(reply.ID) != `"a"` {
+		t.Fatalf("unexpected reply: %+v", reply)
+	}
+	if got, want := names(reply.Result.Locations), []string{"(example.com/srv.T).M", "example.com/srv.I", "example.com/srv.T"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package locate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Snapshot represents a set of packages that are loaded, with type
+// information, once and then shared by multiple instances of T via the
+// UseSnapshot option. It is intended for long running processes, such as
+// editor integrations, that issue many queries against the same packages.
+// Packages are loaded when first requested and are retained until Reload
+// is called. The Dir, Tests, BuildFlags, Overlay and LoadDependencies
+// options are used when loading packages; AllModules and CacheDir are not
+// supported.
+type Snapshot struct {
+	mu      sync.Mutex
+	options options
+	// Indexed by the package path requested, the packages loaded for it,
+	// which will include test packages if the Tests option is set.
+	roots map[string][]*packages.Package
+	// Indexed by the package path requested, the modification times of
+	// the files and directories that its packages were loaded from.
+	stamps map[string]map[string]time.Time
+	// Indexed by directory and patterns, the results of 'go list'.
+	listed map[string][]string
+	loads  int
+}
+
+// NewSnapshot returns a new, empty, Snapshot.
+func NewSnapshot(opts ...Option) *Snapshot {
+	s := &Snapshot{
+		roots:  map[string][]*packages.Package{},
+		stamps: map[string]map[string]time.Time{},
+		listed: map[string][]string{},
+	}
+	for _, fn := range opts {
+		fn(&s.options)
+	}
+	return s
+}
+
+// UseSnapshot specifies that packages are to be obtained from the supplied
+// snapshot rather than being loaded for each call to Do. The expansions of
+// 'go list' expressions are also shared via the snapshot and results are
+// never cached since they are readily available. The snapshot should be
+// created with the same Dir, Tests, BuildFlags and Overlay options as the
+// instances of T that use it.
+func UseSnapshot(s *Snapshot) Option {
+	return func(o *options) {
+		o.snapshot = s
+	}
+}
+
+// Packages returns the paths of the packages that have been requested from
+// the snapshot.
+func (s *Snapshot) Packages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	paths := make([]string, 0, len(s.roots))
+	for path := range s.roots {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Loads returns the number of times that packages have been loaded by the
+// snapshot.
+func (s *Snapshot) Loads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loads
+}
+
+// Reload reloads all of the packages in the snapshot if any of the files,
+// or directories, that they were loaded from have changed, or regardless
+// of any changes if force is set. All of the packages are reloaded together,
+// rather than just those that have changed, so that the type information
+// for all of them remains consistent. The expansions of 'go list'
+// expressions are always discarded so that new packages are found. Reload
+// returns the paths of the packages that have changed.
+func (s *Snapshot) Reload(ctx context.Context, force bool) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listed = map[string][]string{}
+	var changed []string
+	for path, stamps := range s.stamps {
+		if modified(stamps) {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	if len(changed) == 0 && !force {
+		return changed, nil
+	}
+	paths := make([]string, 0, len(s.roots))
+	for path := range s.roots {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return changed, s.loadLocked(ctx, paths)
+}
+
+// modified returns true if any of the files or directories in stamps have
+// been modified or removed.
+func modified(stamps map[string]time.Time) bool {
+	for name, when := range stamps {
+		info, err := os.Stat(name)
+		if err != nil || !info.ModTime().Equal(when) {
+			return true
+		}
+	}
+	return false
+}
+
+// packagesFor returns the packages for the requested paths, loading
+// any that have not been loaded previously. All of the packages in the
+// snapshot are loaded together, see Reload.
+func (s *Snapshot) packagesFor(ctx context.Context, paths []string) ([]*packages.Package, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	missing := false
+	for _, path := range paths {
+		if _, ok := s.roots[path]; !ok {
+			missing = true
+			break
+		}
+	}
+	if missing {
+		all := append([]string{}, paths...)
+		for path := range s.roots {
+			all = append(all, path)
+		}
+		if err := s.loadLocked(ctx, dedup(all)); err != nil {
+			return nil, err
+		}
+	}
+	var pkgs []*packages.Package
+	for _, path := range paths {
+		pkgs = append(pkgs, s.roots[path]...)
+	}
+	return pkgs, nil
+}
+
+func (s *Snapshot) loadMode() packages.LoadMode {
+	if s.options.loadDependencies {
+		return typesLoadMode | packages.NeedImports | packages.NeedDeps
+	}
+	return typesLoadMode
+}
+
+func (s *Snapshot) loadLocked(ctx context.Context, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	cfg := &packages.Config{
+		Context:    ctx,
+		Mode:       s.loadMode(),
+		Tests:      s.options.tests,
+		BuildFlags: s.options.buildFlags,
+		Overlay:    s.options.overlay,
+		Dir:        s.options.dir,
+	}
+	pkgs, err := packages.Load(cfg, paths...)
+	if err != nil {
+		return err
+	}
+	roots := make(map[string][]*packages.Package, len(paths))
+	for _, path := range paths {
+		// Record packages that could not be found so that they are not
+		// reloaded every time that they are requested.
+		roots[path] = nil
+	}
+	stamps := map[string]map[string]time.Time{}
+	for _, pkg := range pkgs {
+		root := rootPath(pkg)
+		roots[root] = append(roots[root], pkg)
+		if stamps[root] == nil {
+			stamps[root] = map[string]time.Time{}
+		}
+		for _, filename := range pkg.GoFiles {
+			stamp(stamps[root], filename)
+			stamp(stamps[root], filepath.Dir(filename))
+		}
+	}
+	s.roots, s.stamps = roots, stamps
+	s.loads++
+	return nil
+}
+
+func stamp(stamps map[string]time.Time, name string) {
+	if _, ok := stamps[name]; ok {
+		return
+	}
+	if info, err := os.Stat(name); err == nil {
+		stamps[name] = info.ModTime()
+	}
+}
+
+// rootPath returns the path of the package that was requested in order
+// for pkg to be loaded, ie. the package under test for test packages, whose
+// IDs are of the form "p [p.test]", "p_test [p.test]" and "p.test".
+func rootPath(pkg *packages.Package) string {
+	if idx := strings.Index(pkg.ID, " ["); idx >= 0 && strings.HasSuffix(pkg.ID, "]") {
+		return strings.TrimSuffix(pkg.ID[idx+2:len(pkg.ID)-1], ".test")
+	}
+	if pkg.Name == "main" && strings.HasSuffix(pkg.ID, ".test") {
+		return strings.TrimSuffix(pkg.ID, ".test")
+	}
+	return pkg.PkgPath
+}
+
+// list returns the, possibly previously obtained, results of running 'go
+// list' for the supplied patterns in dir.
+func (s *Snapshot) list(dir string, patterns []string, goList func() ([]string, error)) ([]string, error) {
+	key := dir + "\x00" + strings.Join(patterns, "\x00")
+	s.mu.Lock()
+	listed, ok := s.listed[key]
+	s.mu.Unlock()
+	if ok {
+		return listed, nil
+	}
+	listed, err := goList()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.listed[key] = listed
+	s.mu.Unlock()
+	return listed, nil
+}
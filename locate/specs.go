@@ -10,11 +10,21 @@ import (
 	"path"
 	"regexp"
 	"strings"
+
+	"cloudeng.io/go/locate/locateutil"
 )
 
+// splitSignature splits a spec into the spec proper and any signature
+// predicates that follow the first ;.
+func splitSignature(spec string) (string, string) {
+	spec, signature, _ := strings.Cut(spec, ";")
+	return spec, signature
+}
+
 // parseSpecAndRegexp parses a type/interface spec that allows for a regular
 // expression in the package local component.
 func parseSpecAndRegexp(typ string) (string, string) {
+	typ, _ = splitSignature(typ)
 	idx := strings.LastIndex(typ, "/")
 	if idx < 0 {
 		if idx := strings.Index(typ, "."); idx >= 0 {
@@ -34,6 +44,9 @@ func parseSpecAndRegexp(typ string) (string, string) {
 }
 
 func getPathAndRegexp(typ string) (string, *regexp.Regexp, error) {
+	if _, signature := splitSignature(typ); len(signature) > 0 {
+		return "", nil, fmt.Errorf("signature predicates are only supported for functions: %v", typ)
+	}
 	path, expr := parseSpecAndRegexp(typ)
 	if len(path) == 0 {
 		return "", nil, fmt.Errorf("no package name in %v", typ)
@@ -45,6 +58,21 @@ func getPathAndRegexp(typ string) (string, *regexp.Regexp, error) {
 	return path, re, nil
 }
 
+// getPathRegexpAndSignature is like getPathAndRegexp but also parses
+// any signature predicates.
+func getPathRegexpAndSignature(spec string) (string, *regexp.Regexp, []locateutil.SignaturePredicate, error) {
+	spec, signature := splitSignature(spec)
+	path, re, err := getPathAndRegexp(spec)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	predicates, err := locateutil.ParseSignature(signature)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("%v: %w", spec, err)
+	}
+	return path, re, predicates, nil
+}
+
 func dedup(inputs []string) []string {
 	deduped := []string{}
 	dedup := map[string]bool{}
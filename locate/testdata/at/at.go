@@ -0,0 +1,22 @@
+package at
+
+// Ifc is an interface.
+type Ifc interface {
+	Method() error
+}
+
+// Impl implements Ifc.
+type Impl struct{}
+
+// Method implements Ifc.Method.
+func (Impl) Method() error { return nil }
+
+var (
+	a, b = 1, 2
+	c    = Impl{}
+)
+
+// Use calls Ifc.Method.
+func Use(i Ifc) error {
+	return i.Method()
+}
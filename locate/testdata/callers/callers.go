@@ -0,0 +1,23 @@
+package callers
+
+import (
+	"cloudeng.io/go/locate/testdata/data"
+	"cloudeng.io/go/locate/testdata/impl"
+)
+
+var fn = data.Fn2
+
+func Direct() {
+	data.Fn1()
+	_ = fn(1)
+}
+
+func ViaInterface(ifc data.Ifc1) {
+	ifc.M1()
+	(&impl.Impl1{}).M2("")
+}
+
+func Indirect() {
+	f := data.Fn1
+	_ = f()
+}
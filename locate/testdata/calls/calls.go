@@ -0,0 +1,37 @@
+package calls
+
+import (
+	"context"
+	"fmt"
+)
+
+type span struct{}
+
+func (s *span) End()      {}
+func (s *span) Annotate() {}
+
+func start(ctx context.Context, name string) (context.Context, *span) {
+	return ctx, &span{}
+}
+
+func trace(format string, args ...interface{}) {}
+
+func Nested(ctx context.Context, n int) {
+	if n > 0 {
+		trace("n=%v", n)
+	}
+	for i := 0; i < n; i++ {
+		func() {
+			trace("i=%v", i)
+		}()
+	}
+	defer trace("done")
+}
+
+func Assigned(ctx context.Context) {
+	name := fmt.Sprintf("%v", "assigned")
+	ctx, sp := start(ctx, name)
+	defer sp.End()
+	sp.Annotate()
+	_ = ctx
+}
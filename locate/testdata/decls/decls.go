@@ -0,0 +1,42 @@
+package decls
+
+import "errors"
+
+type Config struct {
+	Name            string `json:"name"`
+	Count           int    `json:"count,omitempty"`
+	Ignored         bool   `json:"-"`
+	private         int
+	Untagged, Other string
+}
+
+type Alias = Config
+
+type Celsius float64
+
+type Handler func()
+
+type Derived Config
+
+type Storer interface {
+	Store()
+}
+
+type unexported struct {
+	Field int `json:"field"`
+}
+
+const (
+	Boiling  Celsius = 100
+	freezing         = 0
+)
+
+const MaxItems = 10
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrInvalid  = errors.New("invalid")
+	errInternal = errors.New("internal")
+)
+
+var DefaultConfig Config
@@ -0,0 +1,57 @@
+package old
+
+// Func is an old function.
+//
+// Deprecated: use NewFunc instead.
+func Func() {}
+
+// NewFunc replaces Func.
+func NewFunc() {}
+
+// Type is an old type.
+//
+// Deprecated: use
+// NewType instead.
+type Type struct {
+	// Field is an old field.
+	//
+	// Deprecated: use Other.
+	Field int
+	Other int
+}
+
+// Method is an old method.
+//
+// Deprecated: no longer required.
+func (Type) Method() {}
+
+// Ifc is an interface with an old method.
+type Ifc interface {
+	// Deprecated: use Current.
+	Old()
+	Current()
+}
+
+// Deprecated: all of these constants are old.
+const (
+	A = iota
+	B
+)
+
+const (
+	// Deprecated: use D.
+	C = 3
+	D = 4
+)
+
+// Generic is an old generic function.
+//
+// Deprecated: use generics elsewhere.
+func Generic[T any](v T) T {
+	return v
+}
+
+func internal() {
+	// Uses within the declaring package are not reported.
+	Func()
+}
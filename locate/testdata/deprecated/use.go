@@ -0,0 +1,21 @@
+package deprecated
+
+import (
+	"strings"
+
+	"cloudeng.io/go/locate/testdata/deprecated/old"
+)
+
+func Uses(i old.Ifc) string {
+	old.Func()
+	old.NewFunc()
+	var t old.Type
+	t.Field = old.A + old.C + old.D
+	t.Method()
+	i.Old()
+	i.Current()
+	old.Generic(1)
+	return strings.Title("x")
+}
+
+var v = old.B
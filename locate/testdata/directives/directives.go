@@ -0,0 +1,36 @@
+//go:build !nobuild
+
+package directives
+
+import (
+	_ "embed"
+	"os"
+)
+
+//go:generate stringer -type=Kind
+
+// Kind is documented.
+//
+//nolint:revive
+type Kind int
+
+const (
+	A Kind = iota //nolint:gochecknoglobals
+	B
+)
+
+//go:embed directives.go
+var source string
+
+// Write has a directive within its body.
+func Write() {
+	os.WriteFile("x", []byte(source), 0600) //nolint:errcheck
+}
+
+type T struct{}
+
+//go:noinline
+func (t *T) Method() {}
+
+// Note: not a directive.
+var x, y = 1, 2 //nolint
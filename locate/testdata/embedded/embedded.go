@@ -0,0 +1,46 @@
+package embedded
+
+type Base struct{}
+
+func (Base) M1() {}
+
+func (*Base) M2(string) {}
+
+// Embedder implements data.Ifc1 only via its embedded field and only
+// as a pointer.
+type Embedder struct {
+	Base
+}
+
+// Value implements data.Ifc1 via an embedded pointer.
+type Value struct {
+	*Base
+}
+
+type Mixed struct {
+	Base
+}
+
+func (m *Mixed) M3(int) error {
+	return nil
+}
+
+// Sealed can only be implemented within this package.
+type Sealed interface {
+	sealed()
+	Name() string
+}
+
+type Impl struct{}
+
+func (Impl) sealed() {}
+
+func (Impl) Name() string {
+	return "impl"
+}
+
+type NotSealed struct{}
+
+func (NotSealed) Name() string {
+	return "not-sealed"
+}
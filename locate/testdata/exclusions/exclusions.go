@@ -0,0 +1,10 @@
+package exclusions
+
+// Current is the current API.
+func Current() {}
+
+// LegacyOpen is deprecated.
+func LegacyOpen() {}
+
+// LegacyClose is deprecated.
+func LegacyClose() {}
@@ -0,0 +1,4 @@
+package internal
+
+// Internal is internal.
+func Internal() {}
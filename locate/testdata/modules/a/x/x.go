@@ -0,0 +1,9 @@
+package x
+
+// I is implemented in another module.
+type I interface {
+	M(w *W)
+}
+
+// W is used to ensure that types from different modules are identical.
+type W struct{}
@@ -0,0 +1,9 @@
+package y
+
+import "example.com/a/x"
+
+// T implements x.I.
+type T struct{}
+
+// M implements x.I.
+func (T) M(*x.W) {}
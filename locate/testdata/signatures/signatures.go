@@ -0,0 +1,36 @@
+package signatures
+
+import (
+	"context"
+	"database/sql"
+)
+
+func Query(db *sql.DB, q string) error {
+	return nil
+}
+
+func QueryContext(ctx context.Context, db *sql.DB, q string) (*sql.Rows, error) {
+	return nil, nil
+}
+
+func Close() error {
+	return nil
+}
+
+func Printf(format string, args ...interface{}) {}
+
+func Lookup(m map[string]func(int, string) error) {}
+
+type Store struct{}
+
+func (s *Store) Get(ctx context.Context, key string) (string, error) {
+	return "", nil
+}
+
+func (s Store) Len() int {
+	return 0
+}
+
+type List[T any] struct{}
+
+func (l *List[T]) Append(v T) {}
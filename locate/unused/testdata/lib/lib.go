@@ -0,0 +1,58 @@
+package lib
+
+import "fmt"
+
+// Used is called by user.
+func Used() {}
+
+// Unused is only called within lib.
+func Unused() { internal() }
+
+func internal() {}
+
+// T is used via its methods.
+type T struct{}
+
+// Method is called by user.
+func (T) Method() {}
+
+// Other is never called.
+func (T) Other() {}
+
+// String implements fmt.Stringer.
+func (T) String() string { return fmt.Sprint("T") }
+
+// Error implements error.
+func (*T) Error() string { return "" }
+
+// Shape is never used.
+type Shape int
+
+// Kept is never used but is kept.
+//
+//golocate:keep
+func Kept() {}
+
+const (
+	// A is used by user.
+	A = iota
+	// B is never used.
+	B
+)
+
+//golocate:keep
+const (
+	C = 1
+	D = 2
+)
+
+// Closer is an interface that is never used.
+type Closer interface {
+	Close() error
+}
+
+// Local implements Closer.
+type Local struct{}
+
+// Close implements Closer.
+func (Local) Close() error { return nil }
@@ -0,0 +1,11 @@
+package user
+
+import "cloudeng.io/go/locate/unused/testdata/lib"
+
+// Run uses lib.
+func Run() int {
+	lib.Used()
+	var t lib.T
+	t.Method()
+	return lib.A
+}
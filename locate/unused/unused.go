@@ -0,0 +1,273 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package unused provides support for finding exported functions, methods,
+// types and constants that are never referenced outside of the package
+// that declares them within a set of packages. Methods that are required
+// to satisfy an interface are ignored, as are declarations that are
+// documented with a //golocate:keep directive, for example:
+//
+//	// Handler is used by plugins that are not part of this repository.
+//	//
+//	//golocate:keep
+//	func Handler() {}
+//
+// A //golocate:keep directive in the documentation for a grouped
+// declaration applies to all of the declarations in the group.
+package unused
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"cloudeng.io/go/locate"
+	"cloudeng.io/go/locate/locateutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// Kind represents the kind of an unused declaration.
+type Kind string
+
+const (
+	// Function is an unused exported function.
+	Function Kind = "function"
+	// Method is an unused exported method.
+	Method Kind = "method"
+	// Type is an unused exported type, none of whose fields or methods
+	// are referenced either.
+	Type Kind = "type"
+	// Const is an unused exported constant.
+	Const Kind = "const"
+)
+
+// KeepDirective is the directive used to exclude a declaration from
+// being reported as unused.
+var KeepDirective = locateutil.Directive{Key: "golocate", Value: "keep"}
+
+// Unused represents an exported declaration that is not referenced outside
+// of its own package.
+type Unused struct {
+	Kind Kind
+	// Name is the fully qualified name of the declaration as reported by
+	// locate.T.
+	Name     string
+	Package  string
+	Position token.Position
+}
+
+// Find returns the exported declarations in the specified packages that
+// are not referenced outside of their own package by any of the specified
+// packages, using a locate.T created with the supplied options. Note that
+// only the specified packages are searched for references and hence all of
+// the packages that may use the declarations should be specified. Main
+// packages are ignored. A type is considered to be referenced if it, or
+// any of its fields or methods, are referenced. A method is ignored if it
+// is required to satisfy an interface, declared in any of the specified
+// packages or those that they import directly, that its receiver type
+// implements. The results are returned in order of filename and then
+// position within filename. The locate.CacheDir option should not be used
+// since the references are determined from the packages that are loaded
+// rather than cached.
+func Find(ctx context.Context, pkgs []string, opts ...locate.Option) ([]Unused, error) {
+	opts = append([]locate.Option{locate.IgnoreMissingFuctionsEtc()}, opts...)
+	opts = append(opts, locate.IncludeMethods(true), locate.RequireTypes())
+	locator := locate.New(opts...)
+	locator.AddFunctions(pkgs...)
+	locator.AddTypes(pkgs...)
+	locator.AddConsts(pkgs...)
+	locator.AddPackages(pkgs...)
+	if err := locator.Do(ctx); err != nil {
+		return nil, err
+	}
+	refs, ifcs := externalReferences(locator)
+	satisfies := newInterfaceChecker(ifcs)
+	unused := []Unused{}
+	report := func(kind Kind, name string, pkg *packages.Package, pos token.Pos, docs ...*ast.CommentGroup) {
+		if pkg.Name == "main" || refs[name] || keep(docs...) {
+			return
+		}
+		unused = append(unused, Unused{
+			Kind:     kind,
+			Name:     name,
+			Package:  pkg.PkgPath,
+			Position: pkg.Fset.PositionFor(pos, false),
+		})
+	}
+	locator.WalkFunctions(func(name string, pkg *packages.Package, _ *ast.File, fn *types.Func, decl *ast.FuncDecl, _ []string) {
+		if decl == nil {
+			// Interface methods.
+			return
+		}
+		recv := fn.Type().(*types.Signature).Recv()
+		if recv == nil {
+			report(Function, name, pkg, decl.Name.Pos(), decl.Doc)
+			return
+		}
+		named := receiverType(recv.Type())
+		if named == nil || !named.Obj().Exported() || satisfies(named, fn.Name()) {
+			return
+		}
+		report(Method, name, pkg, decl.Name.Pos(), decl.Doc)
+	})
+	locator.WalkTypes(func(name string, pkg *packages.Package, file *ast.File, decl *ast.TypeSpec, _ *types.TypeName) {
+		report(Type, name, pkg, decl.Name.Pos(), append(genDeclDocs(file, decl), decl.Doc, decl.Comment)...)
+	})
+	locator.WalkConsts(func(name string, pkg *packages.Package, file *ast.File, decl *ast.ValueSpec, obj *types.Const) {
+		report(Const, name, pkg, obj.Pos(), append(genDeclDocs(file, decl), decl.Doc, decl.Comment)...)
+	})
+	sort.SliceStable(unused, func(i, j int) bool {
+		a, b := unused[i].Position, unused[j].Position
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+	return unused, nil
+}
+
+// externalReferences returns the names, as reported by locate.T, of the
+// package level objects, methods and types that are referred to from
+// outside of their own package, and the interfaces declared in, or
+// imported by, the loaded packages.
+func externalReferences(locator *locate.T) (map[string]bool, []*types.Interface) {
+	refs := map[string]bool{}
+	var ifcs []*types.Interface
+	seen := map[*types.Package]bool{}
+	locator.WalkPackages(func(pkg *packages.Package) {
+		if pkg.Types == nil || pkg.TypesInfo == nil {
+			return
+		}
+		ifcs = appendInterfaces(ifcs, pkg.Types, seen)
+		for _, imported := range pkg.Types.Imports() {
+			ifcs = appendInterfaces(ifcs, imported, seen)
+		}
+		for _, obj := range pkg.TypesInfo.Uses {
+			if obj.Pkg() == nil || obj.Pkg().Path() == pkg.PkgPath {
+				continue
+			}
+			if name := objectName(obj); len(name) > 0 {
+				refs[name] = true
+			}
+		}
+		// Any use of a field or method of a type counts as a reference
+		// to the type.
+		for _, sel := range pkg.TypesInfo.Selections {
+			named := receiverType(sel.Recv())
+			if named == nil || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() == pkg.PkgPath {
+				continue
+			}
+			refs[objectName(named.Obj())] = true
+		}
+	})
+	return refs, ifcs
+}
+
+func objectName(obj types.Object) string {
+	switch o := obj.(type) {
+	case *types.Func:
+		return o.Origin().FullName()
+	case *types.TypeName:
+		if named, ok := o.Type().(*types.Named); ok {
+			o = named.Origin().Obj()
+		}
+		return o.Pkg().Path() + "." + o.Name()
+	case *types.Const:
+		return o.Pkg().Path() + "." + o.Name()
+	}
+	return ""
+}
+
+func receiverType(typ types.Type) *types.Named {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, _ := typ.(*types.Named)
+	if named == nil {
+		return nil
+	}
+	return named.Origin()
+}
+
+// appendInterfaces appends the non-generic interfaces declared at package
+// level in pkg.
+func appendInterfaces(ifcs []*types.Interface, pkg *types.Package, seen map[*types.Package]bool) []*types.Interface {
+	if seen[pkg] {
+		return ifcs
+	}
+	seen[pkg] = true
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+			continue
+		}
+		if ifc, ok := tn.Type().Underlying().(*types.Interface); ok && ifc.NumMethods() > 0 {
+			ifcs = append(ifcs, ifc)
+		}
+	}
+	return ifcs
+}
+
+// newInterfaceChecker returns a function that determines if the named
+// method of a type is required for that type, or a pointer to it, to
+// implement any of the supplied interfaces or the error interface.
+func newInterfaceChecker(ifcs []*types.Interface) func(*types.Named, string) bool {
+	ifcs = append(ifcs, types.Universe.Lookup("error").Type().Underlying().(*types.Interface))
+	cache := map[*types.Named]map[string]bool{}
+	return func(named *types.Named, method string) bool {
+		if named.TypeParams().Len() > 0 {
+			// The methods of generic types cannot be checked without
+			// instantiating them and are assumed to be required.
+			return true
+		}
+		required, ok := cache[named]
+		if !ok {
+			required = map[string]bool{}
+			ptr := types.NewPointer(named)
+			for _, ifc := range ifcs {
+				if !types.Implements(named, ifc) && !types.Implements(ptr, ifc) {
+					continue
+				}
+				for i := 0; i < ifc.NumMethods(); i++ {
+					required[ifc.Method(i).Name()] = true
+				}
+			}
+			cache[named] = required
+		}
+		return required[method]
+	}
+}
+
+// genDeclDocs returns the documentation for the ast.GenDecl that
+// contains spec.
+func genDeclDocs(file *ast.File, spec ast.Spec) []*ast.CommentGroup {
+	decl, _ := locateutil.EnclosingDecl(file, spec.Pos())
+	if gd, ok := decl.(*ast.GenDecl); ok && gd.Doc != nil {
+		return []*ast.CommentGroup{gd.Doc}
+	}
+	return nil
+}
+
+// keep returns true if any of the supplied comments contain a
+// //golocate:keep directive.
+func keep(docs ...*ast.CommentGroup) bool {
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		for _, c := range doc.List {
+			d, ok := locateutil.ParseDirective(c.Text)
+			if ok && d.Key == KeepDirective.Key && d.Value == KeepDirective.Value {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package unused_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloudeng.io/go/locate/unused"
+)
+
+const here = "cloudeng.io/go/locate/unused/testdata/"
+
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+	found, err := unused.Find(ctx, []string{here + "lib", here + "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, u := range found {
+		got = append(got, fmt.Sprintf("%v %v @ %v:%v", u.Kind,
+			strings.ReplaceAll(u.Name, here, ""),
+			filepath.Base(u.Position.Filename), u.Position.Line))
+	}
+	want := []string{
+		"function lib.Unused @ lib.go:9",
+		"method (lib.T).Other @ lib.go:20",
+		"type lib.Shape @ lib.go:29",
+		"const lib.B @ lib.go:40",
+		"type lib.Closer @ lib.go:50",
+		"type lib.Local @ lib.go:55",
+		"function user.Run @ user.go:6",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// References from packages that are not specified are not seen.
+	found, err = unused.Find(ctx, []string{here + "lib"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(found), 10; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}